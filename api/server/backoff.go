@@ -0,0 +1,83 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// minAcceptBackoff and maxAcceptBackoff bound the delay before accepting
+	// connections again after a transient accept error, which doubles for
+	// each consecutive error.
+	minAcceptBackoff = 5 * time.Millisecond
+	maxAcceptBackoff = time.Second
+)
+
+// transientAcceptErrors are the errors of accept calls caused by a temporary
+// exhaustion of resources, or by a connection reset before it was accepted,
+// after which accepting connections can be retried.
+var transientAcceptErrors = []syscall.Errno{
+	syscall.EMFILE,
+	syscall.ENFILE,
+	syscall.ENOBUFS,
+	syscall.ENOMEM,
+	syscall.ECONNABORTED,
+}
+
+// backoffListener retries accepting connections after transient errors,
+// such as file descriptor exhaustion, instead of returning them, so that
+// the server survives them. Other errors are returned.
+type backoffListener struct {
+	net.Listener
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func withAcceptBackoff(l net.Listener) net.Listener {
+	return &backoffListener{Listener: l, closed: make(chan struct{})}
+}
+
+func (l *backoffListener) Accept() (net.Conn, error) {
+	var delay time.Duration
+	for {
+		c, err := l.Listener.Accept()
+		if err == nil || !isTransientAcceptError(err) {
+			return c, err
+		}
+		if delay == 0 {
+			delay = minAcceptBackoff
+		} else {
+			delay *= 2
+		}
+		if delay > maxAcceptBackoff {
+			delay = maxAcceptBackoff
+		}
+		log.WithError(err).WithField("addr", l.Addr().String()).Warnf("API listener failed to accept a connection; retrying in %v", delay)
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-l.closed:
+			t.Stop()
+			return nil, net.ErrClosed
+		}
+	}
+}
+
+func (l *backoffListener) Close() error {
+	l.closeOnce.Do(func() { close(l.closed) })
+	return l.Listener.Close()
+}
+
+func isTransientAcceptError(err error) bool {
+	for _, errno := range transientAcceptErrors {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+// errListener returns the errors of errs from Accept, and then accepts the
+// connections of its listener.
+type errListener struct {
+	net.Listener
+	errs []error
+}
+
+func (l *errListener) Accept() (net.Conn, error) {
+	if len(l.errs) > 0 {
+		err := l.errs[0]
+		l.errs = l.errs[1:]
+		return nil, err
+	}
+	return l.Listener.Accept()
+}
+
+func TestBackoffListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	emfile := &net.OpError{Op: "accept", Net: "tcp", Err: os.NewSyscallError("accept", syscall.EMFILE)}
+	el := &errListener{Listener: l, errs: []error{emfile, emfile}}
+	bl := withAcceptBackoff(el)
+	defer bl.Close()
+
+	// transient errors are retried
+	conn, err := net.Dial("tcp", l.Addr().String())
+	assert.NilError(t, err)
+	defer conn.Close()
+	c, err := bl.Accept()
+	assert.NilError(t, err)
+	c.Close()
+
+	// other errors are returned
+	permanent := errors.New("permanent error")
+	el.errs = []error{emfile, permanent}
+	_, err = bl.Accept()
+	assert.Check(t, is.ErrorIs(err, permanent))
+
+	// closing the listener interrupts the backoff
+	el.errs = []error{emfile}
+	go func() {
+		time.Sleep(time.Millisecond)
+		bl.Close()
+	}()
+	_, err = bl.Accept()
+	assert.Check(t, is.ErrorIs(err, net.ErrClosed))
+}
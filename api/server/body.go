@@ -0,0 +1,165 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+)
+
+// DefaultMaxDecompressedBodyBytes is the maximum size (in bytes) of the
+// decompressed request bodies when Config.MaxDecompressedBodyBytes is not
+// set.
+const DefaultMaxDecompressedBodyBytes = 64 << 20
+
+type requestBodyTooLargeError struct {
+	limit int64
+}
+
+func (e requestBodyTooLargeError) Error() string {
+	return fmt.Sprintf("request body too large: maximum allowed size is %d bytes", e.limit)
+}
+
+func (requestBodyTooLargeError) HTTPStatusCode() int {
+	return http.StatusRequestEntityTooLarge
+}
+
+// maxBodyReader limits the size of a request body using http.MaxBytesReader,
+// and records whether the limit was exceeded, so that the server can return
+// a "413 Request Entity Too Large" regardless of how the handler reported
+// the read error.
+type maxBodyReader struct {
+	io.ReadCloser
+	limit    int64
+	read     int64
+	exceeded bool
+}
+
+func newMaxBodyReader(w http.ResponseWriter, body io.ReadCloser, limit int64) *maxBodyReader {
+	return &maxBodyReader{
+		ReadCloser: http.MaxBytesReader(w, body, limit),
+		limit:      limit,
+	}
+}
+
+func (b *maxBodyReader) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if err != nil && err != io.EOF && b.read >= b.limit {
+		b.exceeded = true
+		err = requestBodyTooLargeError{limit: b.limit}
+	}
+	return n, err
+}
+
+// rateLimitedReader limits the rate at which a request body is read, so that
+// concurrent large uploads (such as build contexts and image tarballs) are
+// consumed at a bounded rate, applying backpressure to the clients.
+type rateLimitedReader struct {
+	io.ReadCloser
+	ctx     context.Context
+	limiter *rate.Limiter
+}
+
+// newRateLimitedReader returns a reader reading body at the rate of limiter,
+// which may be shared with the readers of other requests. Reads fail once
+// ctx is done.
+func newRateLimitedReader(ctx context.Context, body io.ReadCloser, limiter *rate.Limiter) *rateLimitedReader {
+	return &rateLimitedReader{
+		ReadCloser: body,
+		ctx:        ctx,
+		limiter:    limiter,
+	}
+}
+
+func (b *rateLimitedReader) Read(p []byte) (int, error) {
+	// reads cannot be larger than the burst of the limiter.
+	if len(p) > b.limiter.Burst() {
+		p = p[:b.limiter.Burst()]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		if waitErr := b.limiter.WaitN(b.ctx, n); waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
+
+type decompressedBodyTooLargeError struct {
+	limit int64
+}
+
+func (e decompressedBodyTooLargeError) Error() string {
+	return fmt.Sprintf("request body too large: maximum allowed decompressed size is %d bytes", e.limit)
+}
+
+func (decompressedBodyTooLargeError) HTTPStatusCode() int {
+	return http.StatusRequestEntityTooLarge
+}
+
+// isGzipEncoded returns whether the body of r is compressed with gzip.
+func isGzipEncoded(r *http.Request) bool {
+	switch strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))) {
+	case "gzip", "x-gzip":
+		return true
+	default:
+		return false
+	}
+}
+
+// gzipBodyReader decompresses a request body sent with a "Content-Encoding:
+// gzip" header, so that handlers read the decompressed body. The size of
+// the decompressed body is limited, to protect the daemon from small bodies
+// decompressing to huge ones ("zip bombs"), and whether the limit was
+// exceeded is recorded, like maxBodyReader.
+type gzipBodyReader struct {
+	body     io.ReadCloser
+	zr       *gzip.Reader
+	limit    int64
+	read     int64
+	exceeded bool
+}
+
+// newGzipBodyReader returns a reader decompressing body, which fails once
+// more than limit bytes are decompressed. It returns an error if body does
+// not start with a gzip header.
+func newGzipBodyReader(body io.ReadCloser, limit int64) (*gzipBodyReader, error) {
+	zr, err := gzip.NewReader(body)
+	if err != nil {
+		if b, ok := body.(*maxBodyReader); ok && b.exceeded {
+			return nil, err
+		}
+		return nil, errdefs.InvalidParameter(errors.Wrap(err, "invalid gzip request body"))
+	}
+	return &gzipBodyReader{body: body, zr: zr, limit: limit}, nil
+}
+
+func (b *gzipBodyReader) Read(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, decompressedBodyTooLargeError{limit: b.limit}
+	}
+	// Read one more byte than allowed, to detect bodies exceeding the limit,
+	// instead of bodies of exactly the limit.
+	if remaining := b.limit - b.read + 1; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := b.zr.Read(p)
+	b.read += int64(n)
+	if b.read > b.limit {
+		b.exceeded = true
+		return n - int(b.read-b.limit), decompressedBodyTooLargeError{limit: b.limit}
+	}
+	return n, err
+}
+
+func (b *gzipBodyReader) Close() error {
+	_ = b.zr.Close()
+	return b.body.Close()
+}
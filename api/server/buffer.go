@@ -0,0 +1,129 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// maxBufferedResponseBytes is the maximum size of the responses buffered for
+// the routes with the BufferResponse option. Larger responses are written
+// once they exceed the limit, as for other routes.
+const maxBufferedResponseBytes = 4 << 20
+
+// responseBuffer buffers the response of a handler, so that the response can
+// be replaced by an error response if the handler fails after writing
+// (part of) it. The response is written to the underlying ResponseWriter
+// once committed, or as soon as it exceeds maxBufferedResponseBytes, or the
+// handler flushes or hijacks it, after which writes are passed through.
+type responseBuffer struct {
+	w         http.ResponseWriter
+	header    http.Header
+	status    int
+	buf       bytes.Buffer
+	committed bool
+}
+
+func newResponseBuffer(w http.ResponseWriter) *responseBuffer {
+	return &responseBuffer{w: w, header: w.Header().Clone()}
+}
+
+func (b *responseBuffer) Header() http.Header {
+	if b.committed {
+		return b.w.Header()
+	}
+	return b.header
+}
+
+func (b *responseBuffer) WriteHeader(code int) {
+	if b.committed {
+		b.w.WriteHeader(code)
+		return
+	}
+	if b.status == 0 {
+		b.status = code
+	}
+}
+
+func (b *responseBuffer) Write(p []byte) (int, error) {
+	if b.committed {
+		return b.w.Write(p)
+	}
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	if b.buf.Len()+len(p) > maxBufferedResponseBytes {
+		if err := b.commit(); err != nil {
+			return 0, err
+		}
+		return b.w.Write(p)
+	}
+	return b.buf.Write(p)
+}
+
+// Flush implements http.Flusher, committing the response.
+func (b *responseBuffer) Flush() {
+	if err := b.commit(); err != nil {
+		return
+	}
+	if f, ok := b.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker, committing the response.
+func (b *responseBuffer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := b.w.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the response writer does not support hijacking")
+	}
+	if err := b.commit(); err != nil {
+		return nil, nil, err
+	}
+	return h.Hijack()
+}
+
+// commit writes the buffered response to the underlying ResponseWriter, if
+// it was not committed yet.
+func (b *responseBuffer) commit() error {
+	if b.committed {
+		return nil
+	}
+	b.committed = true
+	dst := b.w.Header()
+	for k := range dst {
+		delete(dst, k)
+	}
+	for k, v := range b.header {
+		dst[k] = v
+	}
+	if b.status == 0 {
+		// nothing was written yet
+		return nil
+	}
+	b.w.WriteHeader(b.status)
+	_, err := b.w.Write(b.buf.Bytes())
+	b.buf.Reset()
+	return err
+}
+
+// discard discards the buffered response, if it was not committed yet, so
+// that an error response can be written instead. The buffered headers are
+// kept, as they include those set by middlewares (such as the API version),
+// except for those describing the discarded body.
+func (b *responseBuffer) discard() {
+	if b.committed {
+		return
+	}
+	b.committed = true
+	b.buf.Reset()
+	b.header.Del("Content-Length")
+	b.header.Del("Content-Type")
+	dst := b.w.Header()
+	for k, v := range b.header {
+		dst[k] = v
+	}
+}
@@ -0,0 +1,37 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/docker/docker/api/server/httputils"
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+)
+
+// configAdminPath is the path of the endpoint returning the effective
+// configuration of the daemon, if enabled by Config.EnableConfigEndpoint.
+const configAdminPath = "/_admin/config"
+
+// SetConfigSource sets the function returning the effective configuration
+// of the daemon, which is returned as JSON by the "/_admin/config" endpoint.
+// The function is responsible for redacting secrets from the configuration.
+func (s *Server) SetConfigSource(source func() (interface{}, error)) {
+	s.mu.Lock()
+	s.configSource = source
+	s.mu.Unlock()
+}
+
+func (s *Server) getConfig(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	s.mu.RLock()
+	source := s.configSource
+	s.mu.RUnlock()
+	if source == nil {
+		return errdefs.Unavailable(errors.New("the daemon configuration is not available"))
+	}
+	conf, err := source()
+	if err != nil {
+		return errdefs.System(errors.Wrap(err, "failed to get the daemon configuration"))
+	}
+	return httputils.WriteJSON(w, http.StatusOK, conf)
+}
@@ -0,0 +1,89 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"net"
+	"sync"
+)
+
+// connLimitListener bounds the number of connections open on a listener:
+// connections accepted while the limit is reached are closed immediately,
+// instead of waiting to be served. Unlike the limits on requests, this
+// protects the daemon from clients holding many idle connections.
+type connLimitListener struct {
+	net.Listener
+	max int
+
+	mu       sync.Mutex
+	open     int
+	accepted uint64
+	rejected uint64
+}
+
+// withConnLimit returns l, bounding the number of its open connections to
+// cfg.MaxConnectionsPerListener, if set.
+func withConnLimit(l net.Listener, cfg *Config) net.Listener {
+	if cfg.MaxConnectionsPerListener <= 0 {
+		return l
+	}
+	return &connLimitListener{Listener: l, max: cfg.MaxConnectionsPerListener}
+}
+
+func (l *connLimitListener) Accept() (net.Conn, error) {
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		l.mu.Lock()
+		if l.open >= l.max {
+			l.rejected++
+			l.mu.Unlock()
+			log.WithField("addr", l.Addr().String()).Debugf("rejecting connection from %s: %d connections are open", peerAddr(c), l.max)
+			_ = c.Close()
+			continue
+		}
+		l.open++
+		l.accepted++
+		l.mu.Unlock()
+		return &limitedConn{Conn: c, l: l}, nil
+	}
+}
+
+// peerAddr returns the address of the peer of c. Unlike c.RemoteAddr, it
+// does not read the PROXY protocol header of connections accepted with
+// Config.TrustedProxyProtocol, which would block Accept until the client sends it.
+func peerAddr(c net.Conn) net.Addr {
+	if pc, ok := c.(interface{ peerAddr() net.Addr }); ok {
+		return pc.peerAddr()
+	}
+	return c.RemoteAddr()
+}
+
+func (l *connLimitListener) release() {
+	l.mu.Lock()
+	l.open--
+	l.mu.Unlock()
+}
+
+// addStats adds the counts of the connections of l to stats.
+func (l *connLimitListener) addStats(stats *ConnStats) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stats.Open = l.open
+	stats.Accepted = l.accepted
+	stats.Rejected = l.rejected
+}
+
+// limitedConn is a connection counted by a connLimitListener until it is
+// closed, including once hijacked.
+type limitedConn struct {
+	net.Conn
+	l    *connLimitListener
+	once sync.Once
+}
+
+func (c *limitedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.l.release)
+	return err
+}
@@ -0,0 +1,87 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestConnLimitListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	cl := withConnLimit(l, &Config{MaxConnectionsPerListener: 1}).(*connLimitListener)
+	defer cl.Close()
+
+	dial := func() net.Conn {
+		c, err := net.Dial("tcp", l.Addr().String())
+		assert.NilError(t, err)
+		return c
+	}
+
+	c1 := dial()
+	defer c1.Close()
+	a1, err := cl.Accept()
+	assert.NilError(t, err)
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, err := cl.Accept()
+		if err == nil {
+			accepted <- c
+		}
+	}()
+
+	// connections beyond the limit are closed
+	c2 := dial()
+	defer c2.Close()
+	_ = c2.SetReadDeadline(time.Now().Add(10 * time.Second))
+	_, err = c2.Read(make([]byte, 1))
+	assert.Check(t, is.ErrorIs(err, io.EOF))
+
+	// closing a connection makes room for another one
+	assert.NilError(t, a1.Close())
+	c3 := dial()
+	defer c3.Close()
+	select {
+	case a3 := <-accepted:
+		defer a3.Close()
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for the connection to be accepted")
+	}
+
+	var stats ConnStats
+	cl.addStats(&stats)
+	assert.Check(t, is.DeepEqual(stats, ConnStats{Open: 1, Accepted: 2, Rejected: 1}))
+}
+
+func TestConnLimitListenerProxyProtocol(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	cfg := &Config{MaxConnectionsPerListener: 1, TrustedProxyProtocol: true}
+	cl := withConnLimit(withProxyProtocol(l, cfg), cfg)
+	defer cl.Close()
+
+	c1, err := net.Dial("tcp", l.Addr().String())
+	assert.NilError(t, err)
+	defer c1.Close()
+	a1, err := cl.Accept()
+	assert.NilError(t, err)
+	defer a1.Close()
+	go func() {
+		if c, err := cl.Accept(); err == nil {
+			c.Close()
+		}
+	}()
+
+	// rejecting a connection does not wait for its PROXY protocol header
+	c2, err := net.Dial("tcp", l.Addr().String())
+	assert.NilError(t, err)
+	defer c2.Close()
+	_ = c2.SetReadDeadline(time.Now().Add(proxyHeaderTimeout / 2))
+	_, err = c2.Read(make([]byte, 1))
+	assert.Check(t, is.ErrorIs(err, io.EOF))
+}
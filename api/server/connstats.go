@@ -0,0 +1,95 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"net"
+	"net/http"
+	"sync"
+)
+
+// ConnStats is a snapshot of the connections of a listener.
+type ConnStats struct {
+	// Addr is the address of the listener.
+	Addr string
+	// New, Active and Idle are the number of connections currently in the
+	// respective state (see http.ConnState).
+	New    int
+	Active int
+	Idle   int
+	// Hijacked and Closed are the total number of connections that were
+	// hijacked (for example, by attach or exec), or closed.
+	Hijacked uint64
+	Closed   uint64
+	// Open is the number of connections currently open on the listener,
+	// and Accepted and Rejected are the total number of connections that
+	// were accepted, or rejected because Config.MaxConnectionsPerListener
+	// connections were open. They are only counted if the limit is set.
+	Open     int
+	Accepted uint64
+	Rejected uint64
+}
+
+// connStats tracks the state of the connections of an http.Server through
+// its ConnState hook.
+type connStats struct {
+	mu       sync.Mutex
+	conns    map[net.Conn]http.ConnState
+	current  map[http.ConnState]int
+	hijacked uint64
+	closed   uint64
+}
+
+func newConnStats() *connStats {
+	return &connStats{
+		conns:   make(map[net.Conn]http.ConnState),
+		current: make(map[http.ConnState]int),
+	}
+}
+
+// track is used as http.Server.ConnState.
+func (c *connStats) track(conn net.Conn, state http.ConnState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if prev, ok := c.conns[conn]; ok {
+		c.current[prev]--
+	}
+	switch state {
+	case http.StateHijacked:
+		delete(c.conns, conn)
+		c.hijacked++
+	case http.StateClosed:
+		delete(c.conns, conn)
+		c.closed++
+	default:
+		c.conns[conn] = state
+		c.current[state]++
+	}
+}
+
+func (c *connStats) snapshot(addr string) ConnStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return ConnStats{
+		Addr:     addr,
+		New:      c.current[http.StateNew],
+		Active:   c.current[http.StateActive],
+		Idle:     c.current[http.StateIdle],
+		Hijacked: c.hijacked,
+		Closed:   c.closed,
+	}
+}
+
+// ConnStats returns a snapshot of the connections of each listener.
+func (s *Server) ConnStats() []ConnStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stats := make([]ConnStats, 0, len(s.servers))
+	for _, srv := range s.servers {
+		st := srv.stats.snapshot(srv.addr)
+		if l, ok := srv.l.shared.Listener.(*connLimitListener); ok {
+			l.addStats(&st)
+		}
+		stats = append(stats, st)
+	}
+	return stats
+}
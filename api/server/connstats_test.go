@@ -0,0 +1,57 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"net"
+	"net/http"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestConnStats(t *testing.T) {
+	c := newConnStats()
+	c1, c2, c3 := &net.TCPConn{}, &net.TCPConn{}, &net.TCPConn{}
+
+	c.track(c1, http.StateNew)
+	c.track(c2, http.StateNew)
+	c.track(c3, http.StateNew)
+	c.track(c1, http.StateActive)
+	c.track(c2, http.StateActive)
+	c.track(c2, http.StateIdle)
+	c.track(c3, http.StateActive)
+	c.track(c3, http.StateHijacked)
+
+	assert.DeepEqual(t, c.snapshot("/run/docker.sock"), ConnStats{
+		Addr:     "/run/docker.sock",
+		Active:   1,
+		Idle:     1,
+		Hijacked: 1,
+	})
+
+	c.track(c1, http.StateClosed)
+	c.track(c2, http.StateClosed)
+	assert.DeepEqual(t, c.snapshot("/run/docker.sock"), ConnStats{
+		Addr:     "/run/docker.sock",
+		Hijacked: 1,
+		Closed:   2,
+	})
+}
+
+func TestAcceptBindings(t *testing.T) {
+	l1, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	defer l1.Close()
+	l2, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	defer l2.Close()
+
+	srv := &Server{cfg: &Config{}}
+	srv.AcceptBindings(nil, Binding{Addr: "tcp://first", Listener: l1}, Binding{Addr: "tcp://second", Listener: l2})
+
+	stats := srv.ConnStats()
+	assert.Equal(t, len(stats), 2)
+	assert.Check(t, is.Equal(stats[0].Addr, l1.Addr().String()))
+	assert.Check(t, is.Equal(stats[1].Addr, l2.Addr().String()))
+	assert.Check(t, is.Equal(srv.servers[1].srv.Addr, "tcp://second"))
+}
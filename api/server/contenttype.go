@@ -0,0 +1,86 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/docker/docker/api/server/httpstatus"
+	"github.com/docker/docker/api/server/httputils"
+	"github.com/docker/docker/api/server/router"
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+)
+
+type unsupportedMediaTypeError struct {
+	contentType string
+
+	// legacy is set for requests using an API version before 1.42, which
+	// are rejected with a "400 Bad Request", as they were before.
+	legacy bool
+}
+
+func (e unsupportedMediaTypeError) Error() string {
+	if e.contentType == "" {
+		return "missing Content-Type header: must be 'application/json'"
+	}
+	return fmt.Sprintf("unsupported Content-Type header (%s): must be 'application/json'", e.contentType)
+}
+
+func (e unsupportedMediaTypeError) HTTPStatusCode() int {
+	if e.legacy {
+		return http.StatusBadRequest
+	}
+	return http.StatusUnsupportedMediaType
+}
+
+// jsonBodyHandler returns a handler that rejects the requests with a body
+// whose Content-Type is not "application/json" with a "415 Unsupported Media
+// Type" status (or "400 Bad Request" for API versions before 1.42), before
+// calling handler. As for httputils.CheckForJSON, requests without a body do
+// not need a Content-Type.
+func jsonBodyHandler(handler httputils.APIFunc) httputils.APIFunc {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		ct := r.Header.Get("Content-Type")
+		if ct == "" && (r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0) {
+			return handler(ctx, w, r, vars)
+		}
+		if mediaType, _, err := mime.ParseMediaType(ct); err != nil || mediaType != "application/json" {
+			return unsupportedMediaTypeError{
+				contentType: ct,
+				legacy:      httputils.VersionFromContext(ctx).LessThan("1.42"),
+			}
+		}
+		return handler(ctx, w, r, vars)
+	}
+}
+
+// validateBodyHandler returns a handler that reads the request body, and
+// rejects the request if validate returns an error, before calling handler,
+// which reads the same body. Errors that do not map to another status are
+// returned as a "400 Bad Request".
+func validateBodyHandler(handler httputils.APIFunc, validate router.BodyValidatorFunc) httputils.APIFunc {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		var body []byte
+		if r.Body != nil && r.Body != http.NoBody {
+			var err error
+			if body, err = io.ReadAll(r.Body); err != nil {
+				return err
+			}
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{bytes.NewReader(body), r.Body}
+		}
+		if err := validate(ctx, body); err != nil {
+			if httpstatus.FromError(err) == http.StatusInternalServerError {
+				err = errdefs.InvalidParameter(errors.Wrap(err, "invalid request body"))
+			}
+			return err
+		}
+		return handler(ctx, w, r, vars)
+	}
+}
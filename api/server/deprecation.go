@@ -0,0 +1,46 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// templateVar matches the variables of a path template, such as "{name:.*}"
+// in "/containers/{name:.*}/json".
+var templateVar = regexp.MustCompile(`\{([^}:]+)(?::[^}]*)?\}`)
+
+// deprecationHeader returns the value of the "Deprecation" header of the
+// responses of a route deprecated since the given time, as a structured
+// field date (see RFC 9745).
+func deprecationHeader(since time.Time) string {
+	return "@" + strconv.FormatInt(since.Unix(), 10)
+}
+
+// successorLink returns the value of the "Link" header pointing to the
+// replacement of the deprecated route of r. If replacement is a path
+// template, its variables are expanded with the (escaped) values of the
+// same variables in the path of r, and it is given the version prefix of r,
+// if any, so that clients can follow the link as-is. It must be called
+// before the variables of r are decoded by normalizePathVars.
+func successorLink(r *http.Request, replacement string) string {
+	if strings.HasPrefix(replacement, "/") {
+		vars := mux.Vars(r)
+		replacement = templateVar.ReplaceAllStringFunc(replacement, func(v string) string {
+			name := templateVar.FindStringSubmatch(v)[1]
+			value, ok := vars[name]
+			if !ok {
+				return v
+			}
+			return value
+		})
+		if version := vars["version"]; version != "" {
+			replacement = "/v" + version + vars["prerelease"] + replacement
+		}
+	}
+	return "<" + replacement + `>; rel="successor-version"`
+}
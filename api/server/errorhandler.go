@@ -1,6 +1,7 @@
 package server
 
 import (
+	"encoding/json"
 	"net/http"
 
 	"github.com/docker/docker/api/server/httpstatus"
@@ -11,23 +12,66 @@ import (
 	"google.golang.org/grpc/status"
 )
 
+// Formats of error responses.
+const (
+	// ErrorFormatJSON is the default format of error responses: a JSON
+	// object with a "message" field.
+	ErrorFormatJSON = "json"
+	// ErrorFormatProblemJSON formats error responses as RFC 7807 problem
+	// details ("application/problem+json").
+	ErrorFormatProblemJSON = "problem+json"
+)
+
+// problemDetails is an RFC 7807 problem details document. It includes the
+// "message" field of types.ErrorResponse, for clients that expect it.
+type problemDetails struct {
+	Type    string `json:"type"`
+	Title   string `json:"title"`
+	Status  int    `json:"status"`
+	Detail  string `json:"detail"`
+	Message string `json:"message"`
+	Code    string `json:"code,omitempty"`
+}
+
 // makeErrorHandler makes an HTTP handler that decodes a Docker error and
-// returns it in the response.
-func makeErrorHandler(err error) http.HandlerFunc {
+// returns it in the response, in the format set by Config.ErrorFormat. The
+// response includes the machine-readable code of the error, as returned by
+// httpstatus.CodeFromError.
+func (s *Server) makeErrorHandler(err error) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		statusCode := httpstatus.FromError(err)
 		vars := mux.Vars(r)
-		if apiVersionSupportsJSONErrors(vars["version"]) {
-			response := &types.ErrorResponse{
-				Message: err.Error(),
-			}
-			_ = httputils.WriteJSON(w, statusCode, response)
-		} else {
+		if !apiVersionSupportsJSONErrors(vars["version"]) {
 			http.Error(w, status.Convert(err).Message(), statusCode)
+			return
 		}
+		if s.cfg.ErrorFormat == ErrorFormatProblemJSON {
+			writeProblem(w, statusCode, err)
+			return
+		}
+		response := &types.ErrorResponse{
+			Message: err.Error(),
+			Code:    httpstatus.CodeFromError(err, statusCode),
+		}
+		_ = httputils.WriteJSON(w, statusCode, response)
 	}
 }
 
+func writeProblem(w http.ResponseWriter, statusCode int, err error) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(statusCode)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(&problemDetails{
+		Type:    "about:blank",
+		Title:   http.StatusText(statusCode),
+		Status:  statusCode,
+		Detail:  err.Error(),
+		Message: err.Error(),
+		Code:    httpstatus.CodeFromError(err, statusCode),
+	})
+}
+
 func apiVersionSupportsJSONErrors(version string) bool {
 	const firstAPIVersionWithJSONErrors = "1.23"
 	return version == "" || versions.GreaterThan(version, firstAPIVersionWithJSONErrors)
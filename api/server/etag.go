@@ -0,0 +1,39 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/docker/docker/api/server/httputils"
+)
+
+// etagHandler returns a handler that sets an ETag header on the successful
+// (200) responses to GET and HEAD requests, computed from their body, and
+// replaces the response by a "304 Not Modified" if the request has an
+// If-None-Match header matching it, so that polling clients do not fetch an
+// unchanged response again. The response is buffered to compute the tag;
+// responses exceeding the size of the buffer are sent without a tag.
+func etagHandler(handler httputils.APIFunc) httputils.APIFunc {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			return handler(ctx, w, r, vars)
+		}
+		buf := newResponseBuffer(w)
+		if err := handler(ctx, buf, r, vars); err != nil {
+			buf.discard()
+			return err
+		}
+		if !buf.committed && buf.status == http.StatusOK {
+			etag := httputils.ETag(buf.buf.Bytes())
+			buf.header.Set("ETag", etag)
+			if httputils.ETagMatches(r.Header.Get("If-None-Match"), etag) {
+				buf.header.Del("Content-Type")
+				buf.header.Del("Content-Length")
+				buf.status = http.StatusNotModified
+				buf.buf.Reset()
+			}
+		}
+		_ = buf.commit()
+		return nil
+	}
+}
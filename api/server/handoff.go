@@ -0,0 +1,71 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"net"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// ListenerFile is a duplicate of the file descriptor of a listener of the
+// server, which can be passed to another process to continue serving on the
+// listener.
+type ListenerFile struct {
+	// Addr is the address of the listener, as passed to Accept or
+	// AcceptTLS.
+	Addr string
+	File *os.File
+}
+
+// ListenerFiles returns the files of the listeners of the server, so that
+// they can be handed off to a new process, for example, when upgrading the
+// daemon binary. The caller is responsible for closing the files.
+//
+// Unix sockets are no longer removed from the filesystem when the server
+// closes them, so that the process the files are handed off to can continue
+// to serve them.
+func (s *Server) ListenerFiles() ([]ListenerFile, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	files := make([]ListenerFile, 0, len(s.servers))
+	for _, srv := range s.servers {
+		f, err := listenerFile(srv.l)
+		if err != nil {
+			for _, lf := range files {
+				_ = lf.File.Close()
+			}
+			return nil, errors.Wrapf(err, "failed to get the file of listener %s", srv.addr)
+		}
+		files = append(files, ListenerFile{Addr: srv.srv.Addr, File: f})
+	}
+	return files, nil
+}
+
+// listenerFile returns a duplicate of the file descriptor of l, unwrapping
+// the listeners wrapped by the server.
+func listenerFile(l net.Listener) (*os.File, error) {
+	for {
+		switch v := l.(type) {
+		case *listenerRef:
+			l = v.Listener
+		case *connLimitListener:
+			l = v.Listener
+		case *backoffListener:
+			l = v.Listener
+		case *throttledListener:
+			l = v.Listener
+		case *proxyProtoListener:
+			l = v.Listener
+		case *tcpKeepAliveListener:
+			l = v.TCPListener
+		case *net.TCPListener:
+			return v.File()
+		case *net.UnixListener:
+			v.SetUnlinkOnClose(false)
+			return v.File()
+		default:
+			return nil, errors.Errorf("unsupported listener type %T", l)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/server/router"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestListenerFiles(t *testing.T) {
+	tcp, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	sock := filepath.Join(t.TempDir(), "docker.sock")
+	unix, err := net.Listen("unix", sock)
+	assert.NilError(t, err)
+
+	srv := &Server{cfg: &Config{}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/ping", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}),
+	}})
+	srv.Accept(tcp.Addr().String(), tcp)
+	srv.Accept(sock, unix)
+
+	files, err := srv.ListenerFiles()
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(files, 2))
+	assert.Check(t, is.Equal(files[0].Addr, tcp.Addr().String()))
+	assert.Check(t, is.Equal(files[1].Addr, sock))
+
+	srv.Close()
+	_, err = os.Stat(sock)
+	assert.Check(t, err, "unix socket removed when closing a listener that was handed off")
+
+	// the listeners can be served from the files once the server is closed.
+	l, err := net.FileListener(files[0].File)
+	assert.NilError(t, err)
+	for _, lf := range files {
+		assert.Check(t, lf.File.Close())
+	}
+	next := &Server{cfg: &Config{}}
+	next.InitRouter(srv.routers...)
+	next.Accept(tcp.Addr().String(), l)
+	go next.Wait(make(chan error, 1))
+	defer next.Close()
+	<-next.Ready()
+
+	resp, err := http.Get("http://" + tcp.Addr().String() + "/ping")
+	assert.NilError(t, err)
+	_ = resp.Body.Close()
+	assert.Check(t, is.Equal(resp.StatusCode, http.StatusNoContent))
+}
@@ -0,0 +1,163 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"runtime"
+
+	"github.com/docker/docker/api"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// Paths of the health endpoints. These endpoints are registered directly on
+// the mux, and are not subject to API versioning or middlewares, so that
+// they can be probed by orchestrators without authorization.
+const (
+	livenessPath  = "/_health/live"
+	readinessPath = "/_health/ready"
+)
+
+type notReadyError struct {
+	cause error
+}
+
+func (e notReadyError) Error() string {
+	if e.cause == nil {
+		return "daemon is not ready"
+	}
+	return "daemon is not ready: " + e.cause.Error()
+}
+
+func (e notReadyError) Cause() error {
+	return e.cause
+}
+
+func (notReadyError) Unavailable() {}
+
+type daemonStartingError struct{}
+
+func (daemonStartingError) Error() string {
+	return "the daemon is starting, and is not ready to handle requests yet; retry later"
+}
+
+func (daemonStartingError) Unavailable() {}
+
+// startupPingPath matches the paths of the ping endpoint, with or without a
+// version prefix, which is answered while the daemon is starting.
+var startupPingPath = regexp.MustCompile(`^(/v[0-9.]+)?` + pingPath + `$`)
+
+// SetHealthCheck sets the function used by the readiness endpoint to report
+// whether the daemon is ready to handle requests. The readiness endpoint
+// returns a "503 Service Unavailable" until a health check is set, and while
+// it returns an error.
+func (s *Server) SetHealthCheck(check func() error) {
+	s.mu.Lock()
+	s.healthCheck = check
+	s.mu.Unlock()
+}
+
+// SetInitialized signals that the daemon completed its initialization, so
+// that requests are no longer rejected if Config.RejectUntilInitialized is
+// set. It is independent of the health check, which may report the daemon
+// as not ready again, for example, while it is shutting down.
+func (s *Server) SetInitialized() {
+	s.mu.Lock()
+	s.initialized = true
+	s.mu.Unlock()
+}
+
+// Initialized returns whether the daemon completed its initialization, as
+// signaled with SetInitialized.
+func (s *Server) Initialized() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.initialized
+}
+
+// startupHandler returns a handler rejecting requests with a "503 Service
+// Unavailable" status until the daemon is initialized, so that clients get
+// a clear signal to retry, instead of a "404 Not Found" for the routes that
+// are not configured yet. The health endpoints are handled by handler, and
+// pings are answered with the API version of the server, so that clients
+// can negotiate it; the other requests are only passed to handler once
+// Initialized returns true.
+func (s *Server) startupHandler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Initialized() || r.URL.Path == livenessPath || r.URL.Path == readinessPath {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		if (r.Method == http.MethodGet || r.Method == http.MethodHead) && startupPingPath.MatchString(r.URL.Path) {
+			version := s.cfg.MaxAPIVersion
+			if version == "" {
+				version = api.DefaultVersion
+			}
+			w.Header().Set("Server", fmt.Sprintf("Docker/%s (%s)", s.cfg.Version, runtime.GOOS))
+			w.Header().Set("API-Version", version)
+			w.Header().Set("OSType", runtime.GOOS)
+			writeHealthy(w, r)
+			return
+		}
+		s.makeErrorHandler(daemonStartingError{})(w, r)
+	})
+}
+
+// SetDraining sets whether the server is draining. While draining, the
+// readiness endpoint returns a "503 Service Unavailable", so that load
+// balancers stop sending requests to the daemon, and a middleware created
+// by middleware.NewDrainMiddleware(s.Draining, ...) rejects requests that
+// modify the daemon's state.
+func (s *Server) SetDraining(draining bool) {
+	s.mu.Lock()
+	s.draining = draining
+	s.mu.Unlock()
+}
+
+// Draining returns whether the server is draining.
+func (s *Server) Draining() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.draining
+}
+
+// checkReady returns an error if the daemon is not ready.
+func (s *Server) checkReady() error {
+	s.mu.RLock()
+	check, draining := s.healthCheck, s.draining
+	s.mu.RUnlock()
+	if draining {
+		return notReadyError{cause: errors.New("daemon is draining")}
+	}
+	if check == nil {
+		return notReadyError{}
+	}
+	if err := check(); err != nil {
+		return notReadyError{cause: err}
+	}
+	return nil
+}
+
+func (s *Server) registerHealthRoutes(m *mux.Router) {
+	m.Path(livenessPath).Methods(http.MethodGet, http.MethodHead).HandlerFunc(writeHealthy)
+	m.Path(readinessPath).Methods(http.MethodGet, http.MethodHead).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := s.checkReady(); err != nil {
+			w.Header().Add("Cache-Control", "no-cache, no-store, must-revalidate")
+			s.makeErrorHandler(err)(w, r)
+			return
+		}
+		writeHealthy(w, r)
+	})
+}
+
+func writeHealthy(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Header().Add("Pragma", "no-cache")
+	if r.Method == http.MethodHead {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Length", "0")
+		return
+	}
+	_, _ = w.Write([]byte{'O', 'K'})
+}
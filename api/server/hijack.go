@@ -0,0 +1,114 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// hijackIdleWriter wraps the connections hijacked from its ResponseWriter
+// (for example, by container attach and exec start) in an idleConn, so that
+// they are closed once idle for the given timeout. It forwards http.Flusher
+// to the wrapped ResponseWriter.
+type hijackIdleWriter struct {
+	http.ResponseWriter
+	timeout time.Duration
+}
+
+// Flush implements http.Flusher.
+func (w *hijackIdleWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker. Only the data read and written through
+// the returned connection resets its idle timer, not the data of the
+// returned bufio.ReadWriter.
+func (w *hijackIdleWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not implement http.Hijacker")
+	}
+	conn, rw, err := h.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	return newIdleConn(conn, w.timeout), rw, nil
+}
+
+// idleConn is a connection that is closed once no data was read from or
+// written to it for its timeout, so that the streams of clients that went
+// away without closing them do not leak.
+type idleConn struct {
+	net.Conn
+	timeout    time.Duration
+	lastActive int64 // in nanoseconds since the epoch, updated atomically
+
+	mu    sync.Mutex
+	timer *time.Timer
+}
+
+func newIdleConn(conn net.Conn, timeout time.Duration) *idleConn {
+	c := &idleConn{Conn: conn, timeout: timeout, lastActive: time.Now().UnixNano()}
+	c.mu.Lock()
+	c.timer = time.AfterFunc(timeout, c.check)
+	c.mu.Unlock()
+	return c
+}
+
+// check closes the connection if it is idle, or checks it again once it
+// may be.
+func (c *idleConn) check() {
+	idle := time.Since(time.Unix(0, atomic.LoadInt64(&c.lastActive)))
+	if idle >= c.timeout {
+		log.WithField("remote-addr", c.RemoteAddr().String()).Debugf("closing hijacked connection idle for %v", idle.Round(time.Second))
+		_ = c.Conn.Close()
+		return
+	}
+	c.mu.Lock()
+	c.timer.Reset(c.timeout - idle)
+	c.mu.Unlock()
+}
+
+func (c *idleConn) active() {
+	atomic.StoreInt64(&c.lastActive, time.Now().UnixNano())
+}
+
+func (c *idleConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.active()
+	}
+	return n, err
+}
+
+func (c *idleConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if n > 0 {
+		c.active()
+	}
+	return n, err
+}
+
+func (c *idleConn) Close() error {
+	c.mu.Lock()
+	c.timer.Stop()
+	c.mu.Unlock()
+	return c.Conn.Close()
+}
+
+// CloseWrite closes the writing side of the connection, as expected by the
+// handlers of hijacked connections (see httputils.CloseStreams), or the
+// connection if it does not support it.
+func (c *idleConn) CloseWrite() error {
+	if cw, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return c.Close()
+}
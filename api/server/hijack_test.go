@@ -0,0 +1,72 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/server/httputils"
+	"github.com/docker/docker/api/server/router"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestHijackIdleTimeout(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+
+	const timeout = 100 * time.Millisecond
+	srv := &Server{cfg: &Config{HijackIdleTimeout: timeout}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewPostRoute("/echo", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			in, out, err := httputils.HijackConnection(w)
+			if err != nil {
+				return err
+			}
+			defer httputils.CloseStreams(in, out)
+			_, _ = io.WriteString(out, "HTTP/1.1 101 UPGRADED\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+			_, _ = io.Copy(out, in)
+			return nil
+		}),
+	}})
+	srv.Accept(l.Addr().String(), l)
+	waitChan := make(chan error, 1)
+	go srv.Wait(waitChan)
+	<-srv.Ready()
+	defer func() {
+		assert.Check(t, srv.Shutdown(context.Background()))
+		assert.Check(t, <-waitChan)
+	}()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	assert.NilError(t, err)
+	defer conn.Close()
+	_, err = io.WriteString(conn, "POST /v1.41/echo HTTP/1.1\r\nHost: docker\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+	assert.NilError(t, err)
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(resp.StatusCode, http.StatusSwitchingProtocols))
+
+	// the stream is kept open while data is sent
+	start := time.Now()
+	buf := make([]byte, 4)
+	for i := 0; i < 5; i++ {
+		time.Sleep(timeout / 2)
+		_, err = io.WriteString(conn, "ping")
+		assert.NilError(t, err)
+		_, err = io.ReadFull(br, buf)
+		assert.NilError(t, err)
+		assert.Check(t, is.Equal(string(buf), "ping"))
+	}
+	assert.Check(t, time.Since(start) > timeout)
+
+	// and closed once idle
+	_ = conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	_, err = br.ReadByte()
+	assert.Check(t, is.ErrorIs(err, io.EOF))
+}
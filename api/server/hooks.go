@@ -0,0 +1,100 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// RequestHookFunc is called before a request is handled.
+type RequestHookFunc func(ctx context.Context, r *http.Request)
+
+// RequestDoneHookFunc is called after a request is handled, with the status
+// code of its response.
+type RequestDoneHookFunc func(ctx context.Context, r *http.Request, status int)
+
+type requestHook struct {
+	before RequestHookFunc
+	after  RequestDoneHookFunc
+}
+
+// AddRequestHook adds callbacks that are called before and after every API
+// request, for side effects such as auditing, without the need to write a
+// middleware. Either callback can be nil. The hooks are called in the order
+// they were added, and after is called even if the request fails or the
+// handler panics. Like UseMiddleware, this needs to be called before the
+// API routes are configured.
+func (s *Server) AddRequestHook(before RequestHookFunc, after RequestDoneHookFunc) {
+	s.hooks = append(s.hooks, requestHook{before: before, after: after})
+}
+
+func runBeforeHooks(hooks []requestHook, r *http.Request) {
+	for _, h := range hooks {
+		if h.before != nil {
+			h.before(r.Context(), r)
+		}
+	}
+}
+
+func runAfterHooks(hooks []requestHook, r *http.Request, status int) {
+	for _, h := range hooks {
+		if h.after != nil {
+			h.after(r.Context(), r, status)
+		}
+	}
+}
+
+// hookStatusWriter records the status code of a response, for the hooks
+// called once the request is handled, and for recoverHandler to tell
+// whether the response was already written.
+type hookStatusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *hookStatusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *hookStatusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Status returns the status code of the response, which is "200 OK" if the
+// handler did not write one.
+func (w *hookStatusWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// Flush implements http.Flusher.
+func (w *hookStatusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker.
+func (w *hookStatusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not implement http.Hijacker")
+	}
+	if w.status == 0 {
+		// hijacked connections are handed over to the handler, which
+		// usually writes a raw "101 UPGRADED" or "200 OK" response.
+		w.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
@@ -0,0 +1,75 @@
+package httpstatus // import "github.com/docker/docker/api/server/httpstatus"
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Codes of the error classes in errdefs, as returned in the "code" field of
+// error responses. The codes of other errors are derived from their HTTP
+// status, such as "precondition_failed" for a "412 Precondition Failed".
+const (
+	CodeInvalidParameter = "invalid_parameter"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeConflict         = "conflict"
+	CodeNotModified      = "not_modified"
+	CodeNotImplemented   = "not_implemented"
+	CodeUnavailable      = "unavailable"
+	CodeInternal         = "internal"
+)
+
+// errorCoder is implemented by errors that have a more specific code than
+// the code of their HTTP status.
+type errorCoder interface {
+	APIErrorCode() string
+}
+
+// CodeFromError returns the stable, machine-readable code of err, so that
+// clients can tell errors apart without parsing their message. statusCode
+// is the HTTP status of err, as returned by FromError.
+func CodeFromError(err error, statusCode int) string {
+	for e := err; e != nil; {
+		if c, ok := e.(errorCoder); ok {
+			return c.APIErrorCode()
+		}
+		switch u := e.(type) {
+		case causer:
+			e = u.Cause()
+		case interface{ Unwrap() error }:
+			e = u.Unwrap()
+		default:
+			e = nil
+		}
+	}
+	return codeFromStatus(statusCode)
+}
+
+func codeFromStatus(statusCode int) string {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return CodeInvalidParameter
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusNotModified:
+		return CodeNotModified
+	case http.StatusNotImplemented:
+		return CodeNotImplemented
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	case http.StatusInternalServerError:
+		return CodeInternal
+	}
+	text := http.StatusText(statusCode)
+	if text == "" {
+		return CodeInternal
+	}
+	return strings.ToLower(strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text))
+}
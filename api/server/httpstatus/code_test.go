@@ -0,0 +1,37 @@
+package httpstatus // import "github.com/docker/docker/api/server/httpstatus"
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+type testCodedError struct{}
+
+func (testCodedError) Error() string        { return "coded" }
+func (testCodedError) InvalidParameter()    {}
+func (testCodedError) APIErrorCode() string { return "specific_code" }
+
+func TestCodeFromError(t *testing.T) {
+	for _, tc := range []struct {
+		err      error
+		expected string
+	}{
+		{err: errdefs.NotFound(errors.New("missing")), expected: CodeNotFound},
+		{err: errdefs.Conflict(errors.New("in use")), expected: CodeConflict},
+		{err: errdefs.InvalidParameter(errors.New("invalid")), expected: CodeInvalidParameter},
+		{err: errdefs.Unavailable(errors.New("busy")), expected: CodeUnavailable},
+		{err: errors.New("unclassified"), expected: CodeInternal},
+		{err: errors.Wrap(testCodedError{}, "wrapped"), expected: "specific_code"},
+	} {
+		assert.Check(t, is.Equal(CodeFromError(tc.err, FromError(tc.err)), tc.expected), tc.err.Error())
+	}
+
+	assert.Check(t, is.Equal(codeFromStatus(http.StatusRequestEntityTooLarge), "request_entity_too_large"))
+	assert.Check(t, is.Equal(codeFromStatus(http.StatusTeapot), "im_a_teapot"))
+	assert.Check(t, is.Equal(codeFromStatus(599), CodeInternal))
+}
@@ -16,6 +16,13 @@ type causer interface {
 	Cause() error
 }
 
+// httpStatusCoder is implemented by errors that map to an HTTP status code
+// which is not covered by the error classes in errdefs, such as the errors
+// produced by the API server itself when rejecting a request.
+type httpStatusCoder interface {
+	HTTPStatusCode() int
+}
+
 // FromError retrieves status code from error message.
 func FromError(err error) int {
 	if err == nil {
@@ -23,6 +30,10 @@ func FromError(err error) int {
 		return http.StatusInternalServerError
 	}
 
+	if statusCode := statusCodeFromHTTPStatusCoder(err); statusCode != 0 {
+		return statusCode
+	}
+
 	var statusCode int
 
 	// Stop right there
@@ -78,6 +89,25 @@ func FromError(err error) int {
 	return statusCode
 }
 
+// statusCodeFromHTTPStatusCoder returns the status code of the first error
+// in the causal chain that implements httpStatusCoder, or 0 if none does.
+func statusCodeFromHTTPStatusCoder(err error) int {
+	for err != nil {
+		if e, ok := err.(httpStatusCoder); ok {
+			return e.HTTPStatusCode()
+		}
+		switch e := err.(type) {
+		case causer:
+			err = e.Cause()
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		default:
+			return 0
+		}
+	}
+	return 0
+}
+
 // statusCodeFromGRPCError returns status code according to gRPC error
 func statusCodeFromGRPCError(err error) int {
 	switch status.Code(err) {
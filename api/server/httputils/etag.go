@@ -0,0 +1,34 @@
+package httputils // import "github.com/docker/docker/api/server/httputils"
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// ETag returns a strong entity tag for a response with the given body, which
+// is stable: responses with the same body have the same entity tag.
+func ETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// ETagMatches returns whether the value of an If-None-Match header, which is
+// "*" or a comma-separated list of entity tags, matches etag, using the weak
+// comparison of RFC 7232, which ignores the "W/" prefix of weak tags.
+func ETagMatches(ifNoneMatch, etag string) bool {
+	ifNoneMatch = strings.TrimSpace(ifNoneMatch)
+	if ifNoneMatch == "" {
+		return false
+	}
+	if ifNoneMatch == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
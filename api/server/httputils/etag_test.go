@@ -0,0 +1,28 @@
+package httputils // import "github.com/docker/docker/api/server/httputils"
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestETag(t *testing.T) {
+	etag := ETag([]byte(`{"Id":"abc"}`))
+	assert.Check(t, is.Equal(etag, ETag([]byte(`{"Id":"abc"}`))))
+	assert.Check(t, etag != ETag([]byte(`{"Id":"def"}`)))
+
+	for _, tc := range []struct {
+		ifNoneMatch string
+		expected    bool
+	}{
+		{ifNoneMatch: "", expected: false},
+		{ifNoneMatch: "*", expected: true},
+		{ifNoneMatch: etag, expected: true},
+		{ifNoneMatch: "W/" + etag, expected: true},
+		{ifNoneMatch: `"other", ` + etag, expected: true},
+		{ifNoneMatch: `"other"`, expected: false},
+	} {
+		assert.Check(t, is.Equal(ETagMatches(tc.ifNoneMatch, etag), tc.expected), tc.ifNoneMatch)
+	}
+}
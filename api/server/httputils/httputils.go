@@ -15,6 +15,33 @@ import (
 // APIVersionKey is the client's requested API version.
 type APIVersionKey struct{}
 
+// RequestIDKey is the unique identifier of the request, which is also sent
+// in the RequestIDHeader of the response.
+type RequestIDKey struct{}
+
+// RequestIDHeader is the header used to pass the request ID.
+const RequestIDHeader = "X-Request-ID"
+
+// RouteTemplateKey is the path template of the route that matched the
+// request, without the API version prefix (e.g. "/containers/{name:.*}/logs").
+type RouteTemplateKey struct{}
+
+// PeerCredKey is the PeerCred of the process that connected to the server
+// through a unix socket.
+type PeerCredKey struct{}
+
+// ClientCertSubjectKey is the subject of the verified TLS client certificate
+// of the request (e.g. "CN=alice,O=ops").
+type ClientCertSubjectKey struct{}
+
+// PeerCred holds the credentials of the process that connected to a unix
+// socket, as reported by the kernel (SO_PEERCRED).
+type PeerCred struct {
+	PID int32
+	UID uint32
+	GID uint32
+}
+
 // APIFunc is an adapter to allow the use of ordinary functions as Docker API endpoints.
 // Any function that has the appropriate signature can be registered as an API endpoint (e.g. getVersion).
 type APIFunc func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error
@@ -57,6 +84,16 @@ func CheckForJSON(r *http.Request) error {
 	return matchesContentType(ct, "application/json")
 }
 
+// ExpectsContinue returns whether the client sent the request with an
+// "Expect: 100-continue" header, waiting for the server to accept the request
+// before sending its body. The "100 Continue" response is sent by net/http
+// when the body is first read, so that handlers, and the middlewares before
+// them, can reject such requests without receiving their body, as long as
+// they do not read it before.
+func ExpectsContinue(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Expect"), "100-continue")
+}
+
 // ReadJSON validates the request to have the correct content-type, and decodes
 // the request's Body into out.
 func ReadJSON(r *http.Request, out interface{}) error {
@@ -107,20 +144,65 @@ func ParseForm(r *http.Request) error {
 	return nil
 }
 
-// VersionFromContext returns an API version from the context using APIVersionKey.
-// It panics if the context value does not have version.Version type.
-func VersionFromContext(ctx context.Context) string {
+// VersionFromContext returns the API version of the request from the
+// context using APIVersionKey, as set by the version middleware, so that
+// handlers compare it using its methods, rather than parsing it. It returns
+// an empty APIVersion if the context has no API version, and panics if the
+// context value is not a string.
+func VersionFromContext(ctx context.Context) APIVersion {
 	if ctx == nil {
 		return ""
 	}
 
 	if val := ctx.Value(APIVersionKey{}); val != nil {
-		return val.(string)
+		return APIVersion(val.(string))
 	}
 
 	return ""
 }
 
+// RequestIDFromContext returns the request ID from the context using
+// RequestIDKey, or an empty string if the context has no request ID.
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(RequestIDKey{}).(string)
+	return id
+}
+
+// RouteTemplateFromContext returns the path template of the route that
+// matched the request from the context using RouteTemplateKey, or an empty
+// string if the context has no route template.
+func RouteTemplateFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	tpl, _ := ctx.Value(RouteTemplateKey{}).(string)
+	return tpl
+}
+
+// PeerCredFromContext returns the credentials of the process that sent the
+// request, if the request was received on a unix socket.
+func PeerCredFromContext(ctx context.Context) (PeerCred, bool) {
+	if ctx == nil {
+		return PeerCred{}, false
+	}
+	cred, ok := ctx.Value(PeerCredKey{}).(PeerCred)
+	return cred, ok
+}
+
+// ClientCertSubjectFromContext returns the subject of the verified TLS client
+// certificate of the request from the context using ClientCertSubjectKey, or
+// an empty string if the request had no verified client certificate.
+func ClientCertSubjectFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	subject, _ := ctx.Value(ClientCertSubjectKey{}).(string)
+	return subject
+}
+
 // matchesContentType validates the content type against the expected one
 func matchesContentType(contentType, expectedType string) error {
 	mimetype, _, err := mime.ParseMediaType(contentType)
@@ -0,0 +1,79 @@
+package httputils // import "github.com/docker/docker/api/server/httputils"
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/versions"
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+)
+
+// APIVersion is an API version, such as "1.41", as returned by
+// VersionFromContext. It is compared to other versions using the functions
+// of the api/types/versions package, so that handlers get the same result
+// whether they use its methods or these functions.
+type APIVersion string
+
+// ParseAPIVersion returns v as an APIVersion, if it is a dot-separated list
+// of non-negative integers.
+func ParseAPIVersion(v string) (APIVersion, error) {
+	if v == "" {
+		return "", errdefs.InvalidParameter(errors.New("invalid API version: version is empty"))
+	}
+	for _, part := range strings.Split(v, ".") {
+		if n, err := strconv.Atoi(part); err != nil || n < 0 {
+			return "", errdefs.InvalidParameter(errors.Errorf("invalid API version: %s", v))
+		}
+	}
+	return APIVersion(v), nil
+}
+
+// String returns the version as a string.
+func (v APIVersion) String() string {
+	return string(v)
+}
+
+// IsZero returns true if v is empty, which VersionFromContext returns if the
+// context has no API version.
+func (v APIVersion) IsZero() bool {
+	return v == ""
+}
+
+// Compare returns -1, 0, or 1 if v is lower than, equal to, or greater
+// than other.
+func (v APIVersion) Compare(other APIVersion) int {
+	switch {
+	case versions.LessThan(string(v), string(other)):
+		return -1
+	case versions.GreaterThan(string(v), string(other)):
+		return 1
+	default:
+		return 0
+	}
+}
+
+// LessThan checks if v is less than other.
+func (v APIVersion) LessThan(other string) bool {
+	return versions.LessThan(string(v), other)
+}
+
+// LessThanOrEqualTo checks if v is less than or equal to other.
+func (v APIVersion) LessThanOrEqualTo(other string) bool {
+	return versions.LessThanOrEqualTo(string(v), other)
+}
+
+// GreaterThan checks if v is greater than other.
+func (v APIVersion) GreaterThan(other string) bool {
+	return versions.GreaterThan(string(v), other)
+}
+
+// GreaterThanOrEqualTo checks if v is greater than or equal to other.
+func (v APIVersion) GreaterThanOrEqualTo(other string) bool {
+	return versions.GreaterThanOrEqualTo(string(v), other)
+}
+
+// Equal checks if v is equal to other.
+func (v APIVersion) Equal(other string) bool {
+	return versions.Equal(string(v), other)
+}
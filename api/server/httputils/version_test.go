@@ -0,0 +1,61 @@
+package httputils // import "github.com/docker/docker/api/server/httputils"
+
+import (
+	"context"
+	"testing"
+
+	"github.com/docker/docker/api/types/versions"
+	"github.com/docker/docker/errdefs"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestParseAPIVersion(t *testing.T) {
+	v, err := ParseAPIVersion("1.41")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(v.String(), "1.41"))
+	assert.Check(t, v.Equal("1.41.0"))
+	assert.Check(t, v.LessThan("1.42"))
+	assert.Check(t, v.LessThanOrEqualTo("1.41"))
+	assert.Check(t, v.GreaterThan("1.9"))
+	assert.Check(t, v.GreaterThanOrEqualTo("1.41"))
+	assert.Check(t, !v.GreaterThan("1.41"))
+
+	other, err := ParseAPIVersion("1.40")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(v.Compare(other), 1))
+	assert.Check(t, is.Equal(other.Compare(v), -1))
+	assert.Check(t, is.Equal(v.Compare(v), 0))
+
+	for _, invalid := range []string{"", "1..41", "v1.41", "1.-1"} {
+		_, err := ParseAPIVersion(invalid)
+		assert.Check(t, errdefs.IsInvalidParameter(err), invalid)
+	}
+}
+
+func TestVersionFromContext(t *testing.T) {
+	assert.Check(t, VersionFromContext(context.Background()).IsZero())
+
+	ctx := context.WithValue(context.Background(), APIVersionKey{}, "1.41")
+	assert.Check(t, !VersionFromContext(ctx).IsZero())
+	assert.Check(t, VersionFromContext(ctx).Equal("1.41"))
+}
+
+func TestAPIVersionMatchesVersions(t *testing.T) {
+	// APIVersion compares versions like the api/types/versions package,
+	// including invalid segments, which compare as zero.
+	for _, tc := range [][2]string{
+		{"1.41", "1.42"},
+		{"1.41", "1.41.0"},
+		{"1.9", "1.10"},
+		{"1.x", "1.0"},
+		{"2", "1.99"},
+	} {
+		v, other := APIVersion(tc[0]), tc[1]
+		assert.Check(t, is.Equal(v.LessThan(other), versions.LessThan(tc[0], other)), tc)
+		assert.Check(t, is.Equal(v.LessThanOrEqualTo(other), versions.LessThanOrEqualTo(tc[0], other)), tc)
+		assert.Check(t, is.Equal(v.GreaterThan(other), versions.GreaterThan(tc[0], other)), tc)
+		assert.Check(t, is.Equal(v.GreaterThanOrEqualTo(other), versions.GreaterThanOrEqualTo(tc[0], other)), tc)
+		assert.Check(t, is.Equal(v.Equal(other), versions.Equal(tc[0], other)), tc)
+	}
+}
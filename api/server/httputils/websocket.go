@@ -0,0 +1,64 @@
+package httputils // import "github.com/docker/docker/api/server/httputils"
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/websocket"
+)
+
+// IsWebSocketUpgrade returns whether the request asks to upgrade the
+// connection to the WebSocket protocol.
+func IsWebSocketUpgrade(r *http.Request) bool {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return false
+	}
+	for _, v := range r.Header.Values("Connection") {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// UpgradeWebSocket performs the WebSocket handshake of the request, and
+// returns the resulting connection, as an alternative to HijackConnection
+// for clients (such as browsers) that cannot use raw connections. Data is
+// sent in binary frames if binary is set, and in text frames otherwise.
+//
+// The connection is closed when release is called, which the caller must do
+// once it stopped using the connection. The connection is hijacked even if
+// the handshake fails, in which case the error response was already written,
+// and the returned error must not be written to w.
+func UpgradeWebSocket(w http.ResponseWriter, r *http.Request, binary bool) (conn io.ReadWriteCloser, release func(), err error) {
+	conns := make(chan *websocket.Conn)
+	released := make(chan struct{})
+	served := make(chan struct{})
+	srv := websocket.Server{Handler: func(c *websocket.Conn) {
+		conns <- c
+		<-released
+	}}
+	go func() {
+		defer close(served)
+		srv.ServeHTTP(w, r)
+	}()
+
+	select {
+	case c := <-conns:
+		if binary {
+			c.PayloadType = websocket.BinaryFrame
+		}
+		var once sync.Once
+		return c, func() {
+			once.Do(func() { close(released) })
+			<-served
+		}, nil
+	case <-served:
+		return nil, nil, errors.New("websocket handshake failed")
+	}
+}
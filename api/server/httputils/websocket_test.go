@@ -0,0 +1,76 @@
+package httputils // import "github.com/docker/docker/api/server/httputils"
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/websocket"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestIsWebSocketUpgrade(t *testing.T) {
+	for _, tc := range []struct {
+		upgrade, connection string
+		expected            bool
+	}{
+		{upgrade: "websocket", connection: "Upgrade", expected: true},
+		{upgrade: "WebSocket", connection: "keep-alive, upgrade", expected: true},
+		{upgrade: "tcp", connection: "Upgrade"},
+		{upgrade: "websocket"},
+		{connection: "Upgrade"},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/containers/foo/attach/ws", nil)
+		if tc.upgrade != "" {
+			r.Header.Set("Upgrade", tc.upgrade)
+		}
+		if tc.connection != "" {
+			r.Header.Set("Connection", tc.connection)
+		}
+		assert.Check(t, is.Equal(IsWebSocketUpgrade(r), tc.expected), "Upgrade: %q, Connection: %q", tc.upgrade, tc.connection)
+	}
+}
+
+func TestUpgradeWebSocket(t *testing.T) {
+	errs := make(chan error, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, release, err := UpgradeWebSocket(w, r, true)
+		if err != nil {
+			errs <- err
+			return
+		}
+		defer release()
+		// echo the message received
+		buf := make([]byte, 64)
+		n, err := conn.Read(buf)
+		if err == nil {
+			_, err = conn.Write(buf[:n])
+		}
+		errs <- err
+	}))
+	defer srv.Close()
+
+	url := strings.Replace(srv.URL, "http://", "ws://", 1)
+	conn, err := websocket.Dial(url, "", srv.URL)
+	assert.NilError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("hello"))
+	assert.NilError(t, err)
+	var msg []byte
+	assert.NilError(t, websocket.Message.Receive(conn, &msg))
+	assert.Check(t, is.Equal(string(msg), "hello"))
+	assert.Check(t, <-errs)
+
+	// The server closes the connection once released.
+	_, err = conn.Read(make([]byte, 1))
+	assert.Check(t, is.Equal(err, io.EOF))
+
+	resp, err := http.Get(srv.URL)
+	assert.NilError(t, err)
+	resp.Body.Close()
+	assert.Check(t, is.Equal(resp.StatusCode, http.StatusBadRequest))
+	assert.Check(t, is.ErrorContains(<-errs, "websocket handshake failed"))
+}
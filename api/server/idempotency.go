@@ -0,0 +1,268 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"bufio"
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/server/httputils"
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+)
+
+const (
+	// IdempotencyKeyHeader is the header through which clients pass the
+	// key identifying a request that may be retried.
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// idempotentReplayedHeader is set on responses that are replayed from
+	// the cache.
+	idempotentReplayedHeader = "Idempotent-Replayed"
+
+	// DefaultIdempotencyCacheSize is the number of responses cached for
+	// idempotency keys when Config.IdempotencyCacheSize is not set.
+	DefaultIdempotencyCacheSize = 1000
+
+	// maxIdempotentResponseBytes is the maximum size of the response bodies
+	// that are cached. Larger responses are not replayed.
+	maxIdempotentResponseBytes = 1 << 20
+
+	// maxIdempotencyKeyLength is the maximum length of idempotency keys.
+	maxIdempotencyKeyLength = 255
+)
+
+type idempotencyConflictError struct{}
+
+func (idempotencyConflictError) Error() string {
+	return "a request with the same idempotency key is in progress"
+}
+
+func (idempotencyConflictError) Conflict() {}
+
+type idempotencyMismatchError struct{}
+
+func (idempotencyMismatchError) Error() string {
+	return "the idempotency key was used for a request with a different body"
+}
+
+func (idempotencyMismatchError) HTTPStatusCode() int {
+	return http.StatusUnprocessableEntity
+}
+
+// cachedResponse is a response cached for an idempotency key. Its done
+// channel is closed once the request completed. digest is the digest of the
+// body of the request, which retries must send unmodified.
+type cachedResponse struct {
+	key     string
+	digest  string
+	expires time.Time
+	done    chan struct{}
+
+	status int
+	header http.Header
+	body   []byte
+}
+
+// idempotencyCache is an LRU cache of the responses of requests with an
+// idempotency key.
+type idempotencyCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	lru     *list.List // of *cachedResponse, most recently used first
+	entries map[string]*list.Element
+}
+
+func newIdempotencyCache(size int, ttl time.Duration) *idempotencyCache {
+	if size <= 0 {
+		size = DefaultIdempotencyCacheSize
+	}
+	return &idempotencyCache{
+		size:    size,
+		ttl:     ttl,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// start returns the cached response for key, if any. Otherwise, it adds a
+// pending response for key, for a request with a body of the given digest,
+// which must be completed with finish or removed with abort, and returns
+// false.
+func (c *idempotencyCache) start(key, digest string) (*cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if e, ok := c.entries[key]; ok {
+		resp := e.Value.(*cachedResponse)
+		if now.Before(resp.expires) {
+			c.lru.MoveToFront(e)
+			return resp, true
+		}
+		c.remove(e)
+	}
+	resp := &cachedResponse{key: key, digest: digest, expires: now.Add(c.ttl), done: make(chan struct{})}
+	c.entries[key] = c.lru.PushFront(resp)
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+	return resp, false
+}
+
+func (c *idempotencyCache) finish(resp *cachedResponse) {
+	close(resp.done)
+}
+
+// abort removes the pending response resp, so that the request is handled
+// again when retried.
+func (c *idempotencyCache) abort(resp *cachedResponse) {
+	c.mu.Lock()
+	if e, ok := c.entries[resp.key]; ok && e.Value == resp {
+		c.remove(e)
+	}
+	c.mu.Unlock()
+	close(resp.done)
+}
+
+func (c *idempotencyCache) remove(e *list.Element) {
+	c.lru.Remove(e)
+	delete(c.entries, e.Value.(*cachedResponse).key)
+}
+
+// idempotencyCacheKey returns the key of the responses cached for requests
+// to the path of r with the given idempotency key. Keys are scoped to the
+// client that sent the request, as identified by its verified TLS client
+// certificate, or by its user if it connected through a unix socket, so that
+// clients cannot obtain the responses of requests sent by others.
+func idempotencyCacheKey(r *http.Request, key string) string {
+	var client string
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		client = "tls:" + r.TLS.VerifiedChains[0][0].Subject.String()
+	} else if cred, ok := httputils.PeerCredFromContext(r.Context()); ok {
+		client = "uid:" + strconv.FormatUint(uint64(cred.UID), 10)
+	}
+	return client + " " + r.Method + " " + r.URL.Path + " " + key
+}
+
+// idempotentHandler returns a handler that replays the response of a
+// previous successful request with the same idempotency key to the same
+// route, instead of calling handler again. Requests without an idempotency
+// key are passed to handler. Only successful (2xx) responses are cached, so
+// that failed requests can be retried. Requests reusing the key of a request
+// with a different body are rejected with a "422 Unprocessable Entity".
+func idempotentHandler(handler httputils.APIFunc, cache *idempotencyCache) httputils.APIFunc {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			return handler(ctx, w, r, vars)
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return errdefs.InvalidParameter(errors.Errorf("%s header exceeds %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength))
+		}
+
+		digest := sha256.New()
+		if r.Body != nil && r.Body != http.NoBody {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				return err
+			}
+			digest.Write(body)
+			r.Body = struct {
+				io.Reader
+				io.Closer
+			}{bytes.NewReader(body), r.Body}
+		}
+
+		sum := hex.EncodeToString(digest.Sum(nil))
+
+		resp, cached := cache.start(idempotencyCacheKey(r, key), sum)
+		if cached {
+			if resp.digest != sum {
+				return idempotencyMismatchError{}
+			}
+			select {
+			case <-resp.done:
+			default:
+				return idempotencyConflictError{}
+			}
+			for k, v := range resp.header {
+				if k != httputils.RequestIDHeader {
+					w.Header()[k] = v
+				}
+			}
+			w.Header().Set(idempotentReplayedHeader, "true")
+			w.WriteHeader(resp.status)
+			_, _ = w.Write(resp.body)
+			return nil
+		}
+
+		rec := &responseCapture{ResponseWriter: w}
+		err := handler(ctx, rec, r, vars)
+		if err != nil || rec.status < 200 || rec.status > 299 || rec.overflow {
+			cache.abort(resp)
+			return err
+		}
+		resp.status = rec.status
+		resp.header = w.Header().Clone()
+		resp.body = rec.body
+		cache.finish(resp)
+		return nil
+	}
+}
+
+// responseCapture records the status and body of a response, up to
+// maxIdempotentResponseBytes. It forwards http.Flusher and http.Hijacker to
+// the wrapped ResponseWriter; hijacked responses are not cached.
+type responseCapture struct {
+	http.ResponseWriter
+	status   int
+	body     []byte
+	overflow bool
+}
+
+func (c *responseCapture) WriteHeader(code int) {
+	if c.status == 0 {
+		c.status = code
+	}
+	c.ResponseWriter.WriteHeader(code)
+}
+
+func (c *responseCapture) Write(b []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	if len(c.body)+len(b) > maxIdempotentResponseBytes {
+		c.overflow = true
+	} else if !c.overflow {
+		c.body = append(c.body, b...)
+	}
+	return c.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher.
+func (c *responseCapture) Flush() {
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker.
+func (c *responseCapture) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := c.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not implement http.Hijacker")
+	}
+	// the response written on the hijacked connection cannot be replayed
+	c.overflow = true
+	return h.Hijack()
+}
@@ -0,0 +1,153 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/server/httputils"
+	"github.com/docker/docker/api/server/router"
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestIdempotencyKey(t *testing.T) {
+	var created int
+	fail := false
+	create := func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		if fail {
+			return errdefs.System(errors.New("daemon failed"))
+		}
+		created++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"Id":"` + strconv.Itoa(created) + `"}`))
+		return nil
+	}
+	srv := &Server{cfg: &Config{IdempotencyKeyTTL: time.Minute}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewPostRoute("/containers/create", create, router.WithIdempotency()),
+		router.NewPostRoute("/volumes/create", create),
+	}})
+	m := srv.createMux()
+
+	post := func(path, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"Image":"busybox"}`))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		resp := httptest.NewRecorder()
+		m.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := post("/v1.41/containers/create", "key-1")
+	assert.Check(t, is.Equal(resp.Code, http.StatusCreated))
+	assert.Check(t, is.Equal(resp.Body.String(), `{"Id":"1"}`))
+	assert.Check(t, is.Equal(resp.Header().Get(idempotentReplayedHeader), ""))
+
+	resp = post("/v1.41/containers/create", "key-1")
+	assert.Check(t, is.Equal(resp.Code, http.StatusCreated))
+	assert.Check(t, is.Equal(resp.Body.String(), `{"Id":"1"}`))
+	assert.Check(t, is.Equal(resp.Header().Get("Content-Type"), "application/json"))
+	assert.Check(t, is.Equal(resp.Header().Get(idempotentReplayedHeader), "true"))
+	assert.Check(t, is.Equal(created, 1))
+
+	assert.Check(t, is.Equal(post("/v1.41/containers/create", "key-2").Body.String(), `{"Id":"2"}`))
+	assert.Check(t, is.Equal(post("/v1.41/containers/create", "").Body.String(), `{"Id":"3"}`))
+
+	// routes that did not opt in ignore the key
+	assert.Check(t, is.Equal(post("/v1.41/volumes/create", "key-1").Body.String(), `{"Id":"4"}`))
+	assert.Check(t, is.Equal(post("/v1.41/volumes/create", "key-1").Body.String(), `{"Id":"5"}`))
+
+	// failed requests are not cached, so that they can be retried
+	fail = true
+	assert.Check(t, is.Equal(post("/v1.41/containers/create", "key-3").Code, http.StatusInternalServerError))
+	fail = false
+	assert.Check(t, is.Equal(post("/v1.41/containers/create", "key-3").Body.String(), `{"Id":"6"}`))
+
+	// the key cannot be reused for a request with a different body
+	req := httptest.NewRequest(http.MethodPost, "/v1.41/containers/create", strings.NewReader(`{"Image":"alpine"}`))
+	req.Header.Set(IdempotencyKeyHeader, "key-1")
+	resp = httptest.NewRecorder()
+	m.ServeHTTP(resp, req)
+	assert.Check(t, is.Equal(resp.Code, http.StatusUnprocessableEntity))
+	assert.Check(t, is.Equal(created, 6))
+}
+
+func TestIdempotencyKeyScope(t *testing.T) {
+	req := func(uid uint32, subject string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/containers/create", nil)
+		if subject != "" {
+			cert := &x509.Certificate{Subject: pkix.Name{CommonName: subject}}
+			r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		} else {
+			r = r.WithContext(context.WithValue(r.Context(), httputils.PeerCredKey{}, httputils.PeerCred{UID: uid}))
+		}
+		return r
+	}
+	assert.Check(t, is.Equal(idempotencyCacheKey(req(1000, ""), "a"), idempotencyCacheKey(req(1000, ""), "a")))
+	assert.Check(t, idempotencyCacheKey(req(1000, ""), "a") != idempotencyCacheKey(req(0, ""), "a"))
+	assert.Check(t, is.Equal(idempotencyCacheKey(req(0, "alice"), "a"), idempotencyCacheKey(req(0, "alice"), "a")))
+	assert.Check(t, idempotencyCacheKey(req(0, "alice"), "a") != idempotencyCacheKey(req(0, "bob"), "a"))
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestIdempotencyCache(t *testing.T) {
+	t.Run("pending", func(t *testing.T) {
+		c := newIdempotencyCache(10, time.Minute)
+		req := httptest.NewRequest(http.MethodPost, "/containers/create", nil)
+		req.Header.Set(IdempotencyKeyHeader, "a")
+		resp, cached := c.start(idempotencyCacheKey(req, "a"), sha256Hex(""))
+		assert.Assert(t, !cached)
+		defer c.abort(resp)
+
+		handler := idempotentHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			t.Error("handler called while the request is pending")
+			return nil
+		}, c)
+		err := handler(context.Background(), httptest.NewRecorder(), req, nil)
+		assert.Check(t, errdefs.IsConflict(err), "got %v", err)
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		c := newIdempotencyCache(10, time.Millisecond)
+		resp, _ := c.start("a", "")
+		c.finish(resp)
+		time.Sleep(5 * time.Millisecond)
+		_, cached := c.start("a", "")
+		assert.Check(t, !cached)
+	})
+
+	t.Run("eviction", func(t *testing.T) {
+		c := newIdempotencyCache(2, time.Minute)
+		for _, k := range []string{"a", "b"} {
+			resp, _ := c.start(k, "")
+			c.finish(resp)
+		}
+		_, cached := c.start("a", "") // "b" is now the least recently used
+		assert.Check(t, cached)
+		resp, _ := c.start("c", "")
+		c.finish(resp)
+
+		_, cached = c.start("a", "")
+		assert.Check(t, cached)
+		_, cached = c.start("b", "")
+		assert.Check(t, !cached)
+	})
+}
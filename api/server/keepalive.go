@@ -0,0 +1,45 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"net"
+	"time"
+)
+
+// DefaultTCPKeepAlivePeriod is the period of TCP keep-alive probes used when
+// Config.TCPKeepAlivePeriod is not set.
+const DefaultTCPKeepAlivePeriod = 3 * time.Minute
+
+// tcpKeepAliveListener sets TCP keep-alive on the connections accepted by a
+// TCP listener, so that connections from clients that went away without
+// closing them, such as crashed hosts, are eventually closed.
+type tcpKeepAliveListener struct {
+	*net.TCPListener
+	enabled bool
+	period  time.Duration
+}
+
+// withTCPKeepAlive returns l, setting TCP keep-alive on its connections
+// according to cfg if it is a TCP listener.
+func withTCPKeepAlive(l net.Listener, cfg *Config) net.Listener {
+	tl, ok := l.(*net.TCPListener)
+	if !ok {
+		return l
+	}
+	period := cfg.TCPKeepAlivePeriod
+	if period <= 0 {
+		period = DefaultTCPKeepAlivePeriod
+	}
+	return &tcpKeepAliveListener{TCPListener: tl, enabled: !cfg.DisableTCPKeepAlive, period: period}
+}
+
+func (l *tcpKeepAliveListener) Accept() (net.Conn, error) {
+	c, err := l.AcceptTCP()
+	if err != nil {
+		return nil, err
+	}
+	_ = c.SetKeepAlive(l.enabled)
+	if l.enabled {
+		_ = c.SetKeepAlivePeriod(l.period)
+	}
+	return c, nil
+}
@@ -0,0 +1,44 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestTCPKeepAlive(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	defer l.Close()
+
+	kl, ok := withTCPKeepAlive(l, &Config{}).(*tcpKeepAliveListener)
+	assert.Assert(t, ok, "expected TCP listener to be wrapped")
+	assert.Check(t, kl.enabled)
+	assert.Check(t, is.Equal(kl.period, DefaultTCPKeepAlivePeriod))
+
+	kl = withTCPKeepAlive(l, &Config{DisableTCPKeepAlive: true, TCPKeepAlivePeriod: time.Minute}).(*tcpKeepAliveListener)
+	assert.Check(t, !kl.enabled)
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, err := kl.Accept()
+		assert.Check(t, err)
+		accepted <- c
+	}()
+	c, err := net.Dial("tcp", l.Addr().String())
+	assert.NilError(t, err)
+	defer c.Close()
+	sc := <-accepted
+	defer sc.Close()
+	_, ok = sc.(*net.TCPConn)
+	assert.Check(t, ok, "expected a *net.TCPConn, got %T", sc)
+
+	ul, err := net.Listen("unix", filepath.Join(t.TempDir(), "docker.sock"))
+	assert.NilError(t, err)
+	defer ul.Close()
+	assert.Check(t, withTCPKeepAlive(ul, &Config{}) == ul)
+}
@@ -0,0 +1,131 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/server/middleware"
+	"github.com/docker/docker/errdefs"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+type methodNotAllowedError struct {
+	method string
+}
+
+func (e methodNotAllowedError) Error() string {
+	return fmt.Sprintf("method %s not allowed", e.method)
+}
+
+func (methodNotAllowedError) HTTPStatusCode() int {
+	return http.StatusMethodNotAllowed
+}
+
+// trailingCatchAll matches a path template ending with a variable matching
+// any string, such as "/containers/{name:.*}".
+var trailingCatchAll = regexp.MustCompile(`\{([^}:]+):\.\*\}$`)
+
+// allowedMethods collects the methods that are registered for each path
+// template, to produce a "405 Method Not Allowed" response, including an
+// "Allow" header, for requests that match a path, but not its method.
+//
+// A variable matching any string at the end of a template only matches a
+// single path segment, as it would otherwise match any path below it: the
+// methods of "/containers/{name:.*}" are not allowed for a mistyped
+// "/containers/foo/jsn", which is not found instead.
+type allowedMethods struct {
+	m        *mux.Router
+	routes   []*mux.Route
+	methods  map[*mux.Route]map[string]struct{}
+	byPath   map[string]*mux.Route
+	catchAll map[*mux.Route]string
+
+	errorHandler func(err error) http.HandlerFunc
+}
+
+func newAllowedMethods(errorHandler func(err error) http.HandlerFunc) *allowedMethods {
+	return &allowedMethods{
+		m:            mux.NewRouter(),
+		methods:      make(map[*mux.Route]map[string]struct{}),
+		byPath:       make(map[string]*mux.Route),
+		catchAll:     make(map[*mux.Route]string),
+		errorHandler: errorHandler,
+	}
+}
+
+// add registers method as an allowed method for the given path template.
+func (a *allowedMethods) add(path, method string) {
+	route, ok := a.byPath[path]
+	if !ok {
+		route = a.m.Path(path)
+		a.byPath[path] = route
+		a.routes = append(a.routes, route)
+		a.methods[route] = make(map[string]struct{})
+		if m := trailingCatchAll.FindStringSubmatch(path); m != nil {
+			a.catchAll[route] = m[1]
+		}
+	}
+	a.methods[route][method] = struct{}{}
+}
+
+// lookup returns the sorted list of methods allowed for the request's path.
+func (a *allowedMethods) lookup(r *http.Request) []string {
+	set := make(map[string]struct{})
+	for _, route := range a.routes {
+		var match mux.RouteMatch
+		if !route.Match(r, &match) {
+			continue
+		}
+		if name, ok := a.catchAll[route]; ok && strings.Contains(match.Vars[name], "/") {
+			continue
+		}
+		for method := range a.methods[route] {
+			set[method] = struct{}{}
+		}
+	}
+	methods := make([]string, 0, len(set))
+	for method := range set {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// handler returns an http.HandlerFunc that returns a "405 Method Not Allowed"
+// if the request's path is registered with other methods, or calls notFound
+// otherwise.
+func (a *allowedMethods) handler(notFound http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		methods := a.lookup(r)
+		if len(methods) == 0 {
+			notFound(w, r)
+			return
+		}
+		w.Header().Set("Allow", strings.Join(methods, ", "))
+		a.errorHandler(methodNotAllowedError{method: r.Method})(w, r)
+	}
+}
+
+// preflight returns an http.HandlerFunc that responds to CORS preflight
+// requests for registered paths, before they reach next. Preflight requests
+// from origins that are not allowed are rejected with a "403 Forbidden".
+func (a *allowedMethods) preflight(cors *middleware.CORSMiddleware, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !middleware.IsPreflightRequest(r) {
+			next(w, r)
+			return
+		}
+		methods := a.lookup(r)
+		if len(methods) == 0 {
+			next(w, r)
+			return
+		}
+		if !cors.Preflight(w, r, methods) {
+			a.errorHandler(errdefs.Forbidden(errors.Errorf("origin %s is not allowed", r.Header.Get("Origin"))))(w, r)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/docker/docker/api/server/router"
+	"github.com/docker/docker/api/server/router/debug"
+	metrics "github.com/docker/go-metrics"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// metricsPath is the path of the endpoint exposing the metrics of the
+// daemon in the Prometheus format.
+const metricsPath = "/metrics"
+
+// metricsReadHeaderTimeout is the time allowed to read the headers of the
+// requests on the metrics listener, so that idle clients cannot hold its
+// connections open.
+const metricsReadHeaderTimeout = 10 * time.Second
+
+// getMetrics serves the metrics on the API listeners, where, unlike on the
+// metrics listener, the requests are subject to the middlewares (including
+// authorization) of API requests.
+func getMetrics(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	metrics.Handler().ServeHTTP(w, r)
+	return nil
+}
+
+// metricsServerLocked returns the HTTPServer of the dedicated metrics
+// listener, listening on Config.MetricsAddr if it is not yet. It returns nil
+// if no metrics address is configured. It must be called with s.mu held.
+func (s *Server) metricsServerLocked() (*HTTPServer, error) {
+	if s.cfg.MetricsAddr == "" || s.metrics != nil {
+		return s.metrics, nil
+	}
+	l, err := net.Listen("tcp", s.cfg.MetricsAddr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to listen on metrics address %s", s.cfg.MetricsAddr)
+	}
+	s.metrics = s.newMetricsHTTPServer(l)
+	return s.metrics, nil
+}
+
+// newMetricsHTTPServer returns an HTTPServer serving the metrics listener l
+// with a plain http.Server: unlike the API listeners, l is not wrapped with
+// the connection limits, accept rate, or PROXY protocol of Config, which
+// are meant for API clients, so that scrapers are not rejected or throttled
+// when the API is under load.
+func (s *Server) newMetricsHTTPServer(l net.Listener) *HTTPServer {
+	stats := newConnStats()
+	return &HTTPServer{
+		srv: &http.Server{
+			Addr:              s.cfg.MetricsAddr,
+			ReadHeaderTimeout: metricsReadHeaderTimeout,
+			ConnState:         stats.track,
+		},
+		l:       newSharedListener(l).ref(),
+		addr:    l.Addr().String(),
+		stats:   stats,
+		handler: s.createMetricsMux(),
+	}
+}
+
+// createMetricsMux returns the router of the metrics listener, which serves
+// the metrics endpoint, and the health and profiler endpoints if enabled by
+// Config.MetricsListenerHealth and Config.MetricsListenerProfiler. The
+// global middlewares are not applied to its requests.
+func (s *Server) createMetricsMux() *mux.Router {
+	m := mux.NewRouter()
+	m.Path(metricsPath).Methods(http.MethodGet).Handler(metrics.Handler())
+	if s.cfg.MetricsListenerHealth {
+		s.registerHealthRoutes(m)
+	}
+	if s.cfg.MetricsListenerProfiler {
+		for _, r := range debug.NewRouter().Routes() {
+			f := s.makeHTTPHandler(s.profilerHandler(r.Handler()), debugPathPrefix+r.Path(), router.OptionsOf(r))
+			m.Path(debugPathPrefix + r.Path()).Handler(f)
+		}
+	}
+	return m
+}
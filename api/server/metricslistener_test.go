@@ -0,0 +1,87 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/docker/api/server/router"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestMetricsListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+
+	srv := &Server{cfg: &Config{MetricsAddr: "127.0.0.1:0", MetricsListenerHealth: true}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/info", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}),
+	}})
+	srv.Accept(l.Addr().String(), l)
+	waitChan := make(chan error, 1)
+	go srv.Wait(waitChan)
+	<-srv.Ready()
+	defer func() {
+		assert.Check(t, srv.Shutdown(context.Background()))
+		assert.Check(t, <-waitChan)
+	}()
+
+	srv.mu.RLock()
+	metricsAddr := srv.metrics.Addr()
+	srv.mu.RUnlock()
+
+	get := func(addr, path string) int {
+		resp, err := http.Get("http://" + addr + path)
+		assert.NilError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Check(t, is.Equal(get(metricsAddr, metricsPath), http.StatusOK))
+	assert.Check(t, is.Equal(get(metricsAddr, livenessPath), http.StatusOK))
+	assert.Check(t, is.Equal(get(metricsAddr, "/v1.41/info"), http.StatusNotFound))
+
+	// the metrics endpoint is no longer served on the API listeners
+	assert.Check(t, is.Equal(get(l.Addr().String(), "/v1.41/info"), http.StatusNoContent))
+	assert.Check(t, is.Equal(get(l.Addr().String(), metricsPath), http.StatusNotFound))
+}
+
+func TestMetricsAuthorization(t *testing.T) {
+	srv := &Server{cfg: &Config{}}
+	srv.UseMiddleware(denyingMiddleware{})
+	resp := httptest.NewRecorder()
+	srv.createMux().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, metricsPath, nil))
+	assert.Check(t, is.Equal(resp.Code, http.StatusForbidden))
+}
+
+func TestMetricsListenerSettings(t *testing.T) {
+	// the settings of the API listeners do not apply to the metrics listener
+	srv := &Server{cfg: &Config{MetricsAddr: "127.0.0.1:0", TrustedProxyProtocol: true, MaxConnectionsPerListener: 1}}
+	waitChan := make(chan error, 1)
+	go srv.Wait(waitChan)
+	<-srv.Ready()
+	defer func() {
+		assert.Check(t, srv.Shutdown(context.Background()))
+		assert.Check(t, <-waitChan)
+	}()
+
+	srv.mu.RLock()
+	metricsAddr := srv.metrics.Addr()
+	srv.mu.RUnlock()
+
+	idle, err := net.Dial("tcp", metricsAddr)
+	assert.NilError(t, err)
+	defer idle.Close()
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	resp, err := client.Get("http://" + metricsAddr + metricsPath)
+	assert.NilError(t, err)
+	resp.Body.Close()
+	assert.Check(t, is.Equal(resp.StatusCode, http.StatusOK))
+}
@@ -1,11 +1,27 @@
 package server // import "github.com/docker/docker/api/server"
 
 import (
+	"context"
+	"net/http"
+
 	"github.com/docker/docker/api/server/httputils"
 	"github.com/docker/docker/api/server/middleware"
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	// middlewaresPath is the path of the endpoint listing the middlewares of
+	// the server, if enabled by Config.EnableMiddlewareList.
+	middlewaresPath = "/_admin/middlewares"
+
+	// bodyLoggingMiddlewareName and debugRequestMiddlewareName are the names
+	// by which the built-in debugging middlewares are listed.
+	bodyLoggingMiddlewareName  = "body-logging"
+	debugRequestMiddlewareName = "debug-request"
+)
+
 // handlerWithGlobalMiddlewares wraps the handler function for a request with
 // the server's global middlewares. The order of the middlewares is backwards,
 // meaning that the first in the list will be evaluated last.
@@ -20,5 +36,83 @@ func (s *Server) handlerWithGlobalMiddlewares(handler httputils.APIFunc) httputi
 		next = middleware.DebugRequestMiddleware(next)
 	}
 
+	if s.cfg.DebugBodyLogging {
+		next = middleware.NewBodyLoggingMiddleware(s.cfg.DebugBodyLogRoutes, s.cfg.DebugBodyLogMaxBytes).WrapHandler(next)
+	}
+
 	return next
 }
+
+// Middlewares returns the names of the middlewares in the request chain, in
+// the order in which they are evaluated, including the built-in debugging
+// middlewares if they are enabled. Middlewares that do not implement
+// middleware.NamedMiddleware are listed with the name of their type.
+func (s *Server) Middlewares() []string {
+	names := make([]string, 0, len(s.middlewares)+2)
+	if s.cfg.DebugBodyLogging {
+		names = append(names, bodyLoggingMiddlewareName)
+	}
+	if logrus.GetLevel() == logrus.DebugLevel {
+		names = append(names, debugRequestMiddlewareName)
+	}
+	for i := len(s.middlewares) - 1; i >= 0; i-- {
+		names = append(names, middleware.NameOf(s.middlewares[i]))
+	}
+	return names
+}
+
+func (s *Server) getMiddlewares(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return httputils.WriteJSON(w, http.StatusOK, s.Middlewares())
+}
+
+// UseMiddlewareBefore adds m to the request chain, so that it is evaluated
+// right before the middleware with the given name. Like UseMiddleware, it
+// must be called before the API routes are configured.
+func (s *Server) UseMiddlewareBefore(name string, m middleware.Middleware) error {
+	i, err := s.middlewareIndex(name)
+	if err != nil {
+		return err
+	}
+	// The middlewares are evaluated in reverse order, so m must follow the
+	// named middleware to be evaluated before it.
+	s.insertMiddleware(i+1, m)
+	return nil
+}
+
+// UseMiddlewareAfter adds m to the request chain, so that it is evaluated
+// right after the middleware with the given name. Like UseMiddleware, it
+// must be called before the API routes are configured.
+func (s *Server) UseMiddlewareAfter(name string, m middleware.Middleware) error {
+	i, err := s.middlewareIndex(name)
+	if err != nil {
+		return err
+	}
+	s.insertMiddleware(i, m)
+	return nil
+}
+
+// ReplaceMiddleware replaces the middleware with the given name by m. Like
+// UseMiddleware, it must be called before the API routes are configured.
+func (s *Server) ReplaceMiddleware(name string, m middleware.Middleware) error {
+	i, err := s.middlewareIndex(name)
+	if err != nil {
+		return err
+	}
+	s.middlewares[i] = m
+	return nil
+}
+
+func (s *Server) middlewareIndex(name string) (int, error) {
+	for i, m := range s.middlewares {
+		if middleware.NameOf(m) == name {
+			return i, nil
+		}
+	}
+	return -1, errdefs.NotFound(errors.Errorf("no middleware found with name %s", name))
+}
+
+func (s *Server) insertMiddleware(i int, m middleware.Middleware) {
+	s.middlewares = append(s.middlewares, nil)
+	copy(s.middlewares[i+1:], s.middlewares[i:])
+	s.middlewares[i] = m
+}
@@ -0,0 +1,114 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/docker/docker/api/server/httpstatus"
+	"github.com/docker/docker/api/server/httputils"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/sirupsen/logrus"
+)
+
+// Formats supported by the AccessLogMiddleware.
+const (
+	AccessLogFormatText = "text"
+	AccessLogFormatJSON = "json"
+)
+
+// AccessLogMiddleware is a middleware that logs a structured entry for
+// every request handled by the API, or for a sample of them.
+type AccessLogMiddleware struct {
+	logger   *logrus.Logger
+	sampling AccessLogSampling
+	count    *uint64
+}
+
+// AccessLogSampling configures the sampling of the requests logged by the
+// AccessLogMiddleware, to limit the volume of logs of busy daemons.
+type AccessLogSampling struct {
+	// Rate, if greater than 1, logs one in Rate of the requests that are
+	// not otherwise logged by the rules below.
+	Rate int
+	// SlowThreshold, if set, logs the requests taking longer to handle.
+	SlowThreshold time.Duration
+	// SampleErrors samples the requests failing with a non-2xx status as
+	// the successful ones, instead of always logging them.
+	SampleErrors bool
+}
+
+// NewAccessLogMiddleware creates a new AccessLogMiddleware that logs every
+// request in the given format. The text format uses the daemon's standard
+// logger; the JSON format writes to the same output, but formats entries as
+// JSON.
+func NewAccessLogMiddleware(format string) AccessLogMiddleware {
+	return NewSampledAccessLogMiddleware(format, AccessLogSampling{})
+}
+
+// NewSampledAccessLogMiddleware creates a new AccessLogMiddleware that logs
+// the requests selected by sampling in the given format. Sampled entries
+// have a "sample_rate" field, so that the total number of requests can be
+// estimated.
+func NewSampledAccessLogMiddleware(format string, sampling AccessLogSampling) AccessLogMiddleware {
+	logger := logrus.StandardLogger()
+	if format == AccessLogFormatJSON {
+		logger = logrus.New()
+		logger.SetOutput(logrus.StandardLogger().Out)
+		logger.SetFormatter(&logrus.JSONFormatter{TimestampFormat: jsonmessage.RFC3339NanoFixed})
+	}
+	return AccessLogMiddleware{logger: logger, sampling: sampling, count: new(uint64)}
+}
+
+// WrapHandler returns a new handler function wrapping the previous one in the request chain.
+func (a AccessLogMiddleware) WrapHandler(handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error) func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		start := time.Now()
+		rec := newStatusRecorder(w)
+		err := handler(ctx, rec, r, vars)
+
+		status := rec.Status()
+		if err != nil {
+			status = httpstatus.FromError(err)
+		}
+		duration := time.Since(start)
+		sampled, ok := a.sample(status, duration)
+		if !ok {
+			return err
+		}
+		fields := logrus.Fields{
+			"method":      r.Method,
+			"path":        r.URL.Path,
+			"status":      status,
+			"bytes":       rec.written,
+			"duration":    duration.String(),
+			"remote_addr": r.RemoteAddr,
+			"request_id":  httputils.RequestIDFromContext(ctx),
+		}
+		if subject := httputils.ClientCertSubjectFromContext(ctx); subject != "" {
+			fields["client_cert"] = subject
+		}
+		if sampled {
+			fields["sample_rate"] = a.sampling.Rate
+		}
+		a.logger.WithFields(fields).Info("API request")
+		return err
+	}
+}
+
+// sample returns whether a request with the given status and duration must
+// be logged, and whether it is only logged because it was sampled.
+func (a AccessLogMiddleware) sample(status int, duration time.Duration) (sampled, ok bool) {
+	if a.sampling.Rate <= 1 {
+		return false, true
+	}
+	if !a.sampling.SampleErrors && (status < 200 || status > 299) {
+		return false, true
+	}
+	if a.sampling.SlowThreshold > 0 && duration > a.sampling.SlowThreshold {
+		return false, true
+	}
+	n := atomic.AddUint64(a.count, 1)
+	return true, n%uint64(a.sampling.Rate) == 1
+}
@@ -0,0 +1,78 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestAccessLogMiddlewareJSON(t *testing.T) {
+	m := NewAccessLogMiddleware(AccessLogFormatJSON)
+	var buf bytes.Buffer
+	m.logger.SetOutput(&buf)
+
+	h := m.WrapHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		w.WriteHeader(http.StatusCreated)
+		_, err := w.Write([]byte("hello"))
+		return err
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/containers/create", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	assert.NilError(t, h(context.Background(), httptest.NewRecorder(), req, map[string]string{}))
+
+	var entry map[string]interface{}
+	assert.NilError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Check(t, is.Equal(entry["method"], http.MethodPost))
+	assert.Check(t, is.Equal(entry["path"], "/containers/create"))
+	assert.Check(t, is.Equal(entry["status"], float64(http.StatusCreated)))
+	assert.Check(t, is.Equal(entry["bytes"], float64(5)))
+	assert.Check(t, is.Equal(entry["remote_addr"], "192.0.2.1:1234"))
+}
+
+func TestAccessLogMiddlewareSampling(t *testing.T) {
+	m := NewSampledAccessLogMiddleware(AccessLogFormatJSON, AccessLogSampling{Rate: 3, SlowThreshold: 50 * time.Millisecond})
+	var buf bytes.Buffer
+	m.logger.SetOutput(&buf)
+
+	h := m.WrapHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		if r.URL.Path == "/slow" {
+			time.Sleep(100 * time.Millisecond)
+		}
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		return nil
+	})
+	request := func(path string) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		assert.NilError(t, h(context.Background(), httptest.NewRecorder(), req, map[string]string{}))
+	}
+
+	for i := 0; i < 6; i++ {
+		request("/ok")
+	}
+	request("/missing")
+	request("/slow")
+
+	var paths []string
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var entry map[string]interface{}
+		assert.NilError(t, dec.Decode(&entry))
+		paths = append(paths, entry["path"].(string))
+		if entry["path"] == "/ok" {
+			assert.Check(t, is.Equal(entry["sample_rate"], float64(3)))
+		} else {
+			assert.Check(t, is.Nil(entry["sample_rate"]))
+		}
+	}
+	assert.Check(t, is.DeepEqual(paths, []string{"/ok", "/ok", "/missing", "/slow"}))
+}
@@ -0,0 +1,197 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/docker/docker/api/server/httputils"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultBodyLogMaxBytes is the number of bytes of the request and response
+// bodies logged by the BodyLoggingMiddleware when no limit is set.
+const DefaultBodyLogMaxBytes = 4096
+
+// redactedHeaders are the request headers whose value is not logged, as
+// they contain credentials.
+var redactedHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"X-Registry-Auth",
+	"X-Registry-Config",
+}
+
+// BodyLoggingMiddleware is a middleware that logs the headers and bodies of
+// requests and of their responses, for debugging. The bodies are copied as
+// they are read and written, up to a size limit, so that streaming requests
+// are not buffered. The headers containing credentials are redacted, and
+// the secrets of JSON bodies are masked; JSON bodies that exceed the limit
+// are not logged, as they cannot be masked.
+type BodyLoggingMiddleware struct {
+	routes   []string
+	maxBytes int
+}
+
+// NewBodyLoggingMiddleware creates a new BodyLoggingMiddleware logging the
+// requests to the routes with the given path templates (such as
+// "/containers/create"), or to all routes if routes is empty. Up to maxBytes
+// bytes of each body are logged, or DefaultBodyLogMaxBytes if maxBytes is
+// zero or less.
+func NewBodyLoggingMiddleware(routes []string, maxBytes int) BodyLoggingMiddleware {
+	if maxBytes <= 0 {
+		maxBytes = DefaultBodyLogMaxBytes
+	}
+	return BodyLoggingMiddleware{routes: routes, maxBytes: maxBytes}
+}
+
+// WrapHandler returns a new handler function wrapping the previous one in the request chain.
+func (m BodyLoggingMiddleware) WrapHandler(handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error) func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		if !m.matches(httputils.RouteTemplateFromContext(ctx)) {
+			return handler(ctx, w, r, vars)
+		}
+
+		var reqBody *cappedBuffer
+		if r.Body != nil && r.Body != http.NoBody {
+			reqBody = &cappedBuffer{max: m.maxBytes}
+			r.Body = &teeReadCloser{ReadCloser: r.Body, buf: reqBody}
+		}
+		rec := &bodyRecorder{statusRecorder: newStatusRecorder(w), buf: cappedBuffer{max: m.maxBytes}}
+
+		err := handler(ctx, rec, r, vars)
+
+		fields := logrus.Fields{
+			"request-id":       httputils.RequestIDFromContext(ctx),
+			"request-headers":  redactHeaders(r.Header),
+			"response-status":  rec.Status(),
+			"response-headers": rec.Header(),
+		}
+		if reqBody != nil {
+			fields["request-body"] = reqBody.String(r.Header.Get("Content-Type"))
+		}
+		if rec.hijacked {
+			fields["response-body"] = "(hijacked)"
+		} else {
+			fields["response-body"] = rec.buf.String(rec.Header().Get("Content-Type"))
+		}
+		if err != nil {
+			fields["error"] = err.Error()
+		}
+		log.WithFields(fields).Infof("API request %s %s", r.Method, r.URL.RequestURI())
+		return err
+	}
+}
+
+func (m BodyLoggingMiddleware) matches(route string) bool {
+	if len(m.routes) == 0 {
+		return true
+	}
+	for _, r := range m.routes {
+		if r == route {
+			return true
+		}
+	}
+	return false
+}
+
+func redactHeaders(h http.Header) http.Header {
+	h = h.Clone()
+	for _, k := range redactedHeaders {
+		if _, ok := h[k]; ok {
+			h[k] = []string{"*****"}
+		}
+	}
+	return h
+}
+
+// cappedBuffer stores the first max bytes written to it, and counts the
+// total number of bytes written.
+type cappedBuffer struct {
+	max   int
+	buf   []byte
+	total int64
+}
+
+func (b *cappedBuffer) Write(p []byte) {
+	b.total += int64(len(p))
+	if n := b.max - len(b.buf); n > 0 {
+		if len(p) > n {
+			p = p[:n]
+		}
+		b.buf = append(b.buf, p...)
+	}
+}
+
+func (b *cappedBuffer) truncated() bool {
+	return b.total > int64(len(b.buf))
+}
+
+// String returns the body for logging. JSON bodies have their secrets
+// masked, and are omitted if they are truncated.
+func (b *cappedBuffer) String(contentType string) string {
+	if b.total == 0 {
+		return ""
+	}
+	if strings.Contains(contentType, "json") {
+		if b.truncated() {
+			return "(truncated JSON body not logged)"
+		}
+		var v interface{}
+		if err := json.Unmarshal(b.buf, &v); err != nil {
+			return "(invalid JSON body not logged)"
+		}
+		maskSecretKeys(v)
+		masked, err := json.Marshal(v)
+		if err != nil {
+			return "(invalid JSON body not logged)"
+		}
+		return string(masked)
+	}
+	if b.truncated() {
+		return string(b.buf) + "... (truncated)"
+	}
+	return string(b.buf)
+}
+
+// teeReadCloser copies the data read from a request body to a cappedBuffer.
+type teeReadCloser struct {
+	io.ReadCloser
+	buf *cappedBuffer
+}
+
+func (t *teeReadCloser) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	if n > 0 {
+		t.buf.Write(p[:n])
+	}
+	return n, err
+}
+
+// bodyRecorder copies the response body to a cappedBuffer.
+type bodyRecorder struct {
+	*statusRecorder
+	buf      cappedBuffer
+	hijacked bool
+}
+
+func (b *bodyRecorder) Write(p []byte) (int, error) {
+	n, err := b.statusRecorder.Write(p)
+	if n > 0 {
+		b.buf.Write(p[:n])
+	}
+	return n, err
+}
+
+// Hijack implements http.Hijacker.
+func (b *bodyRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := b.statusRecorder.Hijack()
+	if err == nil {
+		b.hijacked = true
+	}
+	return conn, rw, err
+}
@@ -0,0 +1,69 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/server/httputils"
+	"github.com/sirupsen/logrus"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestBodyLoggingMiddleware(t *testing.T) {
+	var entries []*logrus.Entry
+	logger := logrus.StandardLogger()
+	hooks := logger.ReplaceHooks(logrus.LevelHooks{})
+	defer logger.ReplaceHooks(hooks)
+	logger.AddHook(hookFunc(func(e *logrus.Entry) { entries = append(entries, e) }))
+
+	m := NewBodyLoggingMiddleware([]string{"/auth", "/build"}, 16)
+	h := m.WrapHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"Status":"ok"}`))
+		return nil
+	})
+	do := func(route, contentType, body string) {
+		req := httptest.NewRequest(http.MethodPost, "/v1.41"+route, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("X-Registry-Auth", "credentials")
+		ctx := context.WithValue(req.Context(), httputils.RouteTemplateKey{}, route)
+		assert.NilError(t, h(ctx, httptest.NewRecorder(), req, nil))
+	}
+
+	do("/auth", "application/json", `{"password":"p"}`)
+	do("/build", "application/x-tar", strings.Repeat("x", 32))
+	do("/containers/create", "application/json", `{}`)
+	assert.Assert(t, is.Len(entries, 2))
+
+	fields := entries[0].Data
+	assert.Check(t, is.Equal(fields["component"], "api"))
+	assert.Check(t, is.Equal(fields["subcomponent"], "middleware"))
+	assert.Check(t, is.Equal(fields["request-body"], `{"password":"*****"}`))
+	assert.Check(t, is.Equal(fields["response-body"], `{"Status":"ok"}`))
+	assert.Check(t, is.Equal(fields["response-status"], http.StatusOK))
+	assert.Check(t, is.Equal(fields["request-headers"].(http.Header).Get("X-Registry-Auth"), "*****"))
+
+	fields = entries[1].Data
+	assert.Check(t, is.Equal(fields["request-body"], strings.Repeat("x", 16)+"... (truncated)"))
+}
+
+func TestBodyLogTruncatedJSON(t *testing.T) {
+	b := &cappedBuffer{max: 8}
+	b.Write([]byte(`{"password":"secret"}`))
+	assert.Check(t, is.Equal(b.String("application/json"), "(truncated JSON body not logged)"))
+}
+
+type hookFunc func(*logrus.Entry)
+
+func (h hookFunc) Levels() []logrus.Level { return logrus.AllLevels }
+
+func (h hookFunc) Fire(e *logrus.Entry) error {
+	h(e)
+	return nil
+}
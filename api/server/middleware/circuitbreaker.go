@@ -0,0 +1,147 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/server/httpstatus"
+)
+
+var circuitBreakerTrips = metricsNS.NewLabeledCounter("circuit_breaker_trips", "The number of times the circuit breaker of a route group opened", "group")
+
+type circuitOpenError struct {
+	group string
+}
+
+func (e circuitOpenError) Error() string {
+	return fmt.Sprintf("the %s endpoints are temporarily unavailable after repeated failures; retry later", e.group)
+}
+
+func (circuitOpenError) Unavailable() {}
+
+// CircuitBreakerMiddleware is a middleware that short-circuits the requests
+// to a group of routes with a "503 Service Unavailable" status for a
+// cooldown period after consecutive requests to the group failed with a
+// server error (5xx), to shed load while the subsystem they depend on (such
+// as containerd) recovers. Once the cooldown period elapses, a single
+// request is handled to probe the subsystem, closing the circuit if it
+// succeeds, or opening it for another cooldown period otherwise.
+type CircuitBreakerMiddleware struct {
+	groups    []string
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+type circuitBreaker struct {
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// NewCircuitBreakerMiddleware creates a new CircuitBreakerMiddleware opening
+// the circuit of a group of routes for cooldown after threshold consecutive
+// failures. The groups are path template prefixes (such as "/containers",
+// or "/containers/{name:.*}/exec"), matching whole path segments; routes
+// are in the group of the longest prefix that matches their path template.
+// If groups is empty, or no group matches a route, routes are grouped by
+// the first segment of their path template.
+func NewCircuitBreakerMiddleware(groups []string, threshold int, cooldown time.Duration) *CircuitBreakerMiddleware {
+	return &CircuitBreakerMiddleware{
+		groups:    groups,
+		threshold: threshold,
+		cooldown:  cooldown,
+		breakers:  make(map[string]*circuitBreaker),
+	}
+}
+
+// WrapHandler returns a new handler function wrapping the previous one in the request chain.
+func (m *CircuitBreakerMiddleware) WrapHandler(handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error) func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		group := m.group(routeTemplate(ctx))
+		if retryAfter, ok := m.allow(group); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			return circuitOpenError{group: group}
+		}
+
+		rec := newStatusRecorder(w)
+		err := handler(ctx, rec, r, vars)
+		code := rec.Status()
+		if err != nil {
+			code = httpstatus.FromError(err)
+		}
+		// Requests cancelled by the client are not failures of the subsystem.
+		m.record(group, code >= 500 && ctx.Err() == nil)
+		return err
+	}
+}
+
+// group returns the group of the route with the given path template.
+func (m *CircuitBreakerMiddleware) group(route string) string {
+	var group string
+	for _, prefix := range m.groups {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if len(prefix) > len(group) && (route == prefix || strings.HasPrefix(route, prefix+"/")) {
+			group = prefix
+		}
+	}
+	if group != "" {
+		return group
+	}
+	if i := strings.IndexByte(strings.TrimPrefix(route, "/"), '/'); i >= 0 {
+		return route[:i+1]
+	}
+	return route
+}
+
+// allow returns whether a request to group can be handled, or else the time
+// until the circuit of group may be closed.
+func (m *CircuitBreakerMiddleware) allow(group string) (time.Duration, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.breakers[group]
+	if !ok || b.openUntil.IsZero() {
+		return 0, true
+	}
+	if wait := time.Until(b.openUntil); wait > 0 {
+		return wait, false
+	}
+	if b.probing {
+		return m.cooldown, false
+	}
+	b.probing = true
+	return 0, true
+}
+
+// record records the outcome of a request to group.
+func (m *CircuitBreakerMiddleware) record(group string, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.breakers[group]
+	if !failed {
+		// Requests started before the circuit opened do not close it.
+		if ok && (b.openUntil.IsZero() || b.probing) {
+			delete(m.breakers, group)
+		}
+		return
+	}
+	if !ok {
+		b = &circuitBreaker{}
+		m.breakers[group] = b
+	}
+	b.failures++
+	if b.probing || (b.openUntil.IsZero() && b.failures >= m.threshold) {
+		log.WithField("group", group).Warnf("opening the circuit breaker of the API routes after %d consecutive failures; rejecting requests for %s", b.failures, m.cooldown)
+		b.openUntil = time.Now().Add(m.cooldown)
+		b.probing = false
+		circuitBreakerTrips.WithValues(group).Inc()
+	}
+}
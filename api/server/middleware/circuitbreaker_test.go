@@ -0,0 +1,66 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/server/httputils"
+	"github.com/docker/docker/errdefs"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestCircuitBreakerMiddleware(t *testing.T) {
+	m := NewCircuitBreakerMiddleware([]string{"/containers/{name:.*}/exec"}, 2, 50*time.Millisecond)
+	var failing bool
+	h := m.WrapHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		if failing {
+			return errdefs.System(errors.New("containerd is down"))
+		}
+		return nil
+	})
+	request := func(route string) error {
+		ctx := context.WithValue(context.Background(), httputils.RouteTemplateKey{}, route)
+		return h(ctx, httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil), nil)
+	}
+
+	failing = true
+	assert.Check(t, errdefs.IsSystem(request("/containers/{name:.*}/start")))
+	assert.Check(t, errdefs.IsSystem(request("/containers/create")))
+
+	// the circuit of the /containers group is open, but not those of the
+	// other groups
+	failing = false
+	err := request("/containers/{name:.*}/start")
+	assert.Check(t, errdefs.IsUnavailable(err), "expected an unavailable error, got %v", err)
+	assert.Check(t, is.ErrorContains(err, "the /containers endpoints are temporarily unavailable"))
+	assert.Check(t, request("/containers/{name:.*}/exec"))
+	assert.Check(t, request("/images/create"))
+
+	// a failed probe opens the circuit again
+	time.Sleep(60 * time.Millisecond)
+	failing = true
+	assert.Check(t, errdefs.IsSystem(request("/containers/create")))
+	failing = false
+	assert.Check(t, errdefs.IsUnavailable(request("/containers/create")))
+
+	// a successful probe closes the circuit
+	time.Sleep(60 * time.Millisecond)
+	assert.Check(t, request("/containers/create"))
+	assert.Check(t, request("/containers/create"))
+}
+
+func TestCircuitBreakerIgnoresClientErrors(t *testing.T) {
+	m := NewCircuitBreakerMiddleware(nil, 1, time.Minute)
+	h := m.WrapHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		w.WriteHeader(http.StatusNotFound)
+		return nil
+	})
+	for i := 0; i < 3; i++ {
+		assert.Check(t, h(context.Background(), httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), nil))
+	}
+}
@@ -0,0 +1,43 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/docker/docker/api/server/httputils"
+)
+
+// ClientCertMiddleware is a middleware that stores the subject of the
+// verified TLS client certificate of requests in their context (see
+// httputils.ClientCertSubjectFromContext), so that the middlewares it wraps,
+// such as the access log and the metrics middlewares, can attribute requests
+// to the operators they were sent by. Requests that are not sent over TLS, or
+// without a verified client certificate, are passed unmodified.
+type ClientCertMiddleware struct{}
+
+// NewClientCertMiddleware creates a new ClientCertMiddleware.
+func NewClientCertMiddleware() ClientCertMiddleware {
+	return ClientCertMiddleware{}
+}
+
+// WrapHandler returns a new handler function wrapping the previous one in the request chain.
+func (ClientCertMiddleware) WrapHandler(handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error) func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		if subject := clientCertSubject(r); subject != "" {
+			ctx = context.WithValue(ctx, httputils.ClientCertSubjectKey{}, subject)
+			r = r.WithContext(ctx)
+		}
+		return handler(ctx, w, r, vars)
+	}
+}
+
+// clientCertSubject returns the subject of the leaf certificate of the first
+// verified chain of the client certificate of r, if any. Certificates that
+// were not verified (for example, if client certificates are requested, but
+// not verified) are ignored, as their subject could be forged.
+func clientCertSubject(r *http.Request) string {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	return r.TLS.VerifiedChains[0][0].Subject.String()
+}
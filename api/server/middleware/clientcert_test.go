@@ -0,0 +1,68 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/docker/api/server/httputils"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestClientCertMiddleware(t *testing.T) {
+	var subject string
+	h := NewClientCertMiddleware().WrapHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		subject = httputils.ClientCertSubjectFromContext(ctx)
+		assert.Check(t, is.Equal(httputils.ClientCertSubjectFromContext(r.Context()), subject))
+		return nil
+	})
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "alice", Organization: []string{"ops"}}}
+
+	for _, tc := range []struct {
+		name     string
+		state    *tls.ConnectionState
+		expected string
+	}{
+		{name: "no TLS"},
+		{name: "no client certificate", state: &tls.ConnectionState{}},
+		{name: "unverified certificate", state: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}},
+		{
+			name:     "verified certificate",
+			state:    &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}, VerifiedChains: [][]*x509.Certificate{{cert}}},
+			expected: "CN=alice,O=ops",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/info", nil)
+			req.TLS = tc.state
+			subject = "unset"
+			assert.NilError(t, h(req.Context(), httptest.NewRecorder(), req, map[string]string{}))
+			assert.Check(t, is.Equal(subject, tc.expected))
+		})
+	}
+}
+
+func TestClientCertAccessLog(t *testing.T) {
+	a := NewAccessLogMiddleware(AccessLogFormatJSON)
+	var buf bytes.Buffer
+	a.logger.SetOutput(&buf)
+	h := NewClientCertMiddleware().WrapHandler(a.WrapHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		return nil
+	}))
+
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "alice"}}
+	req := httptest.NewRequest(http.MethodGet, "/info", nil)
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	assert.NilError(t, h(req.Context(), httptest.NewRecorder(), req, map[string]string{}))
+
+	var entry map[string]interface{}
+	assert.NilError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Check(t, is.Equal(entry["client_cert"], "CN=alice"))
+}
@@ -0,0 +1,206 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultCompressionMinSize is the default size (in bytes) above which
+// responses are compressed by the CompressionMiddleware.
+const DefaultCompressionMinSize = 1024
+
+// CompressionMiddleware is a middleware that compresses JSON and text
+// responses using gzip or deflate, as accepted by the client, if they are
+// larger than a minimum size. Other responses, such as (already compressed)
+// archives and raw streams, as well as hijacked connections, are never
+// compressed.
+type CompressionMiddleware struct {
+	minSize int
+}
+
+// NewCompressionMiddleware creates a new CompressionMiddleware compressing
+// responses larger than minSize bytes.
+func NewCompressionMiddleware(minSize int) CompressionMiddleware {
+	return CompressionMiddleware{minSize: minSize}
+}
+
+// WrapHandler returns a new handler function wrapping the previous one in the request chain.
+func (c CompressionMiddleware) WrapHandler(handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error) func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		encoding := acceptedEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			return handler(ctx, w, r, vars)
+		}
+		w.Header().Add("Vary", "Accept-Encoding")
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: c.minSize}
+		err := handler(ctx, cw, r, vars)
+		if closeErr := cw.close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}
+}
+
+// acceptedEncoding returns the preferred encoding supported by the server
+// in the given "Accept-Encoding" header, or an empty string if none is.
+func acceptedEncoding(header string) string {
+	var deflate bool
+	for _, e := range strings.Split(header, ",") {
+		name, params, _ := cut(strings.TrimSpace(e), ";")
+		if q := strings.TrimSpace(params); strings.HasPrefix(q, "q=0") && strings.Trim(q[3:], ".0") == "" {
+			// explicitly not acceptable ("q=0", "q=0.0", ...)
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "gzip":
+			return "gzip"
+		case "deflate":
+			deflate = true
+		}
+	}
+	if deflate {
+		return "deflate"
+	}
+	return ""
+}
+
+// cut is strings.Cut, which is not available in all Go versions supported.
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// compressible returns whether responses with the given content type are
+// compressed.
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasPrefix(mediaType, "text/")
+}
+
+// compressWriter buffers the start of the response until it knows whether
+// the response must be compressed: either once it exceeds the minimum size,
+// or when the handler flushes, hijacks, or completes the response.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+
+	status  int
+	buf     bytes.Buffer
+	decided bool
+	w       io.Writer
+	cw      io.WriteCloser
+}
+
+func (c *compressWriter) WriteHeader(code int) {
+	if c.status == 0 {
+		c.status = code
+	}
+}
+
+func (c *compressWriter) Write(b []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	if !c.decided {
+		c.buf.Write(b)
+		if c.buf.Len() <= c.minSize {
+			return len(b), nil
+		}
+		if err := c.decide(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	return c.w.Write(b)
+}
+
+// decide writes the response headers, and the buffered start of the
+// response, compressing the response if compress is set and the response
+// is compressible.
+func (c *compressWriter) decide(compress bool) error {
+	c.decided = true
+	h := c.Header()
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.w = c.ResponseWriter
+	if compress && h.Get("Content-Encoding") == "" && compressible(h.Get("Content-Type")) {
+		h.Set("Content-Encoding", c.encoding)
+		h.Del("Content-Length")
+		if c.encoding == "gzip" {
+			c.cw = gzip.NewWriter(c.ResponseWriter)
+		} else {
+			c.cw = zlib.NewWriter(c.ResponseWriter)
+		}
+		c.w = c.cw
+	}
+	c.ResponseWriter.WriteHeader(c.status)
+	if c.buf.Len() == 0 {
+		return nil
+	}
+	_, err := c.w.Write(c.buf.Bytes())
+	c.buf.Reset()
+	return err
+}
+
+// close completes the response, once the handler returned.
+func (c *compressWriter) close() error {
+	if !c.decided {
+		if c.status == 0 {
+			// nothing written; leave the response to the server,
+			// which may write an error.
+			return nil
+		}
+		return c.decide(false)
+	}
+	if c.cw != nil {
+		return c.cw.Close()
+	}
+	return nil
+}
+
+// Flush implements http.Flusher. Flushing before the response reached the
+// minimum size indicates a streaming response, which is not compressed.
+func (c *compressWriter) Flush() {
+	if !c.decided {
+		if err := c.decide(false); err != nil {
+			return
+		}
+	}
+	if f, ok := c.cw.(interface{ Flush() error }); ok {
+		_ = f.Flush()
+	}
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker. Hijacked connections are not compressed.
+func (c *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := c.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not implement http.Hijacker")
+	}
+	if c.decided {
+		return nil, nil, errors.New("cannot hijack a connection after writing the response")
+	}
+	c.decided = true
+	c.w = c.ResponseWriter
+	return h.Hijack()
+}
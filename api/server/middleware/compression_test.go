@@ -0,0 +1,52 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestCompressionMiddleware(t *testing.T) {
+	large := strings.Repeat(`{"Id":"abcdef"}`, 100)
+	m := NewCompressionMiddleware(DefaultCompressionMinSize)
+	serve := func(contentType, body, acceptEncoding string) *httptest.ResponseRecorder {
+		h := m.WrapHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			w.Header().Set("Content-Type", contentType)
+			w.WriteHeader(http.StatusOK)
+			_, err := io.WriteString(w, body)
+			return err
+		})
+		req := httptest.NewRequest(http.MethodGet, "/images/json", nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		resp := httptest.NewRecorder()
+		assert.NilError(t, h(context.Background(), resp, req, nil))
+		return resp
+	}
+
+	resp := serve("application/json", large, "deflate, gzip;q=0.8")
+	assert.Check(t, is.Equal(resp.Header().Get("Content-Encoding"), "gzip"))
+	gz, err := gzip.NewReader(resp.Body)
+	assert.NilError(t, err)
+	body, err := io.ReadAll(gz)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(string(body), large))
+
+	resp = serve("application/json", `{"Id":"abcdef"}`, "gzip")
+	assert.Check(t, is.Equal(resp.Header().Get("Content-Encoding"), ""))
+	assert.Check(t, is.Equal(resp.Body.String(), `{"Id":"abcdef"}`))
+
+	resp = serve("application/x-tar", large, "gzip")
+	assert.Check(t, is.Equal(resp.Header().Get("Content-Encoding"), ""))
+	assert.Check(t, is.Equal(resp.Body.String(), large))
+
+	resp = serve("application/json", large, "gzip;q=0, identity")
+	assert.Check(t, is.Equal(resp.Header().Get("Content-Encoding"), ""))
+	assert.Check(t, is.Equal(resp.Body.String(), large))
+}
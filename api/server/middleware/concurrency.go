@@ -0,0 +1,76 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type tooManyConcurrentRequestsError struct {
+	limit int
+}
+
+func (e tooManyConcurrentRequestsError) Error() string {
+	return fmt.Sprintf("server is busy: too many concurrent requests (limit %d)", e.limit)
+}
+
+func (tooManyConcurrentRequestsError) Unavailable() {}
+
+// ConcurrencyLimitMiddleware is a middleware that limits the number of
+// requests that are handled concurrently. Requests beyond the limit wait for
+// a request to complete, for up to the queue timeout, and are rejected with a
+// "503 Service Unavailable" status if none completes in time.
+type ConcurrencyLimitMiddleware struct {
+	sem          chan struct{}
+	queueTimeout time.Duration
+}
+
+// NewConcurrencyLimitMiddleware creates a new ConcurrencyLimitMiddleware
+// handling up to limit requests concurrently. Requests beyond the limit wait
+// for up to queueTimeout; a zero queueTimeout rejects them immediately.
+func NewConcurrencyLimitMiddleware(limit int, queueTimeout time.Duration) ConcurrencyLimitMiddleware {
+	return ConcurrencyLimitMiddleware{
+		sem:          make(chan struct{}, limit),
+		queueTimeout: queueTimeout,
+	}
+}
+
+// WrapHandler returns a new handler function wrapping the previous one in the request chain.
+func (m ConcurrencyLimitMiddleware) WrapHandler(handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error) func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		if err := m.acquire(ctx); err != nil {
+			return err
+		}
+		// The semaphore is released in a deferred call, so that it is also
+		// released if the handler panics.
+		defer m.release()
+		return handler(ctx, w, r, vars)
+	}
+}
+
+func (m ConcurrencyLimitMiddleware) acquire(ctx context.Context) error {
+	select {
+	case m.sem <- struct{}{}:
+		return nil
+	default:
+	}
+	if m.queueTimeout <= 0 {
+		return tooManyConcurrentRequestsError{limit: cap(m.sem)}
+	}
+
+	timer := time.NewTimer(m.queueTimeout)
+	defer timer.Stop()
+	select {
+	case m.sem <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return tooManyConcurrentRequestsError{limit: cap(m.sem)}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m ConcurrencyLimitMiddleware) release() {
+	<-m.sem
+}
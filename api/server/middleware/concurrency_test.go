@@ -0,0 +1,56 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/errdefs"
+	"gotest.tools/v3/assert"
+)
+
+func TestConcurrencyLimitMiddleware(t *testing.T) {
+	m := NewConcurrencyLimitMiddleware(1, 10*time.Millisecond)
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	blocking := m.WrapHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		close(started)
+		<-unblock
+		return nil
+	})
+	h := m.WrapHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		return nil
+	})
+
+	done := make(chan error)
+	go func() {
+		done <- blocking(context.Background(), httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), nil)
+	}()
+	<-started
+
+	err := h(context.Background(), httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), nil)
+	assert.Check(t, errdefs.IsUnavailable(err), "expected an unavailable error, got %v", err)
+
+	close(unblock)
+	assert.NilError(t, <-done)
+	assert.NilError(t, h(context.Background(), httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), nil))
+}
+
+func TestConcurrencyLimitMiddlewareReleasesOnPanic(t *testing.T) {
+	m := NewConcurrencyLimitMiddleware(1, 0)
+	panicking := m.WrapHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		panic("boom")
+	})
+	func() {
+		defer func() { _ = recover() }()
+		_ = panicking(context.Background(), httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), nil)
+	}()
+
+	h := m.WrapHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		return nil
+	})
+	assert.NilError(t, h(context.Background(), httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), nil))
+}
@@ -3,35 +3,109 @@ package middleware // import "github.com/docker/docker/api/server/middleware"
 import (
 	"context"
 	"net/http"
+	"strings"
+	"sync"
+)
 
-	"github.com/sirupsen/logrus"
+const (
+	corsAllowHeaders = "Origin, X-Requested-With, Content-Type, Accept, X-Registry-Auth"
+	corsAllowMethods = "HEAD, GET, POST, DELETE, PUT, OPTIONS"
 )
 
 // CORSMiddleware injects CORS headers to each request
 // when it's configured.
 type CORSMiddleware struct {
-	defaultHeaders string
+	mu       sync.RWMutex
+	disabled bool
+	origins  []string
 }
 
 // NewCORSMiddleware creates a new CORSMiddleware with default headers.
-func NewCORSMiddleware(d string) CORSMiddleware {
-	return CORSMiddleware{defaultHeaders: d}
+// The default headers may contain a comma-separated list of origins, in
+// which case only requests from one of those origins are allowed.
+func NewCORSMiddleware(d string) *CORSMiddleware {
+	return &CORSMiddleware{origins: parseOrigins(d)}
+}
+
+func parseOrigins(d string) []string {
+	var origins []string
+	for _, o := range strings.Split(d, ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			origins = append(origins, o)
+		}
+	}
+	return origins
+}
+
+// SetHeaders changes the default headers of the middleware while it is in
+// use, for example, when the daemon configuration is reloaded. Setting
+// empty headers disables CORS.
+func (c *CORSMiddleware) SetHeaders(d string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.disabled = d == ""
+	c.origins = parseOrigins(d)
+}
+
+// allowedOrigin returns the value of the "Access-Control-Allow-Origin"
+// header for a request with the given "Origin" header, or an empty string
+// if the origin is not allowed.
+func (c *CORSMiddleware) allowedOrigin(origin string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.disabled {
+		return ""
+	}
+	// If "api-cors-header" is not given, but "api-enable-cors" is true, we set cors to "*"
+	if len(c.origins) == 0 {
+		return "*"
+	}
+	for _, o := range c.origins {
+		if o == "*" || o == origin {
+			return o
+		}
+	}
+	if len(c.origins) == 1 && origin == "" {
+		return c.origins[0]
+	}
+	return ""
 }
 
 // WrapHandler returns a new handler function wrapping the previous one in the request chain.
-func (c CORSMiddleware) WrapHandler(handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error) func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+func (c *CORSMiddleware) WrapHandler(handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error) func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
-		// If "api-cors-header" is not given, but "api-enable-cors" is true, we set cors to "*"
-		// otherwise, all head values will be passed to HTTP handler
-		corsHeaders := c.defaultHeaders
-		if corsHeaders == "" {
-			corsHeaders = "*"
+		if origin := c.allowedOrigin(r.Header.Get("Origin")); origin != "" {
+			log.Debugf("CORS header is enabled and set to: %s", origin)
+			c.setHeaders(w, origin)
 		}
-
-		logrus.Debugf("CORS header is enabled and set to: %s", corsHeaders)
-		w.Header().Add("Access-Control-Allow-Origin", corsHeaders)
-		w.Header().Add("Access-Control-Allow-Headers", "Origin, X-Requested-With, Content-Type, Accept, X-Registry-Auth")
-		w.Header().Add("Access-Control-Allow-Methods", "HEAD, GET, POST, DELETE, PUT, OPTIONS")
 		return handler(ctx, w, r, vars)
 	}
 }
+
+// Preflight responds to a CORS preflight request for a path that accepts
+// the given methods. It returns false, without writing a response, if the
+// request's origin is not allowed.
+func (c *CORSMiddleware) Preflight(w http.ResponseWriter, r *http.Request, methods []string) bool {
+	origin := c.allowedOrigin(r.Header.Get("Origin"))
+	if origin == "" {
+		return false
+	}
+	c.setHeaders(w, origin)
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(append(methods, http.MethodOptions), ", "))
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+func (c *CORSMiddleware) setHeaders(w http.ResponseWriter, origin string) {
+	w.Header().Add("Access-Control-Allow-Origin", origin)
+	w.Header().Add("Access-Control-Allow-Headers", corsAllowHeaders)
+	w.Header().Add("Access-Control-Allow-Methods", corsAllowMethods)
+	if origin != "*" {
+		w.Header().Add("Vary", "Origin")
+	}
+}
+
+// IsPreflightRequest returns whether r is a CORS preflight request.
+func IsPreflightRequest(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Origin") != "" && r.Header.Get("Access-Control-Request-Method") != ""
+}
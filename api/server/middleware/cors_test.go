@@ -0,0 +1,21 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestCORSSetHeaders(t *testing.T) {
+	c := NewCORSMiddleware("https://a.example.com")
+	assert.Check(t, is.Equal(c.allowedOrigin("https://a.example.com"), "https://a.example.com"))
+	assert.Check(t, is.Equal(c.allowedOrigin("https://b.example.com"), ""))
+
+	c.SetHeaders("https://a.example.com,https://b.example.com")
+	assert.Check(t, is.Equal(c.allowedOrigin("https://b.example.com"), "https://b.example.com"))
+
+	c.SetHeaders("")
+	assert.Check(t, is.Equal(c.allowedOrigin("https://a.example.com"), ""))
+	assert.Check(t, is.Equal(c.allowedOrigin(""), ""))
+}
@@ -10,13 +10,12 @@ import (
 
 	"github.com/docker/docker/api/server/httputils"
 	"github.com/docker/docker/pkg/ioutils"
-	"github.com/sirupsen/logrus"
 )
 
 // DebugRequestMiddleware dumps the request to logger
 func DebugRequestMiddleware(handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error) func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
-		logrus.Debugf("Calling %s %s", r.Method, r.RequestURI)
+		log.Debugf("Calling %s %s", r.Method, r.RequestURI)
 
 		if r.Method != http.MethodPost {
 			return handler(ctx, w, r, vars)
@@ -25,7 +24,9 @@ func DebugRequestMiddleware(handler func(ctx context.Context, w http.ResponseWri
 			return handler(ctx, w, r, vars)
 		}
 		maxBodySize := 4096 // 4KB
-		if r.ContentLength > int64(maxBodySize) {
+		if r.ContentLength > int64(maxBodySize) || httputils.ExpectsContinue(r) {
+			// Reading the body of a request expecting a "100 Continue" would
+			// accept it before the next handlers could reject it.
 			return handler(ctx, w, r, vars)
 		}
 
@@ -44,9 +45,9 @@ func DebugRequestMiddleware(handler func(ctx context.Context, w http.ResponseWri
 			maskSecretKeys(postForm)
 			formStr, errMarshal := json.Marshal(postForm)
 			if errMarshal == nil {
-				logrus.Debugf("form data: %s", string(formStr))
+				log.Debugf("form data: %s", string(formStr))
 			} else {
-				logrus.Debugf("form data: %q", postForm)
+				log.Debugf("form data: %q", postForm)
 			}
 		}
 
@@ -0,0 +1,49 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/docker/docker/api/types/versions"
+)
+
+// DeprecationMiddleware is a middleware that warns clients using a
+// deprecated API version, and rejects clients using an API version below
+// the minimum supported version.
+type DeprecationMiddleware struct {
+	minVersion        string
+	deprecatedVersion string
+}
+
+// NewDeprecationMiddleware creates a new DeprecationMiddleware. Requests for
+// an API version lower than minVersion are rejected, and responses for an
+// API version lower than deprecatedVersion include a "Warning" header. Either
+// version can be empty to disable the respective check.
+func NewDeprecationMiddleware(minVersion, deprecatedVersion string) DeprecationMiddleware {
+	return DeprecationMiddleware{
+		minVersion:        minVersion,
+		deprecatedVersion: deprecatedVersion,
+	}
+}
+
+// WrapHandler returns a new handler function wrapping the previous one in the request chain.
+func (d DeprecationMiddleware) WrapHandler(handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error) func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		apiVersion := vars["version"]
+		if apiVersion == "" {
+			// requests without a version use the default (latest) version
+			return handler(ctx, w, r, vars)
+		}
+		if d.minVersion != "" && versions.LessThan(apiVersion, d.minVersion) {
+			return versionUnsupportedError{version: apiVersion, minVersion: d.minVersion}
+		}
+		if d.deprecatedVersion != "" && versions.LessThan(apiVersion, d.deprecatedVersion) {
+			// See https://datatracker.ietf.org/doc/html/rfc7234#section-5.5
+			msg := fmt.Sprintf("API version %s is deprecated and will be removed in a future release; please upgrade your client to use API version %s or above", apiVersion, d.deprecatedVersion)
+			w.Header().Add("Warning", "299 - "+strconv.Quote(msg))
+		}
+		return handler(ctx, w, r, vars)
+	}
+}
@@ -0,0 +1,50 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/docker/errdefs"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestDeprecationMiddleware(t *testing.T) {
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		return nil
+	}
+	h := NewDeprecationMiddleware("1.24", "1.30").WrapHandler(handler)
+
+	tests := []struct {
+		version string
+		warning bool
+		err     string
+	}{
+		{version: ""},
+		{version: "1.41"},
+		{version: "1.30"},
+		{version: "1.29", warning: true},
+		{version: "1.24", warning: true},
+		{version: "1.23", err: "client version 1.23 is too old. Minimum supported API version is 1.24, please upgrade your client to a newer version"},
+	}
+	for _, tc := range tests {
+		t.Run("version "+tc.version, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/containers/json", nil)
+			resp := httptest.NewRecorder()
+			err := h(context.Background(), resp, req, map[string]string{"version": tc.version})
+			if tc.err != "" {
+				assert.Check(t, is.Error(err, tc.err))
+				assert.Check(t, errdefs.IsInvalidParameter(err))
+				return
+			}
+			assert.Check(t, err)
+			if tc.warning {
+				assert.Check(t, is.Contains(resp.Header().Get("Warning"), "299 - \"API version "+tc.version+" is deprecated"))
+			} else {
+				assert.Check(t, is.Equal(resp.Header().Get("Warning"), ""))
+			}
+		})
+	}
+}
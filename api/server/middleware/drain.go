@@ -0,0 +1,53 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"context"
+	"net/http"
+)
+
+type drainingError struct{}
+
+func (drainingError) Error() string {
+	return "the daemon is draining, and does not accept new requests that modify its state"
+}
+
+func (drainingError) Unavailable() {}
+
+// DrainMiddleware is a middleware that rejects requests with a "503 Service
+// Unavailable" status while the server is draining, except for read-only
+// requests (GET, HEAD, and OPTIONS), and for the routes in its allowlist.
+type DrainMiddleware struct {
+	draining  func() bool
+	allowlist map[string]struct{}
+}
+
+// NewDrainMiddleware creates a new DrainMiddleware, which uses draining to
+// check whether the server is draining. The allowlist contains the path
+// templates of routes (such as "/containers/{name:.*}/stop") that are
+// allowed for all methods while draining.
+func NewDrainMiddleware(draining func() bool, allowlist []string) DrainMiddleware {
+	m := DrainMiddleware{draining: draining, allowlist: make(map[string]struct{}, len(allowlist))}
+	for _, path := range allowlist {
+		m.allowlist[path] = struct{}{}
+	}
+	return m
+}
+
+// WrapHandler returns a new handler function wrapping the previous one in the request chain.
+func (m DrainMiddleware) WrapHandler(handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error) func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		if m.draining() && !m.allowed(ctx, r) {
+			return drainingError{}
+		}
+		return handler(ctx, w, r, vars)
+	}
+}
+
+func (m DrainMiddleware) allowed(ctx context.Context, r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	_, ok := m.allowlist[routeTemplate(ctx)]
+	return ok
+}
@@ -0,0 +1,47 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/docker/api/server/httputils"
+	"github.com/docker/docker/errdefs"
+	"github.com/gorilla/mux"
+	"gotest.tools/v3/assert"
+)
+
+func TestDrainMiddleware(t *testing.T) {
+	draining := false
+	m := NewDrainMiddleware(func() bool { return draining }, []string{"/containers/{name:.*}/stop"})
+
+	router := mux.NewRouter()
+	var lastErr error
+	h := m.WrapHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		return nil
+	})
+	handle := func(tpl string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), httputils.RouteTemplateKey{}, tpl)
+			lastErr = h(ctx, w, r, mux.Vars(r))
+		}
+	}
+	router.Path("/v{version:[0-9.]+}/containers/{name:.*}/stop").Methods(http.MethodPost).HandlerFunc(handle("/containers/{name:.*}/stop"))
+	router.Path("/v{version:[0-9.]+}/containers/{name:.*}/start").Methods(http.MethodPost).HandlerFunc(handle("/containers/{name:.*}/start"))
+	router.Path("/v{version:[0-9.]+}/containers/json").Methods(http.MethodGet).HandlerFunc(handle("/containers/json"))
+
+	do := func(method, path string) error {
+		lastErr = nil
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, path, nil))
+		return lastErr
+	}
+
+	assert.Check(t, do(http.MethodPost, "/v1.41/containers/foo/start"))
+
+	draining = true
+	err := do(http.MethodPost, "/v1.41/containers/foo/start")
+	assert.Check(t, errdefs.IsUnavailable(err), "expected unavailable error, got %v", err)
+	assert.Check(t, do(http.MethodPost, "/v1.41/containers/foo/stop"))
+	assert.Check(t, do(http.MethodGet, "/v1.41/containers/json"))
+}
@@ -0,0 +1,86 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// ResponseHeadersMiddleware is a middleware that sets a fixed set of headers
+// on every response, such as security headers. The headers are set right
+// before the response header is written, overriding the values set by the
+// handler, or, if the handler returns without writing a response, before
+// the server writes the error response.
+type ResponseHeadersMiddleware struct {
+	headers http.Header
+}
+
+// NewResponseHeadersMiddleware creates a new ResponseHeadersMiddleware
+// setting the given headers.
+func NewResponseHeadersMiddleware(headers map[string]string) ResponseHeadersMiddleware {
+	m := ResponseHeadersMiddleware{headers: make(http.Header, len(headers))}
+	for k, v := range headers {
+		m.headers.Set(k, v)
+	}
+	return m
+}
+
+// WrapHandler returns a new handler function wrapping the previous one in the request chain.
+func (m ResponseHeadersMiddleware) WrapHandler(handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error) func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		hw := &headerWriter{ResponseWriter: w, headers: m.headers}
+		err := handler(ctx, hw, r, vars)
+		hw.setHeaders()
+		return err
+	}
+}
+
+// headerWriter is an http.ResponseWriter that sets headers on the response
+// right before its header is written.
+type headerWriter struct {
+	http.ResponseWriter
+	headers http.Header
+	done    bool
+}
+
+func (h *headerWriter) setHeaders() {
+	if h.done {
+		return
+	}
+	h.done = true
+	for k, v := range h.headers {
+		h.ResponseWriter.Header()[k] = v
+	}
+}
+
+func (h *headerWriter) WriteHeader(code int) {
+	h.setHeaders()
+	h.ResponseWriter.WriteHeader(code)
+}
+
+func (h *headerWriter) Write(b []byte) (int, error) {
+	h.setHeaders()
+	return h.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher.
+func (h *headerWriter) Flush() {
+	if f, ok := h.ResponseWriter.(http.Flusher); ok {
+		h.setHeaders()
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker.
+func (h *headerWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := h.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not implement http.Hijacker")
+	}
+	// the handler writes the response to the hijacked connection itself.
+	h.done = true
+	return hj.Hijack()
+}
@@ -0,0 +1,42 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestResponseHeadersMiddleware(t *testing.T) {
+	m := NewResponseHeadersMiddleware(map[string]string{
+		"x-content-type-options":    "nosniff",
+		"Strict-Transport-Security": "max-age=31536000",
+	})
+
+	h := m.WrapHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		w.Header().Set("X-Content-Type-Options", "handler")
+		w.WriteHeader(http.StatusNoContent)
+		w.Header().Set("Strict-Transport-Security", "too late")
+		return nil
+	})
+	resp := httptest.NewRecorder()
+	assert.NilError(t, h(context.Background(), resp, httptest.NewRequest(http.MethodGet, "/", nil), nil))
+	result := resp.Result()
+	assert.Check(t, is.Equal(result.StatusCode, http.StatusNoContent))
+	assert.Check(t, is.Equal(result.Header.Get("X-Content-Type-Options"), "nosniff"))
+	assert.Check(t, is.Equal(result.Header.Get("Strict-Transport-Security"), "max-age=31536000"))
+
+	// the headers are set on the response written by the server for errors.
+	h = m.WrapHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		return errdefs.NotFound(errors.New("no such container"))
+	})
+	resp = httptest.NewRecorder()
+	err := h(context.Background(), resp, httptest.NewRequest(http.MethodGet, "/", nil), nil)
+	assert.Check(t, errdefs.IsNotFound(err))
+	assert.Check(t, is.Equal(resp.Header().Get("X-Content-Type-Options"), "nosniff"))
+}
@@ -0,0 +1,64 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type misdirectedRequestError struct {
+	host string
+}
+
+func (e misdirectedRequestError) Error() string {
+	return fmt.Sprintf("host %q is not allowed", e.host)
+}
+
+func (misdirectedRequestError) HTTPStatusCode() int {
+	return http.StatusMisdirectedRequest
+}
+
+// HostMiddleware is a middleware that rejects requests whose Host header is
+// not in its allowlist with a "421 Misdirected Request" status, to protect
+// TCP sockets against DNS rebinding attacks. Requests received on a unix
+// socket are not checked.
+type HostMiddleware struct {
+	hosts map[string]struct{}
+}
+
+// NewHostMiddleware creates a new HostMiddleware allowing the given hosts.
+// Hosts are matched case-insensitively; hosts without a port (such as
+// "docker.example.com") match any port, and hosts with a port (such as
+// "docker.example.com:2376") only match that port. If hosts is empty, all
+// hosts are allowed.
+func NewHostMiddleware(hosts []string) HostMiddleware {
+	m := HostMiddleware{hosts: make(map[string]struct{}, len(hosts))}
+	for _, h := range hosts {
+		m.hosts[strings.ToLower(strings.TrimSpace(h))] = struct{}{}
+	}
+	return m
+}
+
+// WrapHandler returns a new handler function wrapping the previous one in the request chain.
+func (m HostMiddleware) WrapHandler(handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error) func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		if len(m.hosts) > 0 && !isUnixSocket(r) && !m.allowed(r.Host) {
+			return misdirectedRequestError{host: r.Host}
+		}
+		return handler(ctx, w, r, vars)
+	}
+}
+
+func (m HostMiddleware) allowed(host string) bool {
+	host = strings.ToLower(host)
+	if _, ok := m.hosts[host]; ok {
+		return true
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		_, ok := m.hosts[h]
+		return ok
+	}
+	return false
+}
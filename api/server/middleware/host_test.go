@@ -0,0 +1,53 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/docker/api/server/httpstatus"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestHostMiddleware(t *testing.T) {
+	m := NewHostMiddleware([]string{"docker.example.com", "LOCALHOST:2375"})
+	h := m.WrapHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		return nil
+	})
+
+	tests := []struct {
+		host    string
+		allowed bool
+	}{
+		{host: "docker.example.com", allowed: true},
+		{host: "Docker.Example.com:2376", allowed: true},
+		{host: "localhost:2375", allowed: true},
+		{host: "localhost:2376"},
+		{host: "localhost"},
+		{host: "attacker.example.com"},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/info", nil)
+		req.Host = tc.host
+		err := h(req.Context(), httptest.NewRecorder(), req, nil)
+		if tc.allowed {
+			assert.Check(t, err, tc.host)
+		} else {
+			assert.Check(t, is.Equal(httpstatus.FromError(err), http.StatusMisdirectedRequest), tc.host)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/info", nil)
+	req.Host = "attacker.example.com"
+	req = req.WithContext(context.WithValue(req.Context(), http.LocalAddrContextKey, &net.UnixAddr{Name: "/var/run/docker.sock", Net: "unix"}))
+	assert.Check(t, h(req.Context(), httptest.NewRecorder(), req, nil), "requests on unix sockets should not be checked")
+
+	req = httptest.NewRequest(http.MethodGet, "/info", nil)
+	req.Host = "attacker.example.com"
+	assert.Check(t, NewHostMiddleware(nil).WrapHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		return nil
+	})(req.Context(), httptest.NewRecorder(), req, nil))
+}
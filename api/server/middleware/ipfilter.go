@@ -0,0 +1,99 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+)
+
+// IPFilterMiddleware is a middleware that rejects requests from source
+// addresses that are denied, or not allowed, with a "403 Forbidden" status.
+// Requests received on a unix socket are not checked.
+type IPFilterMiddleware struct {
+	allowed []*net.IPNet
+	denied  []*net.IPNet
+}
+
+// NewIPFilterMiddleware creates a new IPFilterMiddleware. The allowed and
+// denied lists contain CIDRs (such as "192.168.0.0/16") or IP addresses.
+// Requests from addresses in denied are rejected and, if allowed is not
+// empty, requests from addresses that are not in allowed.
+func NewIPFilterMiddleware(allowed, denied []string) (IPFilterMiddleware, error) {
+	var (
+		m   IPFilterMiddleware
+		err error
+	)
+	if m.allowed, err = parseCIDRs(allowed); err != nil {
+		return m, err
+	}
+	if m.denied, err = parseCIDRs(denied); err != nil {
+		return m, err
+	}
+	return m, nil
+}
+
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, c := range cidrs {
+		c = strings.TrimSpace(c)
+		if !strings.Contains(c, "/") {
+			ip := net.ParseIP(c)
+			if ip == nil {
+				return nil, errdefs.InvalidParameter(errors.Errorf("invalid IP address or CIDR: %q", c))
+			}
+			if ip4 := ip.To4(); ip4 != nil {
+				nets = append(nets, &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)})
+			} else {
+				nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)})
+			}
+			continue
+		}
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return nil, errdefs.InvalidParameter(errors.Errorf("invalid IP address or CIDR: %q", c))
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// WrapHandler returns a new handler function wrapping the previous one in the request chain.
+func (m IPFilterMiddleware) WrapHandler(handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error) func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		if isUnixSocket(r) || (len(m.allowed) == 0 && len(m.denied) == 0) {
+			return handler(ctx, w, r, vars)
+		}
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		ip := net.ParseIP(host)
+		if ip == nil || !m.allowedIP(ip) {
+			return errdefs.Forbidden(errors.Errorf("requests from %s are not allowed", host))
+		}
+		return handler(ctx, w, r, vars)
+	}
+}
+
+func (m IPFilterMiddleware) allowedIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if containsIP(m.denied, ip) {
+		return false
+	}
+	return len(m.allowed) == 0 || containsIP(m.allowed, ip)
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
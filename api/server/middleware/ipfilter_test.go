@@ -0,0 +1,57 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/docker/errdefs"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestIPFilterMiddleware(t *testing.T) {
+	m, err := NewIPFilterMiddleware([]string{"10.0.0.0/8", "192.168.1.10", "fd00::/8"}, []string{"10.1.0.0/16"})
+	assert.NilError(t, err)
+	h := m.WrapHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		return nil
+	})
+
+	tests := []struct {
+		remoteAddr string
+		allowed    bool
+	}{
+		{remoteAddr: "10.2.3.4:1234", allowed: true},
+		{remoteAddr: "192.168.1.10:1234", allowed: true},
+		{remoteAddr: "[::ffff:10.2.3.4]:1234", allowed: true},
+		{remoteAddr: "[fd00::1]:1234", allowed: true},
+		{remoteAddr: "10.1.2.3:1234"},
+		{remoteAddr: "192.168.1.11:1234"},
+		{remoteAddr: "[2001:db8::1]:1234"},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/info", nil)
+		req.RemoteAddr = tc.remoteAddr
+		err := h(req.Context(), httptest.NewRecorder(), req, nil)
+		if tc.allowed {
+			assert.Check(t, err, tc.remoteAddr)
+		} else {
+			assert.Check(t, errdefs.IsForbidden(err), tc.remoteAddr)
+		}
+	}
+
+	// unix sockets are not checked.
+	req := httptest.NewRequest(http.MethodGet, "/info", nil)
+	req.RemoteAddr = "@"
+	ctx := context.WithValue(req.Context(), http.LocalAddrContextKey, &net.UnixAddr{Name: "/var/run/docker.sock", Net: "unix"})
+	assert.Check(t, h(ctx, httptest.NewRecorder(), req.WithContext(ctx), nil))
+}
+
+func TestIPFilterMiddlewareInvalidCIDR(t *testing.T) {
+	_, err := NewIPFilterMiddleware([]string{"10.0.0.0/33"}, nil)
+	assert.Check(t, is.ErrorContains(err, `invalid IP address or CIDR: "10.0.0.0/33"`))
+	_, err = NewIPFilterMiddleware(nil, []string{"not-an-ip"})
+	assert.Check(t, errdefs.IsInvalidParameter(err))
+}
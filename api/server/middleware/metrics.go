@@ -0,0 +1,106 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api"
+	"github.com/docker/docker/api/server/httpstatus"
+	"github.com/docker/docker/api/server/httputils"
+	"github.com/docker/docker/api/types/versions"
+	metrics "github.com/docker/go-metrics"
+)
+
+var (
+	metricsNS = metrics.NewNamespace("engine", "api", nil)
+
+	requestsCounter = metricsNS.NewLabeledCounter("requests", "The number of API requests handled", "method", "route", "code")
+	requestsTimer   = metricsNS.NewLabeledTimer("request_duration", "The number of seconds it takes to handle an API request", "method", "route", "code")
+
+	// clientCertCounter is only updated for requests with a verified TLS
+	// client certificate.
+	clientCertCounter = metricsNS.NewLabeledCounter("client_cert_requests", "The number of API requests handled, by subject of the TLS client certificate", "subject", "code")
+
+	// versionTimer is labeled by the API version of the requests, so that
+	// the latency of requests of older clients can be compared.
+	versionTimer = metricsNS.NewLabeledTimer("request_duration_by_version", "The number of seconds it takes to handle an API request, by API version", "version", "route", "code")
+)
+
+func init() {
+	metrics.Register(metricsNS)
+}
+
+// MetricsMiddleware is a middleware that records the number of requests and
+// their duration, labeled by method, route template and status code. The
+// duration is also recorded by API version (see apiVersionBucket), and
+// requests are counted by subject of their TLS client certificate, if any
+// (see ClientCertMiddleware).
+type MetricsMiddleware struct{}
+
+// NewMetricsMiddleware creates a new MetricsMiddleware.
+func NewMetricsMiddleware() MetricsMiddleware {
+	return MetricsMiddleware{}
+}
+
+// WrapHandler returns a new handler function wrapping the previous one in the request chain.
+func (m MetricsMiddleware) WrapHandler(handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error) func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		start := time.Now()
+		rec := newStatusRecorder(w)
+		err := handler(ctx, rec, r, vars)
+
+		code := rec.Status()
+		if err != nil {
+			code = httpstatus.FromError(err)
+		}
+		labels := []string{r.Method, routeTemplate(ctx), strconv.Itoa(code)}
+		requestsCounter.WithValues(labels...).Inc()
+		requestsTimer.WithValues(labels...).UpdateSince(start)
+		if subject := httputils.ClientCertSubjectFromContext(ctx); subject != "" {
+			clientCertCounter.WithValues(subject, labels[2]).Inc()
+		}
+		versionTimer.WithValues(apiVersionBucket(vars["version"]), labels[1], labels[2]).UpdateSince(start)
+		return err
+	}
+}
+
+// routeTemplate returns the path template of the route that matched the
+// request (e.g. "/containers/{name:.*}/start"), as stored in ctx by the
+// server, or "unknown" if ctx has no route template.
+func routeTemplate(ctx context.Context) string {
+	if tpl := httputils.RouteTemplateFromContext(ctx); tpl != "" {
+		return tpl
+	}
+	return "unknown"
+}
+
+// apiVersionBucket returns the label recording the API version v of a
+// request: its normalized "major.minor" form, "none" if the request has no
+// version, "newer" if v is newer than the current API version, and
+// "invalid" if it cannot be parsed. This bounds the number of labels, as
+// the version is sent by (possibly misbehaving) clients.
+func apiVersionBucket(v string) string {
+	if v == "" {
+		return "none"
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) < 2 {
+		return "invalid"
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil || major < 1 {
+		return "invalid"
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil || minor < 0 {
+		return "invalid"
+	}
+	bucket := strconv.Itoa(major) + "." + strconv.Itoa(minor)
+	if versions.GreaterThan(bucket, api.DefaultVersion) {
+		return "newer"
+	}
+	return bucket
+}
@@ -0,0 +1,95 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/docker/api/server/httputils"
+	"github.com/docker/docker/errdefs"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	m := NewMetricsMiddleware()
+	h := m.WrapHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		return errdefs.NotFound(errors.New("no such container"))
+	})
+
+	router := mux.NewRouter()
+	router.Path("/v{version:[0-9.]+}/containers/{name:.*}/json").Methods(http.MethodGet).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), httputils.RouteTemplateKey{}, "/containers/{name:.*}/json")
+		err := h(ctx, w, r, mux.Vars(r))
+		assert.Check(t, errdefs.IsNotFound(err))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1.41/containers/foo/json", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	assert.NilError(t, err)
+
+	var found bool
+	for _, f := range families {
+		if f.GetName() != "engine_api_requests_total" {
+			continue
+		}
+		for _, metric := range f.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range metric.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["route"] == "/containers/{name:.*}/json" {
+				found = true
+				assert.Check(t, is.Equal(labels["method"], http.MethodGet))
+				assert.Check(t, is.Equal(labels["code"], "404"))
+				assert.Check(t, is.Equal(metric.GetCounter().GetValue(), float64(1)))
+			}
+		}
+	}
+	assert.Check(t, found, "no request metric recorded for route")
+
+	found = false
+	for _, f := range families {
+		if f.GetName() != "engine_api_request_duration_by_version_seconds" {
+			continue
+		}
+		for _, metric := range f.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range metric.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["route"] == "/containers/{name:.*}/json" {
+				found = true
+				assert.Check(t, is.Equal(labels["version"], "1.41"))
+				assert.Check(t, is.Equal(labels["code"], "404"))
+				assert.Check(t, is.Equal(metric.GetHistogram().GetSampleCount(), uint64(1)))
+			}
+		}
+	}
+	assert.Check(t, found, "no request duration recorded for API version")
+}
+
+func TestAPIVersionBucket(t *testing.T) {
+	for _, tc := range []struct {
+		version  string
+		expected string
+	}{
+		{version: "1.41", expected: "1.41"},
+		{version: "1.041", expected: "1.41"},
+		{version: "1.24.1", expected: "1.24"},
+		{version: "", expected: "none"},
+		{version: "1.9999", expected: "newer"},
+		{version: "2.0", expected: "newer"},
+		{version: "1", expected: "invalid"},
+		{version: "1.x", expected: "invalid"},
+		{version: "0.5", expected: "invalid"},
+	} {
+		assert.Check(t, is.Equal(apiVersionBucket(tc.version), tc.expected), "version %q", tc.version)
+	}
+}
@@ -2,11 +2,49 @@ package middleware // import "github.com/docker/docker/api/server/middleware"
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+
+	"github.com/sirupsen/logrus"
 )
 
+// log is the logger of the middlewares. Its entries have a "component" and a
+// "subcomponent" field, so that they can be filtered from the logs of the
+// daemon.
+var log = logrus.WithFields(logrus.Fields{"component": "api", "subcomponent": "middleware"})
+
 // Middleware is an interface to allow the use of ordinary functions as Docker API filters.
 // Any struct that has the appropriate signature can be registered as a middleware.
 type Middleware interface {
 	WrapHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error) func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error
 }
+
+// NamedMiddleware is a Middleware with a name, which can be used to refer to
+// the middleware when configuring the order of the request chain.
+type NamedMiddleware interface {
+	Middleware
+	Name() string
+}
+
+type namedMiddleware struct {
+	Middleware
+	name string
+}
+
+func (m namedMiddleware) Name() string {
+	return m.name
+}
+
+// WithName returns m as a NamedMiddleware with the given name.
+func WithName(name string, m Middleware) NamedMiddleware {
+	return namedMiddleware{Middleware: m, name: name}
+}
+
+// NameOf returns the name of m if it is a NamedMiddleware, or the name of
+// its type otherwise.
+func NameOf(m Middleware) string {
+	if n, ok := m.(NamedMiddleware); ok {
+		return n.Name()
+	}
+	return fmt.Sprintf("%T", m)
+}
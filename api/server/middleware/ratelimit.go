@@ -0,0 +1,125 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// rateLimiterIdleTimeout is the duration after which the rate limiter of a
+// client that did not send any request is discarded.
+const rateLimiterIdleTimeout = 10 * time.Minute
+
+type tooManyRequestsError struct {
+	retryAfter time.Duration
+}
+
+func (e tooManyRequestsError) Error() string {
+	return fmt.Sprintf("too many requests: retry after %s", e.retryAfter)
+}
+
+func (tooManyRequestsError) HTTPStatusCode() int {
+	return http.StatusTooManyRequests
+}
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// RateLimitMiddleware is a middleware that limits the rate of requests of
+// each client, using a token bucket. Clients are identified by the common
+// name of their verified TLS client certificate, or by their IP address
+// otherwise.
+type RateLimitMiddleware struct {
+	limit          rate.Limit
+	burst          int
+	skipUnixSocket bool
+
+	mu        sync.Mutex
+	clients   map[string]*clientLimiter
+	lastSweep time.Time
+}
+
+// NewRateLimitMiddleware creates a new RateLimitMiddleware allowing each
+// client rps requests per second, with bursts of up to burst requests. If
+// skipUnixSocket is set, requests received on a unix socket are not limited.
+func NewRateLimitMiddleware(rps float64, burst int, skipUnixSocket bool) *RateLimitMiddleware {
+	return &RateLimitMiddleware{
+		limit:          rate.Limit(rps),
+		burst:          burst,
+		skipUnixSocket: skipUnixSocket,
+		clients:        make(map[string]*clientLimiter),
+	}
+}
+
+// WrapHandler returns a new handler function wrapping the previous one in the request chain.
+func (m *RateLimitMiddleware) WrapHandler(handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error) func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		if m.skipUnixSocket && isUnixSocket(r) {
+			return handler(ctx, w, r, vars)
+		}
+		res := m.limiter(clientIdentity(r)).Reserve()
+		if delay := res.Delay(); delay > 0 {
+			res.Cancel()
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			return tooManyRequestsError{retryAfter: delay}
+		}
+		return handler(ctx, w, r, vars)
+	}
+}
+
+// limiter returns the rate limiter for the given client, and discards the
+// limiters of clients that have been idle for rateLimiterIdleTimeout.
+func (m *RateLimitMiddleware) limiter(client string) *rate.Limiter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(m.lastSweep) > rateLimiterIdleTimeout {
+		for k, c := range m.clients {
+			if now.Sub(c.lastSeen) > rateLimiterIdleTimeout {
+				delete(m.clients, k)
+			}
+		}
+		m.lastSweep = now
+	}
+
+	c, ok := m.clients[client]
+	if !ok {
+		c = &clientLimiter{limiter: rate.NewLimiter(m.limit, m.burst)}
+		m.clients[client] = c
+	}
+	c.lastSeen = now
+	return c.limiter
+}
+
+// clientIdentity returns the common name of the leaf certificate of the
+// first verified chain of the client's TLS certificate, or the client's IP
+// address if no verified certificate was presented. As for
+// clientCertSubject, certificates that were not verified are ignored, as
+// clients could forge their common name to use the limit of other clients,
+// or rotate it to escape the limit.
+func clientIdentity(r *http.Request) string {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		return "cn:" + r.TLS.VerifiedChains[0][0].Subject.CommonName
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// isUnixSocket returns whether the request was received on a unix socket.
+func isUnixSocket(r *http.Request) bool {
+	addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr)
+	return ok && addr.Network() == "unix"
+}
@@ -0,0 +1,76 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/docker/api/server/httpstatus"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		return nil
+	}
+	h := NewRateLimitMiddleware(0.001, 1, true).WrapHandler(handler)
+
+	request := func(remoteAddr string, local net.Addr) (*httptest.ResponseRecorder, error) {
+		req := httptest.NewRequest(http.MethodGet, "/containers/json", nil)
+		req.RemoteAddr = remoteAddr
+		if local != nil {
+			req = req.WithContext(context.WithValue(req.Context(), http.LocalAddrContextKey, local))
+		}
+		resp := httptest.NewRecorder()
+		return resp, h(req.Context(), resp, req, map[string]string{})
+	}
+
+	_, err := request("192.0.2.1:1234", nil)
+	assert.Check(t, err)
+
+	resp, err := request("192.0.2.1:5678", nil)
+	assert.Check(t, is.ErrorContains(err, "too many requests"))
+	assert.Check(t, is.Equal(httpstatus.FromError(err), http.StatusTooManyRequests))
+	assert.Check(t, resp.Header().Get("Retry-After") != "")
+
+	// other clients have their own limit
+	_, err = request("192.0.2.2:1234", nil)
+	assert.Check(t, err)
+
+	// clients with a verified certificate are identified by its common
+	// name, but unverified certificates are ignored.
+	verified := &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "operator"}}},
+	}
+	verified.VerifiedChains = [][]*x509.Certificate{verified.PeerCertificates}
+	forged := &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "operator"}}},
+	}
+	requestTLS := func(remoteAddr string, state *tls.ConnectionState) error {
+		req := httptest.NewRequest(http.MethodGet, "/containers/json", nil)
+		req.RemoteAddr = remoteAddr
+		req.TLS = state
+		return h(req.Context(), httptest.NewRecorder(), req, map[string]string{})
+	}
+	assert.Check(t, requestTLS("192.0.2.3:1234", verified))
+	err = requestTLS("192.0.2.4:1234", forged)
+	assert.Check(t, err, "an unverified certificate must not use the limit of the client with its common name")
+	err = requestTLS("192.0.2.4:5678", &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "rotated"}}},
+	})
+	assert.Check(t, is.ErrorContains(err, "too many requests"), "rotating the common name of unverified certificates must not escape the limit")
+	assert.Check(t, is.ErrorContains(requestTLS("192.0.2.5:1234", verified), "too many requests"))
+
+	// requests on the unix socket are not limited
+	unix := &net.UnixAddr{Name: "/var/run/docker.sock", Net: "unix"}
+	for i := 0; i < 3; i++ {
+		_, err = request("@", unix)
+		assert.Check(t, err)
+	}
+}
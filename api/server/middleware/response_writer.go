@@ -0,0 +1,70 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// statusRecorder wraps an http.ResponseWriter to record the status code and
+// number of bytes written by the handler. It forwards http.Flusher and
+// http.Hijacker to the wrapped ResponseWriter, so that streaming and
+// hijacking endpoints keep working.
+type statusRecorder struct {
+	http.ResponseWriter
+	status  int
+	written int64
+}
+
+func newStatusRecorder(w http.ResponseWriter) *statusRecorder {
+	return &statusRecorder{ResponseWriter: w}
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if s.status == 0 {
+		s.status = code
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(b)
+	s.written += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher.
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker.
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := s.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not implement http.Hijacker")
+	}
+	if s.status == 0 {
+		// hijacked connections are handed over to the handler, which
+		// usually writes a raw "101 UPGRADED" or "200 OK" response.
+		s.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
+
+// Status returns the status code written to the response. It returns
+// http.StatusOK if the handler did not write a response (yet), which is
+// the status that net/http sends by default.
+func (s *statusRecorder) Status() int {
+	if s.status == 0 {
+		return http.StatusOK
+	}
+	return s.status
+}
@@ -0,0 +1,72 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/docker/docker/api/server/httpstatus"
+	"github.com/docker/docker/api/server/httputils"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/docker/docker/api/server"
+
+// TracingMiddleware is a middleware that starts a span for each request,
+// named after the template of the route that matched the request. Spans
+// continue the trace propagated by the client in the "traceparent" header,
+// and are added to the context of the request. They are annotated with the
+// request ID, so that spans and logs can be correlated.
+//
+// Spans end when the handler returns, so the spans of streaming and
+// hijacking endpoints (such as attach and logs) last until the stream is
+// closed.
+type TracingMiddleware struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// NewTracingMiddleware creates a new TracingMiddleware, which creates spans
+// using tp, or the global TracerProvider if tp is nil.
+func NewTracingMiddleware(tp trace.TracerProvider) TracingMiddleware {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return TracingMiddleware{
+		tracer:     tp.Tracer(tracerName),
+		propagator: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+	}
+}
+
+// WrapHandler returns a new handler function wrapping the previous one in the request chain.
+func (m TracingMiddleware) WrapHandler(handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error) func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		ctx = m.propagator.Extract(ctx, propagation.HeaderCarrier(r.Header))
+		route := routeTemplate(ctx)
+		ctx, span := m.tracer.Start(ctx, route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPMethodKey.String(r.Method),
+				semconv.HTTPTargetKey.String(r.URL.Path),
+				semconv.HTTPRouteKey.String(route),
+				attribute.String("docker.request_id", httputils.RequestIDFromContext(ctx)),
+			),
+		)
+		defer span.End()
+
+		rec := newStatusRecorder(w)
+		err := handler(ctx, rec, r.WithContext(ctx), vars)
+
+		code := rec.Status()
+		if err != nil {
+			code = httpstatus.FromError(err)
+			span.RecordError(err)
+		}
+		span.SetAttributes(semconv.HTTPStatusCodeKey.Int(code))
+		span.SetStatus(semconv.SpanStatusFromHTTPStatusCodeAndSpanKind(code, trace.SpanKindServer))
+		return err
+	}
+}
@@ -0,0 +1,76 @@
+package middleware // import "github.com/docker/docker/api/server/middleware"
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/docker/docker/api/server/httputils"
+	"github.com/docker/docker/errdefs"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []sdktrace.ReadOnlySpan
+}
+
+func (r *spanRecorder) ExportSpans(_ context.Context, spans []sdktrace.ReadOnlySpan) error {
+	r.mu.Lock()
+	r.spans = append(r.spans, spans...)
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *spanRecorder) Shutdown(context.Context) error {
+	return nil
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	recorder := &spanRecorder{}
+	m := NewTracingMiddleware(sdktrace.NewTracerProvider(sdktrace.WithSyncer(recorder)))
+
+	var handlerSpan trace.SpanContext
+	h := m.WrapHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		handlerSpan = trace.SpanContextFromContext(ctx)
+		return errdefs.NotFound(errors.New("no such container"))
+	})
+
+	router := mux.NewRouter()
+	router.Path("/v{version:[0-9.]+}/containers/{name:.*}/json").Methods(http.MethodGet).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), httputils.RequestIDKey{}, "abc123")
+		ctx = context.WithValue(ctx, httputils.RouteTemplateKey{}, "/containers/{name:.*}/json")
+		err := h(ctx, w, r, mux.Vars(r))
+		assert.Check(t, errdefs.IsNotFound(err))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1.41/containers/foo/json", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Assert(t, is.Len(recorder.spans, 1))
+	span := recorder.spans[0]
+	assert.Check(t, is.Equal(span.Name(), "/containers/{name:.*}/json"))
+	assert.Check(t, is.Equal(span.SpanKind(), trace.SpanKindServer))
+	assert.Check(t, is.Equal(span.SpanContext().TraceID().String(), "4bf92f3577b34da6a3ce929d0e0e4736"))
+	assert.Check(t, is.Equal(span.Parent().SpanID().String(), "00f067aa0ba902b7"))
+	assert.Check(t, is.Equal(span.SpanContext().SpanID(), handlerSpan.SpanID()))
+	assert.Check(t, is.Equal(span.Status().Code, codes.Unset))
+	assert.Check(t, !span.EndTime().Before(span.StartTime()))
+
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	assert.Check(t, is.Equal(attrs["http.status_code"].AsInt64(), int64(http.StatusNotFound)))
+	assert.Check(t, is.Equal(attrs["docker.request_id"].AsString(), "abc123"))
+}
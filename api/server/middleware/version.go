@@ -41,11 +41,19 @@ func (e versionUnsupportedError) Error() string {
 
 func (e versionUnsupportedError) InvalidParameter() {}
 
+func (e versionUnsupportedError) APIErrorCode() string {
+	return "unsupported_api_version"
+}
+
 // WrapHandler returns a new handler function wrapping the previous one in the request chain.
 func (v VersionMiddleware) WrapHandler(handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error) func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 		w.Header().Set("Server", fmt.Sprintf("Docker/%s (%s)", v.serverVersion, runtime.GOOS))
-		w.Header().Set("API-Version", v.defaultVersion)
+		if w.Header().Get("API-Version") == "" {
+			// The header may be set to a lower version by a
+			// MaxVersionMiddleware.
+			w.Header().Set("API-Version", v.defaultVersion)
+		}
 		w.Header().Set("OSType", runtime.GOOS)
 
 		apiVersion := vars["version"]
@@ -58,8 +66,43 @@ func (v VersionMiddleware) WrapHandler(handler func(ctx context.Context, w http.
 		if versions.GreaterThan(apiVersion, v.defaultVersion) {
 			return versionUnsupportedError{version: apiVersion, maxVersion: v.defaultVersion}
 		}
+		if _, err := httputils.ParseAPIVersion(apiVersion); err != nil {
+			return err
+		}
 		ctx = context.WithValue(ctx, httputils.APIVersionKey{}, apiVersion)
 		return handler(ctx, w, r, vars)
 	}
 
 }
+
+// MaxVersionMiddleware is a middleware that rejects requests for an API
+// version higher than a maximum version, which may be lower than the
+// version supported by the daemon, so that clients that assume features
+// the maximum version does not provide fail early, instead of being served
+// a newer contract.
+type MaxVersionMiddleware struct {
+	maxVersion string
+}
+
+// NewMaxVersionMiddleware creates a new MaxVersionMiddleware rejecting the
+// requests for an API version higher than maxVersion with a "400 Bad
+// Request" status. Requests without a version use maxVersion, which is also
+// returned in the "API-Version" header, for clients to negotiate the API
+// version. The middleware must be evaluated before the VersionMiddleware.
+func NewMaxVersionMiddleware(maxVersion string) MaxVersionMiddleware {
+	return MaxVersionMiddleware{maxVersion: maxVersion}
+}
+
+// WrapHandler returns a new handler function wrapping the previous one in the request chain.
+func (m MaxVersionMiddleware) WrapHandler(handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error) func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		w.Header().Set("API-Version", m.maxVersion)
+		apiVersion := vars["version"]
+		if apiVersion == "" {
+			vars["version"] = m.maxVersion
+		} else if versions.GreaterThan(apiVersion, m.maxVersion) {
+			return versionUnsupportedError{version: apiVersion, maxVersion: m.maxVersion}
+		}
+		return handler(ctx, w, r, vars)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/docker/docker/api/server/httputils"
+	"github.com/docker/docker/errdefs"
 	"gotest.tools/v3/assert"
 	is "gotest.tools/v3/assert/cmp"
 )
@@ -18,7 +19,8 @@ func TestVersionMiddlewareVersion(t *testing.T) {
 	expectedVersion := defaultVersion
 	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 		v := httputils.VersionFromContext(ctx)
-		assert.Check(t, is.Equal(expectedVersion, v))
+		assert.Check(t, is.Equal(expectedVersion, v.String()))
+		assert.Check(t, v.Equal(expectedVersion))
 		return nil
 	}
 
@@ -49,6 +51,10 @@ func TestVersionMiddlewareVersion(t *testing.T) {
 			reqVersion: "9999.9999",
 			errString:  "client version 9999.9999 is too new. Maximum supported API version is 1.10.0",
 		},
+		{
+			reqVersion: "1.9..0",
+			errString:  "invalid API version: 1.9..0",
+		},
 	}
 
 	for _, test := range tests {
@@ -90,3 +96,28 @@ func TestVersionMiddlewareWithErrorsReturnsHeaders(t *testing.T) {
 	assert.Check(t, is.Equal(hdr.Get("API-Version"), defaultVersion))
 	assert.Check(t, is.Equal(hdr.Get("OSType"), runtime.GOOS))
 }
+
+func TestMaxVersionMiddleware(t *testing.T) {
+	m := NewMaxVersionMiddleware("1.40")
+	req, _ := http.NewRequest(http.MethodGet, "/containers/json", nil)
+	var version string
+	h := m.WrapHandler(NewVersionMiddleware("1.10.0", "1.41", "1.12").WrapHandler(func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		version = httputils.VersionFromContext(ctx).String()
+		return nil
+	}))
+
+	resp := httptest.NewRecorder()
+	assert.Check(t, h(context.Background(), resp, req, map[string]string{}))
+	assert.Check(t, is.Equal(version, "1.40"))
+	assert.Check(t, is.Equal(resp.Header().Get("API-Version"), "1.40"))
+
+	resp = httptest.NewRecorder()
+	assert.Check(t, h(context.Background(), resp, req, map[string]string{"version": "1.39"}))
+	assert.Check(t, is.Equal(version, "1.39"))
+
+	resp = httptest.NewRecorder()
+	err := h(context.Background(), resp, req, map[string]string{"version": "1.41"})
+	assert.Check(t, is.Error(err, "client version 1.41 is too new. Maximum supported API version is 1.40"))
+	assert.Check(t, errdefs.IsInvalidParameter(err))
+	assert.Check(t, is.Equal(resp.Header().Get("API-Version"), "1.40"))
+}
@@ -0,0 +1,223 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ocsp"
+)
+
+const (
+	// ocspRetryInterval is the time after which an OCSP response that
+	// failed to be obtained is retried.
+	ocspRetryInterval = time.Minute
+
+	// ocspDefaultRefresh is the time after which an OCSP response without
+	// a next update time is refreshed.
+	ocspDefaultRefresh = time.Hour
+
+	// ocspMaxResponseBytes is the maximum size of the OCSP responses read
+	// from the responder.
+	ocspMaxResponseBytes = 1 << 20
+)
+
+// ocspStapler staples an OCSP response to the certificate presented by TLS
+// listeners, so that clients checking the revocation of the certificate do
+// not need to query the OCSP responder of its CA. The response is fetched
+// from a responder, or read from a file, in the background, and refreshed
+// halfway through its validity. Failing to obtain a response only prevents
+// stapling: the certificate is presented without a response, and clients
+// can still query the responder themselves.
+type ocspStapler struct {
+	responderURL string
+	stapleFile   string
+	client       *http.Client
+
+	mu         sync.Mutex
+	leaf       []byte // the certificate the response is for
+	response   []byte
+	nextUpdate time.Time
+	refreshAt  time.Time
+	refreshing bool
+}
+
+func newOCSPStapler(responderURL, stapleFile string) *ocspStapler {
+	return &ocspStapler{
+		responderURL: responderURL,
+		stapleFile:   stapleFile,
+		client:       &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// configureOCSPStapling sets up tlsConfig to staple the OCSP response
+// obtained from responderURL, or read from stapleFile, to the certificate
+// it presents to clients.
+func configureOCSPStapling(tlsConfig *tls.Config, responderURL, stapleFile string) *ocspStapler {
+	s := newOCSPStapler(responderURL, stapleFile)
+	getCertificate := tlsConfig.GetCertificate
+	certificates := tlsConfig.Certificates
+	staple := func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		var cert *tls.Certificate
+		if getCertificate != nil {
+			c, err := getCertificate(hello)
+			if err != nil {
+				return nil, err
+			}
+			cert = c
+		}
+		if cert == nil && len(certificates) > 0 {
+			cert = &certificates[0]
+		}
+		if cert == nil {
+			return nil, nil
+		}
+		return s.staple(cert), nil
+	}
+	tlsConfig.GetCertificate = staple
+	if getConfigForClient := tlsConfig.GetConfigForClient; getConfigForClient != nil {
+		// Configurations returned for a client (such as those of the
+		// tlsFileReloader) must staple the response in the same way.
+		tlsConfig.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			c, err := getConfigForClient(hello)
+			if c != nil {
+				c.GetCertificate = staple
+			}
+			return c, err
+		}
+	}
+	return s
+}
+
+// staple returns a copy of cert with the OCSP response for cert, if one is
+// available. It starts refreshing the response in the background if it is
+// missing, or due to be refreshed.
+func (s *ocspStapler) staple(cert *tls.Certificate) *tls.Certificate {
+	if len(cert.Certificate) == 0 {
+		return cert
+	}
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sameCert := bytes.Equal(s.leaf, cert.Certificate[0])
+	if (!sameCert || !now.Before(s.refreshAt)) && !s.refreshing {
+		s.refreshing = true
+		go s.refresh(cert)
+	}
+	if !sameCert || s.response == nil || (!s.nextUpdate.IsZero() && now.After(s.nextUpdate)) {
+		return cert
+	}
+	stapled := *cert
+	stapled.OCSPStaple = s.response
+	return &stapled
+}
+
+// refresh obtains the OCSP response for cert. On failure, the previous
+// response remains stapled until it expires, and is refreshed again after
+// ocspRetryInterval.
+func (s *ocspStapler) refresh(cert *tls.Certificate) {
+	resp, der, err := s.fetch(cert)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refreshing = false
+	if !bytes.Equal(s.leaf, cert.Certificate[0]) {
+		// the certificate was replaced: do not staple the response of the
+		// previous certificate.
+		s.leaf = cert.Certificate[0]
+		s.response, s.nextUpdate = nil, time.Time{}
+	}
+	if err != nil {
+		log.WithError(err).Warn("failed to obtain OCSP response; TLS certificates are presented without OCSP staple")
+		s.refreshAt = time.Now().Add(ocspRetryInterval)
+		return
+	}
+	s.response, s.nextUpdate = der, resp.NextUpdate
+	if resp.NextUpdate.IsZero() {
+		s.refreshAt = time.Now().Add(ocspDefaultRefresh)
+	} else {
+		s.refreshAt = resp.ThisUpdate.Add(resp.NextUpdate.Sub(resp.ThisUpdate) / 2)
+	}
+}
+
+// fetch returns the OCSP response for cert, and its DER encoding. Only
+// responses with a "good" status are returned, as there is no point in
+// stapling a response telling clients not to trust the certificate.
+func (s *ocspStapler) fetch(cert *tls.Certificate) (*ocsp.Response, []byte, error) {
+	leaf, issuer, err := parseCertificateChain(cert)
+	if err != nil {
+		return nil, nil, err
+	}
+	var der []byte
+	if s.stapleFile != "" {
+		der, err = os.ReadFile(s.stapleFile)
+	} else {
+		der, err = s.query(leaf, issuer)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := ocsp.ParseResponseForCert(der, leaf, issuer)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "invalid OCSP response")
+	}
+	if resp.Status != ocsp.Good {
+		return nil, nil, errors.Errorf("OCSP response status of the TLS certificate is %s", ocspStatus(resp.Status))
+	}
+	if !resp.NextUpdate.IsZero() && time.Now().After(resp.NextUpdate) {
+		return nil, nil, errors.Errorf("OCSP response expired at %s", resp.NextUpdate.Format(time.RFC3339))
+	}
+	return resp, der, nil
+}
+
+// query requests the OCSP response for leaf from the OCSP responder.
+func (s *ocspStapler) query(leaf, issuer *x509.Certificate) ([]byte, error) {
+	req, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create OCSP request")
+	}
+	resp, err := s.client.Post(s.responderURL, "application/ocsp-request", bytes.NewReader(req))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query OCSP responder")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("OCSP responder returned status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, ocspMaxResponseBytes))
+}
+
+// parseCertificateChain returns the leaf certificate of cert, and the
+// certificate of its issuer, which must follow it in the chain.
+func parseCertificateChain(cert *tls.Certificate) (leaf, issuer *x509.Certificate, err error) {
+	if len(cert.Certificate) < 2 {
+		return nil, nil, errors.New("TLS certificate chain does not include the issuer certificate, which is required for OCSP stapling")
+	}
+	leaf = cert.Leaf
+	if leaf == nil {
+		if leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return nil, nil, errors.Wrap(err, "failed to parse TLS certificate")
+		}
+	}
+	if issuer, err = x509.ParseCertificate(cert.Certificate[1]); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to parse TLS issuer certificate")
+	}
+	return leaf, issuer, nil
+}
+
+func ocspStatus(status int) string {
+	switch status {
+	case ocsp.Good:
+		return "good"
+	case ocsp.Revoked:
+		return "revoked"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,159 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/poll"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.NilError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NilError(t, err)
+	return &testCA{cert: cert, key: key}
+}
+
+// issue returns a certificate issued by the CA, with the CA certificate in
+// its chain.
+func (ca *testCA) issue(t *testing.T, serial int64) *tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "docker"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	assert.NilError(t, err)
+	return &tls.Certificate{Certificate: [][]byte{der, ca.cert.Raw}, PrivateKey: key}
+}
+
+func (ca *testCA) ocspResponse(t *testing.T, serial *big.Int, status int) []byte {
+	t.Helper()
+	resp, err := ocsp.CreateResponse(ca.cert, ca.cert, ocsp.Response{
+		Status:       status,
+		SerialNumber: serial,
+		ThisUpdate:   time.Now().Add(-time.Minute),
+		NextUpdate:   time.Now().Add(time.Hour),
+		RevokedAt:    time.Now().Add(-time.Minute),
+	}, ca.key)
+	assert.NilError(t, err)
+	return resp
+}
+
+func TestOCSPStaplingResponder(t *testing.T) {
+	ca := newTestCA(t)
+	cert := ca.issue(t, 2)
+
+	var failing bool
+	responder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		assert.Check(t, err)
+		req, err := ocsp.ParseRequest(body)
+		if !assert.Check(t, err) {
+			return
+		}
+		_, _ = w.Write(ca.ocspResponse(t, req.SerialNumber, ocsp.Good))
+	}))
+	defer responder.Close()
+
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{*cert}}
+	s := configureOCSPStapling(tlsConfig, responder.URL, "")
+
+	// the response is fetched in the background, and stapled once fetched.
+	poll.WaitOn(t, func(poll.LogT) poll.Result {
+		c, err := tlsConfig.GetCertificate(&tls.ClientHelloInfo{})
+		if err != nil {
+			return poll.Error(err)
+		}
+		if len(c.OCSPStaple) == 0 {
+			return poll.Continue("waiting for OCSP staple")
+		}
+		return poll.Success()
+	}, poll.WithTimeout(10*time.Second), poll.WithDelay(10*time.Millisecond))
+
+	c, err := tlsConfig.GetCertificate(&tls.ClientHelloInfo{})
+	assert.NilError(t, err)
+	resp, err := ocsp.ParseResponse(c.OCSPStaple, ca.cert)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(resp.Status, ocsp.Good))
+	assert.Check(t, is.Len(cert.OCSPStaple, 0), "the configured certificate must not be modified")
+
+	// the previous response remains stapled if refreshing it fails
+	failing = true
+	s.refresh(cert)
+	c, err = tlsConfig.GetCertificate(&tls.ClientHelloInfo{})
+	assert.NilError(t, err)
+	assert.Check(t, len(c.OCSPStaple) > 0)
+
+	// but not for another certificate
+	other := ca.issue(t, 3)
+	s.refresh(other)
+	assert.Check(t, is.Len(s.staple(other).OCSPStaple, 0))
+}
+
+func TestOCSPStaplingFile(t *testing.T) {
+	ca := newTestCA(t)
+	cert := ca.issue(t, 2)
+	stapleFile := filepath.Join(t.TempDir(), "staple.der")
+	s := newOCSPStapler("", stapleFile)
+
+	// missing file
+	s.refresh(cert)
+	assert.Check(t, is.Len(s.staple(cert).OCSPStaple, 0))
+
+	// revoked certificate
+	assert.NilError(t, os.WriteFile(stapleFile, ca.ocspResponse(t, big.NewInt(2), ocsp.Revoked), 0o600))
+	s.refresh(cert)
+	assert.Check(t, is.Len(s.staple(cert).OCSPStaple, 0))
+
+	// response for another certificate
+	assert.NilError(t, os.WriteFile(stapleFile, ca.ocspResponse(t, big.NewInt(3), ocsp.Good), 0o600))
+	s.refresh(cert)
+	assert.Check(t, is.Len(s.staple(cert).OCSPStaple, 0))
+
+	staple := ca.ocspResponse(t, big.NewInt(2), ocsp.Good)
+	assert.NilError(t, os.WriteFile(stapleFile, staple, 0o600))
+	s.refresh(cert)
+	assert.Check(t, is.DeepEqual(s.staple(cert).OCSPStaple, staple))
+}
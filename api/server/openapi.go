@@ -0,0 +1,98 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/docker/docker/api"
+	"github.com/docker/docker/api/server/httputils"
+)
+
+// openAPIPath is the path of the endpoint serving the description of the
+// API, if enabled by Config.EnableOpenAPI.
+const openAPIPath = "/_admin/openapi"
+
+// pathVarPattern matches the variables of path templates, such as
+// "{name:.*}", capturing their name.
+var pathVarPattern = regexp.MustCompile(`\{([^:}]+)(?::[^}]*)?\}`)
+
+// openAPISpec is a minimal Swagger 2.0 description of the API, generated
+// from the routes of the server.
+type openAPISpec struct {
+	Swagger  string                                 `json:"swagger"`
+	Info     openAPIInfo                            `json:"info"`
+	BasePath string                                 `json:"basePath"`
+	Paths    map[string]map[string]openAPIOperation `json:"paths"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIOperation struct {
+	Parameters []openAPIParameter         `json:"parameters,omitempty"`
+	Responses  map[string]openAPIResponse `json:"responses"`
+	Deprecated bool                       `json:"deprecated,omitempty"`
+}
+
+type openAPIParameter struct {
+	Name     string `json:"name"`
+	In       string `json:"in"`
+	Required bool   `json:"required"`
+	Type     string `json:"type"`
+}
+
+type openAPIResponse struct {
+	Description string `json:"description"`
+}
+
+// generateOpenAPISpec returns a description of the API routes, listing their
+// paths, methods, and path parameters, but not their request and response
+// bodies. The debug routes are not included.
+func (s *Server) generateOpenAPISpec() openAPISpec {
+	spec := openAPISpec{
+		Swagger:  "2.0",
+		Info:     openAPIInfo{Title: "Docker Engine API", Version: api.DefaultVersion},
+		BasePath: "/v" + api.DefaultVersion,
+		Paths:    make(map[string]map[string]openAPIOperation),
+	}
+	for _, r := range s.Routes() {
+		if strings.HasPrefix(r.Path, debugPathPrefix+"/") {
+			continue
+		}
+		op := openAPIOperation{
+			Responses:  map[string]openAPIResponse{"default": {Description: "See the API reference"}},
+			Deprecated: r.Deprecated,
+		}
+		for _, m := range pathVarPattern.FindAllStringSubmatch(r.Path, -1) {
+			op.Parameters = append(op.Parameters, openAPIParameter{Name: m[1], In: "path", Required: true, Type: "string"})
+		}
+		path := pathVarPattern.ReplaceAllString(r.Path, "{$1}")
+		if spec.Paths[path] == nil {
+			spec.Paths[path] = make(map[string]openAPIOperation)
+		}
+		spec.Paths[path][strings.ToLower(r.Method)] = op
+	}
+	return spec
+}
+
+// getOpenAPI serves Config.OpenAPISpec as-is, or a spec generated from the
+// routes of the server if none is bundled.
+func (s *Server) getOpenAPI(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	spec := s.cfg.OpenAPISpec
+	if len(spec) == 0 {
+		return httputils.WriteJSON(w, http.StatusOK, s.generateOpenAPISpec())
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(spec), []byte("{")) {
+		w.Header().Set("Content-Type", "application/json")
+	} else {
+		w.Header().Set("Content-Type", "application/yaml")
+	}
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write(spec)
+	return err
+}
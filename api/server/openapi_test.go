@@ -0,0 +1,63 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api"
+	"github.com/docker/docker/api/server/router"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestOpenAPI(t *testing.T) {
+	noop := func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		return nil
+	}
+	routes := fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/containers/{name:.*}/json", noop),
+		router.NewPostRoute("/containers/{name:.*}/start", noop),
+		router.NewGetRoute("/containers/{name:.*}/old", noop, router.WithDeprecation(time.Date(2022, time.March, 1, 0, 0, 0, 0, time.UTC), "")),
+	}}
+	get := func(srv *Server) *httptest.ResponseRecorder {
+		srv.InitRouter(routes)
+		resp := httptest.NewRecorder()
+		srv.createMux().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, openAPIPath, nil))
+		return resp
+	}
+
+	resp := get(&Server{cfg: &Config{}})
+	assert.Check(t, is.Equal(resp.Code, http.StatusNotFound))
+
+	resp = get(&Server{cfg: &Config{EnableOpenAPI: true}})
+	assert.Check(t, is.Equal(resp.Code, http.StatusOK))
+	var spec openAPISpec
+	assert.NilError(t, json.NewDecoder(resp.Body).Decode(&spec))
+	assert.Check(t, is.Equal(spec.Swagger, "2.0"))
+	assert.Check(t, is.Equal(spec.BasePath, "/v"+api.DefaultVersion))
+	assert.Check(t, is.Len(spec.Paths, 3))
+	nameParam := []openAPIParameter{{Name: "name", In: "path", Required: true, Type: "string"}}
+	assert.Check(t, is.DeepEqual(spec.Paths["/containers/{name}/json"]["get"].Parameters, nameParam))
+	assert.Check(t, is.DeepEqual(spec.Paths["/containers/{name}/start"]["post"].Parameters, nameParam))
+	assert.Check(t, spec.Paths["/containers/{name}/old"]["get"].Deprecated)
+	for path := range spec.Paths {
+		assert.Check(t, !strings.HasPrefix(path, debugPathPrefix), "unexpected debug route %s", path)
+	}
+
+	bundled := []byte("swagger: \"2.0\"\nbasePath: \"/v1.42\"\n")
+	resp = get(&Server{cfg: &Config{EnableOpenAPI: true, OpenAPISpec: bundled}})
+	assert.Check(t, is.Equal(resp.Code, http.StatusOK))
+	assert.Check(t, is.Equal(resp.Header().Get("Content-Type"), "application/yaml"))
+	assert.Check(t, is.Equal(resp.Body.String(), string(bundled)))
+
+	// the endpoint is subject to the middlewares of API requests
+	srv := &Server{cfg: &Config{EnableOpenAPI: true}}
+	srv.UseMiddleware(denyingMiddleware{})
+	resp = get(srv)
+	assert.Check(t, is.Equal(resp.Code, http.StatusForbidden))
+}
@@ -0,0 +1,55 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type pathTooLongError struct {
+	max int
+}
+
+func (e pathTooLongError) Error() string {
+	return fmt.Sprintf("request path exceeds the maximum length of %d bytes", e.max)
+}
+
+func (pathTooLongError) HTTPStatusCode() int {
+	return http.StatusRequestURITooLong
+}
+
+type tooManyPathSegmentsError struct {
+	max int
+}
+
+func (e tooManyPathSegmentsError) Error() string {
+	return fmt.Sprintf("request path exceeds the maximum of %d segments", e.max)
+}
+
+func (tooManyPathSegmentsError) InvalidParameter() {}
+
+// maxPathLengthHandler returns a handler rejecting the requests whose path
+// is longer than maxLength bytes, before passing the other requests to
+// handler, so that pathological paths are not matched against the routes.
+func (s *Server) maxPathLengthHandler(handler http.Handler, maxLength int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.URL.Path) > maxLength {
+			s.makeErrorHandler(pathTooLongError{max: maxLength})(w, r)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// maxPathSegmentsHandler returns a handler rejecting the requests whose path
+// has more than maxSegments segments, before passing the other requests to
+// handler, so that adversarial paths are not matched against the routes.
+func (s *Server) maxPathSegmentsHandler(handler http.Handler, maxSegments int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.Count(r.URL.Path, "/") > maxSegments {
+			s.makeErrorHandler(tooManyPathSegmentsError{max: maxSegments})(w, r)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,87 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+)
+
+// compilePathVarPattern compiles the pattern of a path variable, which must
+// match the whole value of the variable.
+func compilePathVarPattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + pattern + ")$")
+}
+
+// ValidatePathVarPatterns validates the PathVarPatterns of the
+// configuration.
+func (cfg *Config) ValidatePathVarPatterns() error {
+	for name, pattern := range cfg.PathVarPatterns {
+		if _, err := compilePathVarPattern(pattern); err != nil {
+			return errors.Wrapf(err, "invalid pattern for API path variable %s", name)
+		}
+	}
+	return nil
+}
+
+// pathVarPatterns returns the compiled patterns of Config.PathVarPatterns,
+// which are compiled once. The patterns are checked by Config.Validate
+// before the server starts serving; patterns failing to compile anyway are
+// logged, and ignored.
+func (s *Server) pathVarPatterns() map[string]*regexp.Regexp {
+	s.pathVarsOnce.Do(func() {
+		s.pathVarRegexps = make(map[string]*regexp.Regexp, len(s.cfg.PathVarPatterns))
+		for name, pattern := range s.cfg.PathVarPatterns {
+			re, err := compilePathVarPattern(pattern)
+			if err != nil {
+				log.WithError(err).WithField("var", name).Warn("ignoring invalid pattern for API path variable")
+				continue
+			}
+			s.pathVarRegexps[name] = re
+		}
+	})
+	return s.pathVarRegexps
+}
+
+// normalizePathVars normalizes and validates the variables of the path of a
+// request (such as the name of a container), in place, before the request
+// is handled. The routes are matched against the escaped path, so that
+// slashes encoded in the values of variables (as in "library%2Fubuntu") do
+// not separate path segments: values are decoded, values with slashes are
+// cleaned, so that "library//ubuntu/" becomes "library/ubuntu", and values
+// with ".." segments are rejected. Values must not contain control
+// characters, which may have been encoded in the path (as in "web%00"),
+// and must match the patterns of Config.PathVarPatterns, if any, once
+// normalized.
+func (s *Server) normalizePathVars(vars map[string]string) error {
+	patterns := s.pathVarPatterns()
+	for name, value := range vars {
+		value, err := url.PathUnescape(value)
+		if err != nil {
+			return errdefs.InvalidParameter(errors.Wrapf(err, "invalid %s in request path", name))
+		}
+		if strings.IndexFunc(value, isControlChar) >= 0 {
+			return errdefs.InvalidParameter(errors.Errorf("invalid %s in request path: %q contains control characters", name, value))
+		}
+		for _, segment := range strings.Split(value, "/") {
+			if segment == ".." {
+				return errdefs.InvalidParameter(errors.Errorf("invalid %s in request path: %q contains a \"..\" segment", name, value))
+			}
+		}
+		if strings.Contains(value, "/") {
+			value = path.Clean(value)
+		}
+		vars[name] = value
+		if re, ok := patterns[name]; ok && !re.MatchString(value) {
+			return errdefs.InvalidParameter(errors.Errorf("invalid %s in request path: %q does not match %s", name, value, s.cfg.PathVarPatterns[name]))
+		}
+	}
+	return nil
+}
+
+func isControlChar(r rune) bool {
+	return r < 0x20 || r == 0x7f
+}
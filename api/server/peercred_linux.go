@@ -0,0 +1,30 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"net"
+
+	"github.com/docker/docker/api/server/httputils"
+	"golang.org/x/sys/unix"
+)
+
+// peerCred returns the credentials of the process connected to the unix
+// socket connection c.
+func peerCred(c *net.UnixConn) (httputils.PeerCred, error) {
+	raw, err := c.SyscallConn()
+	if err != nil {
+		return httputils.PeerCred{}, err
+	}
+	var (
+		ucred   *unix.Ucred
+		credErr error
+	)
+	if err := raw.Control(func(fd uintptr) {
+		ucred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	}); err != nil {
+		return httputils.PeerCred{}, err
+	}
+	if credErr != nil {
+		return httputils.PeerCred{}, credErr
+	}
+	return httputils.PeerCred{PID: ucred.Pid, UID: ucred.Uid, GID: ucred.Gid}, nil
+}
@@ -0,0 +1,51 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/server/httputils"
+	"github.com/docker/docker/api/server/router"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestPeerCredentials(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "docker.sock")
+	l, err := net.Listen("unix", sockPath)
+	assert.NilError(t, err)
+
+	creds := make(chan httputils.PeerCred, 1)
+	srv := &Server{cfg: &Config{}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/whoami", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			cred, ok := httputils.PeerCredFromContext(ctx)
+			assert.Check(t, ok, "no peer credentials in the request context")
+			creds <- cred
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}),
+	}})
+	srv.Accept(sockPath, l)
+	go srv.Wait(make(chan error, 1))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", sockPath)
+		},
+	}}
+	resp, err := client.Get("http://docker/whoami")
+	assert.NilError(t, err)
+	resp.Body.Close()
+	assert.Check(t, is.Equal(resp.StatusCode, http.StatusNoContent))
+
+	cred := <-creds
+	assert.Check(t, is.Equal(cred.UID, uint32(os.Getuid())))
+	assert.Check(t, is.Equal(cred.GID, uint32(os.Getgid())))
+	assert.Check(t, is.Equal(cred.PID, int32(os.Getpid())))
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"net"
+
+	"github.com/docker/docker/api/server/httputils"
+	"github.com/pkg/errors"
+)
+
+func peerCred(c *net.UnixConn) (httputils.PeerCred, error) {
+	return httputils.PeerCred{}, errors.New("peer credentials are not supported on this platform")
+}
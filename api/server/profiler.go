@@ -0,0 +1,175 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"runtime/pprof"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/server/httputils"
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+)
+
+// profilerAdminPath is the path of the endpoint to enable and disable the
+// profiler at runtime. It is only available if access to the profiler is
+// restricted, using Config.ProfilerAllowedCNs or Config.ProfilerToken.
+const profilerAdminPath = "/_admin/profiler"
+
+// EnableProfiler enables the debug routes, which include the Go profiler.
+// The profiler is enabled by default.
+func (s *Server) EnableProfiler() {
+	s.mu.Lock()
+	s.profilerDisabled = false
+	s.mu.Unlock()
+}
+
+// DisableProfiler disables the debug routes, which then return a "404 Not
+// Found".
+func (s *Server) DisableProfiler() {
+	s.mu.Lock()
+	s.profilerDisabled = true
+	s.mu.Unlock()
+}
+
+// ProfilerEnabled returns whether the debug routes are enabled.
+func (s *Server) ProfilerEnabled() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return !s.profilerDisabled
+}
+
+// startProfilerWarmup enables the profiler, and disables it after d. If
+// cpuProfile is set, a CPU profile of the warm-up period is written to it.
+func (s *Server) startProfilerWarmup(d time.Duration, cpuProfile string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.profilerDisabled = false
+	if cpuProfile != "" {
+		s.profilerWarmupCPU = startCPUProfile(cpuProfile)
+	}
+	var t *time.Timer
+	t = time.AfterFunc(d, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.profilerWarmup != t {
+			// the warm-up was cancelled while the timer fired
+			return
+		}
+		log.Infof("disabling the profiler at the end of its %v warm-up period", d)
+		s.profilerDisabled = true
+		s.endProfilerWarmupLocked()
+	})
+	s.profilerWarmup = t
+}
+
+// stopProfilerWarmup cancels the warm-up of the profiler, if any, leaving it
+// in its current state. The CPU profile of the warm-up, if any, is completed.
+func (s *Server) stopProfilerWarmup() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.profilerWarmup != nil {
+		s.profilerWarmup.Stop()
+	}
+	s.endProfilerWarmupLocked()
+}
+
+func (s *Server) endProfilerWarmupLocked() {
+	s.profilerWarmup = nil
+	if s.profilerWarmupCPU != nil {
+		pprof.StopCPUProfile()
+		if err := s.profilerWarmupCPU.Close(); err != nil {
+			log.WithError(err).Warn("failed to write the CPU profile of the profiler warm-up")
+		} else {
+			log.Infof("wrote the CPU profile of the profiler warm-up to %s", s.profilerWarmupCPU.Name())
+		}
+		s.profilerWarmupCPU = nil
+	}
+}
+
+// startCPUProfile starts writing a CPU profile to path. Failing to start the
+// profile is logged, and does not prevent the server from starting.
+func startCPUProfile(path string) *os.File {
+	f, err := os.Create(path)
+	if err != nil {
+		log.WithError(err).Warn("failed to create the CPU profile of the profiler warm-up")
+		return nil
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		log.WithError(err).Warn("failed to start the CPU profile of the profiler warm-up")
+		_ = f.Close()
+		_ = os.Remove(path)
+		return nil
+	}
+	return f
+}
+
+// profilerRestricted returns whether access to the profiler is restricted.
+func (s *Server) profilerRestricted() bool {
+	return len(s.cfg.ProfilerAllowedCNs) > 0 || s.cfg.ProfilerToken != ""
+}
+
+// authorizeProfiler checks that the request is allowed to access the
+// profiler: either using a verified client certificate with one of the
+// allowed common names, or using the profiler token as bearer token.
+func (s *Server) authorizeProfiler(r *http.Request) error {
+	if !s.profilerRestricted() {
+		return nil
+	}
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+		for _, allowed := range s.cfg.ProfilerAllowedCNs {
+			if cn == allowed {
+				return nil
+			}
+		}
+	}
+	if s.cfg.ProfilerToken != "" {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.ProfilerToken)) == 1 {
+			return nil
+		}
+	}
+	return errdefs.Forbidden(errors.New("access to the profiler is not allowed"))
+}
+
+// profilerHandler returns a handler that serves handler if the profiler is
+// enabled, and the request is allowed to access it.
+func (s *Server) profilerHandler(handler httputils.APIFunc) httputils.APIFunc {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		if !s.ProfilerEnabled() {
+			return pageNotFoundError{}
+		}
+		if err := s.authorizeProfiler(r); err != nil {
+			return err
+		}
+		return handler(ctx, w, r, vars)
+	}
+}
+
+type profilerStatus struct {
+	Enabled bool
+}
+
+// profilerAdmin returns whether the profiler is enabled and, for POST
+// requests, enables or disables it according to the "enabled" parameter.
+func (s *Server) profilerAdmin(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := s.authorizeProfiler(r); err != nil {
+		return err
+	}
+	if r.Method == http.MethodPost {
+		if err := httputils.ParseForm(r); err != nil {
+			return err
+		}
+		s.stopProfilerWarmup()
+		if httputils.BoolValue(r, "enabled") {
+			s.EnableProfiler()
+		} else {
+			s.DisableProfiler()
+		}
+	}
+	return httputils.WriteJSON(w, http.StatusOK, profilerStatus{Enabled: s.ProfilerEnabled()})
+}
@@ -0,0 +1,112 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+	"gotest.tools/v3/poll"
+)
+
+func TestProfilerAccess(t *testing.T) {
+	srv := &Server{cfg: &Config{ProfilerToken: "secret"}}
+	m := srv.createMux()
+
+	do := func(method, path, token string) int {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp := httptest.NewRecorder()
+		m.ServeHTTP(resp, req)
+		return resp.Code
+	}
+
+	assert.Check(t, is.Equal(do(http.MethodGet, "/debug/vars", ""), http.StatusForbidden))
+	assert.Check(t, is.Equal(do(http.MethodGet, "/debug/vars", "wrong"), http.StatusForbidden))
+	assert.Check(t, is.Equal(do(http.MethodGet, "/debug/vars", "secret"), http.StatusOK))
+
+	assert.Check(t, is.Equal(do(http.MethodPost, "/_admin/profiler?enabled=0", ""), http.StatusForbidden))
+	assert.Check(t, srv.ProfilerEnabled())
+	assert.Check(t, is.Equal(do(http.MethodPost, "/_admin/profiler?enabled=0", "secret"), http.StatusOK))
+	assert.Check(t, !srv.ProfilerEnabled())
+	assert.Check(t, is.Equal(do(http.MethodGet, "/debug/vars", "secret"), http.StatusNotFound))
+	assert.Check(t, is.Len(srv.Routes(), 0))
+
+	assert.Check(t, is.Equal(do(http.MethodPost, "/_admin/profiler?enabled=1", "secret"), http.StatusOK))
+	assert.Check(t, is.Equal(do(http.MethodGet, "/debug/vars", "secret"), http.StatusOK))
+}
+
+func TestProfilerAdminRequiresRestriction(t *testing.T) {
+	srv := &Server{cfg: &Config{}}
+	resp := httptest.NewRecorder()
+	srv.createMux().ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/_admin/profiler?enabled=0", nil))
+	assert.Check(t, is.Equal(resp.Code, http.StatusNotFound))
+	assert.Check(t, srv.ProfilerEnabled())
+}
+
+func TestProfilerWarmup(t *testing.T) {
+	cpuProfile := filepath.Join(t.TempDir(), "warmup.pprof")
+	srv := New(&Config{ProfilerWarmup: 50 * time.Millisecond, ProfilerWarmupCPUProfile: cpuProfile})
+	assert.Check(t, srv.ProfilerEnabled())
+	poll.WaitOn(t, func(poll.LogT) poll.Result {
+		if srv.ProfilerEnabled() {
+			return poll.Continue("waiting for the profiler to be disabled")
+		}
+		return poll.Success()
+	}, poll.WithTimeout(10*time.Second), poll.WithDelay(10*time.Millisecond))
+
+	// the CPU profile is complete once the warm-up ended
+	f, err := os.Open(cpuProfile)
+	assert.NilError(t, err)
+	defer f.Close()
+	_, err = gzip.NewReader(f)
+	assert.Check(t, err)
+
+	// enabling the profiler explicitly cancels the warm-up
+	srv = New(&Config{ProfilerWarmup: 50 * time.Millisecond, ProfilerToken: "secret"})
+	req := httptest.NewRequest(http.MethodPost, "/_admin/profiler?enabled=1", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp := httptest.NewRecorder()
+	srv.createMux().ServeHTTP(resp, req)
+	assert.Check(t, is.Equal(resp.Code, http.StatusOK))
+	srv.mu.RLock()
+	warmup := srv.profilerWarmup
+	srv.mu.RUnlock()
+	assert.Check(t, warmup == nil, "expected the warm-up to be cancelled")
+	assert.Check(t, srv.ProfilerEnabled())
+}
+
+func TestMemStats(t *testing.T) {
+	srv := &Server{cfg: &Config{ProfilerToken: "secret"}}
+	m := srv.createMux()
+
+	get := func(path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp := httptest.NewRecorder()
+		m.ServeHTTP(resp, req)
+		return resp
+	}
+
+	assert.Check(t, is.Equal(get("/debug/memstats", "").Code, http.StatusForbidden))
+
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+	resp := get("/debug/memstats?gc=1", "secret")
+	assert.Check(t, is.Equal(resp.Code, http.StatusOK))
+	var stats runtime.MemStats
+	assert.NilError(t, json.NewDecoder(resp.Body).Decode(&stats))
+	assert.Check(t, stats.NumGC > before.NumGC, "expected a garbage collection to be run")
+	assert.Check(t, stats.HeapAlloc > 0)
+}
@@ -0,0 +1,231 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// proxyHeaderTimeout is the time a client has to send the PROXY protocol
+// header after connecting.
+const proxyHeaderTimeout = 10 * time.Second
+
+// proxyProtoV2Signature is the signature starting PROXY protocol v2 headers.
+var proxyProtoV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// proxyProtoListener decodes the PROXY protocol header (v1 or v2) sent by a
+// load balancer at the start of the connections accepted by a TCP listener,
+// so that the remote address of the connections is the address of the
+// client connecting to the load balancer.
+type proxyProtoListener struct {
+	net.Listener
+}
+
+// withProxyProtocol returns l, decoding the PROXY protocol header of its
+// connections if the PROXY protocol is enabled in cfg and l is a TCP
+// listener.
+func withProxyProtocol(l net.Listener, cfg *Config) net.Listener {
+	if !cfg.TrustedProxyProtocol {
+		return l
+	}
+	switch l.(type) {
+	case *net.TCPListener, *tcpKeepAliveListener:
+		return &proxyProtoListener{Listener: l}
+	default:
+		return l
+	}
+}
+
+// Accept returns the next connection of the listener. The header of the
+// connection is read on its first use, so that a slow client does not
+// block accepting other connections.
+func (l *proxyProtoListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &proxyConn{Conn: c, r: bufio.NewReader(c)}, nil
+}
+
+// proxyConn is a connection starting with a PROXY protocol header.
+type proxyConn struct {
+	net.Conn
+	r *bufio.Reader
+
+	once       sync.Once
+	err        error
+	remoteAddr net.Addr
+	localAddr  net.Addr
+}
+
+func (c *proxyConn) readHeader() {
+	_ = c.Conn.SetReadDeadline(time.Now().Add(proxyHeaderTimeout))
+	c.remoteAddr, c.localAddr, c.err = readProxyHeader(c.r)
+	_ = c.Conn.SetReadDeadline(time.Time{})
+	if c.err != nil {
+		// Close the connection, so that no response is sent to clients that
+		// do not connect through the proxy.
+		c.err = errors.Wrapf(c.err, "invalid PROXY protocol header from %s", c.Conn.RemoteAddr())
+		log.WithError(c.err).Debug("closing connection")
+		_ = c.Conn.Close()
+	}
+}
+
+func (c *proxyConn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.r.Read(b)
+}
+
+// RemoteAddr returns the address of the client, as sent in the PROXY
+// protocol header, or the address of the proxy if the header does not
+// contain it.
+func (c *proxyConn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.remoteAddr != nil {
+		return c.remoteAddr
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// peerAddr returns the address of the peer of the connection, which is
+// the proxy, without reading the PROXY protocol header.
+func (c *proxyConn) peerAddr() net.Addr {
+	return c.Conn.RemoteAddr()
+}
+
+// LocalAddr returns the address the client connected to, as sent in the
+// PROXY protocol header, or the local address of the connection if the
+// header does not contain it.
+func (c *proxyConn) LocalAddr() net.Addr {
+	c.once.Do(c.readHeader)
+	if c.localAddr != nil {
+		return c.localAddr
+	}
+	return c.Conn.LocalAddr()
+}
+
+// readProxyHeader reads a PROXY protocol header from r, returning the
+// source and destination addresses it contains, which are nil for local
+// connections (such as health checks of the proxy) and unknown protocols.
+func readProxyHeader(r *bufio.Reader) (src, dst net.Addr, err error) {
+	sig, err := r.Peek(len(proxyProtoV2Signature))
+	switch {
+	case bytes.Equal(sig, proxyProtoV2Signature):
+		return readProxyHeaderV2(r)
+	case bytes.HasPrefix(sig, []byte("PROXY ")):
+		return readProxyHeaderV1(r)
+	case err != nil:
+		return nil, nil, err
+	default:
+		return nil, nil, errors.New("missing header")
+	}
+}
+
+// readProxyHeaderV1 reads a header of the human-readable version of the
+// protocol, such as "PROXY TCP4 192.0.2.1 198.51.100.1 56324 2376\r\n".
+func readProxyHeaderV1(r *bufio.Reader) (src, dst net.Addr, err error) {
+	// The header is at most 107 bytes long, including the CRLF.
+	var line []byte
+	for len(line) < 107 {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, nil, err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, nil, errors.New("header too long")
+	}
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	if len(fields) < 2 || fields[0] != "PROXY" {
+		return nil, nil, errors.New("missing header")
+	}
+	switch fields[1] {
+	case "UNKNOWN":
+		return nil, nil, nil
+	case "TCP4", "TCP6":
+	default:
+		return nil, nil, errors.Errorf("unsupported protocol %q", fields[1])
+	}
+	if len(fields) != 6 {
+		return nil, nil, errors.New("malformed header")
+	}
+	srcAddr, err := parseProxyAddr(fields[2], fields[4])
+	if err != nil {
+		return nil, nil, err
+	}
+	dstAddr, err := parseProxyAddr(fields[3], fields[5])
+	if err != nil {
+		return nil, nil, err
+	}
+	return srcAddr, dstAddr, nil
+}
+
+func parseProxyAddr(ip, port string) (*net.TCPAddr, error) {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return nil, errors.Errorf("invalid address %q", ip)
+	}
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return nil, errors.Errorf("invalid port %q", port)
+	}
+	return &net.TCPAddr{IP: addr, Port: int(p)}, nil
+}
+
+// readProxyHeaderV2 reads a header of the binary version of the protocol.
+func readProxyHeaderV2(r *bufio.Reader) (src, dst net.Addr, err error) {
+	header := make([]byte, len(proxyProtoV2Signature)+4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, nil, err
+	}
+	verCmd, family := header[12], header[13]
+	length := binary.BigEndian.Uint16(header[14:])
+	if verCmd>>4 != 2 {
+		return nil, nil, errors.Errorf("unsupported version %d", verCmd>>4)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, nil, err
+	}
+
+	switch verCmd & 0xf {
+	case 0x0: // LOCAL
+		return nil, nil, nil
+	case 0x1: // PROXY
+	default:
+		return nil, nil, errors.Errorf("unsupported command %d", verCmd&0xf)
+	}
+
+	var ipLen int
+	switch family {
+	case 0x11: // TCP over IPv4
+		ipLen = net.IPv4len
+	case 0x21: // TCP over IPv6
+		ipLen = net.IPv6len
+	default:
+		return nil, nil, nil
+	}
+	if len(payload) < 2*ipLen+4 {
+		return nil, nil, errors.New("malformed header")
+	}
+	srcIP := net.IP(payload[:ipLen])
+	dstIP := net.IP(payload[ipLen : 2*ipLen])
+	srcPort := binary.BigEndian.Uint16(payload[2*ipLen:])
+	dstPort := binary.BigEndian.Uint16(payload[2*ipLen+2:])
+	return &net.TCPAddr{IP: srcIP, Port: int(srcPort)}, &net.TCPAddr{IP: dstIP, Port: int(dstPort)}, nil
+}
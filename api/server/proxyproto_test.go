@@ -0,0 +1,137 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/server/router"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestReadProxyHeader(t *testing.T) {
+	v2 := func(cmd, family byte, addrs ...byte) string {
+		return string(proxyProtoV2Signature) + string([]byte{0x20 | cmd, family, 0, byte(len(addrs))}) + string(addrs)
+	}
+	for _, tc := range []struct {
+		name     string
+		header   string
+		src, dst string
+		err      string
+	}{
+		{
+			name:   "v1 TCP4",
+			header: "PROXY TCP4 192.0.2.1 198.51.100.1 56324 2376\r\n",
+			src:    "192.0.2.1:56324",
+			dst:    "198.51.100.1:2376",
+		},
+		{
+			name:   "v1 TCP6",
+			header: "PROXY TCP6 2001:db8::1 2001:db8::2 56324 2376\r\n",
+			src:    "[2001:db8::1]:56324",
+			dst:    "[2001:db8::2]:2376",
+		},
+		{
+			name:   "v1 UNKNOWN",
+			header: "PROXY UNKNOWN\r\n",
+		},
+		{
+			name:   "v1 invalid address",
+			header: "PROXY TCP4 192.0.2 198.51.100.1 56324 2376\r\n",
+			err:    `invalid address "192.0.2"`,
+		},
+		{
+			name:   "v1 too long",
+			header: "PROXY TCP4 " + strings.Repeat("1", 100) + "\r\n",
+			err:    "header too long",
+		},
+		{
+			name:   "v2 TCP4",
+			header: v2(0x1, 0x11, 192, 0, 2, 1, 198, 51, 100, 1, 0xdc, 0x04, 0x09, 0x48),
+			src:    "192.0.2.1:56324",
+			dst:    "198.51.100.1:2376",
+		},
+		{
+			name:   "v2 LOCAL",
+			header: v2(0x0, 0x00),
+		},
+		{
+			name:   "v2 truncated",
+			header: v2(0x1, 0x11, 192, 0, 2, 1),
+			err:    "malformed header",
+		},
+		{
+			name:   "missing",
+			header: "GET /_ping HTTP/1.1\r\n",
+			err:    "missing header",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(tc.header + "GET"))
+			src, dst, err := readProxyHeader(r)
+			if tc.err != "" {
+				assert.Check(t, is.ErrorContains(err, tc.err))
+				return
+			}
+			assert.NilError(t, err)
+			if tc.src == "" {
+				assert.Check(t, src == nil, "unexpected source address %v", src)
+				assert.Check(t, dst == nil, "unexpected destination address %v", dst)
+			} else {
+				assert.Check(t, is.Equal(src.String(), tc.src))
+				assert.Check(t, is.Equal(dst.String(), tc.dst))
+			}
+			rest, err := io.ReadAll(r)
+			assert.NilError(t, err)
+			assert.Check(t, is.Equal(string(rest), "GET"))
+		})
+	}
+}
+
+func TestProxyProtocol(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+
+	srv := &Server{cfg: &Config{TrustedProxyProtocol: true}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/remote", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			_, err := io.WriteString(w, r.RemoteAddr)
+			return err
+		}),
+	}})
+	srv.Accept(l.Addr().String(), l)
+	waitChan := make(chan error, 1)
+	go srv.Wait(waitChan)
+	<-srv.Ready()
+	defer func() {
+		assert.Check(t, srv.Shutdown(context.Background()))
+		assert.Check(t, <-waitChan)
+	}()
+
+	request := func(header string) (string, error) {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		assert.NilError(t, err)
+		defer conn.Close()
+		_, err = io.WriteString(conn, header+"GET /v1.41/remote HTTP/1.1\r\nHost: docker\r\nConnection: close\r\n\r\n")
+		assert.NilError(t, err)
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		return string(body), err
+	}
+
+	remote, err := request("PROXY TCP4 192.0.2.1 198.51.100.1 56324 2376\r\n")
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(remote, "192.0.2.1:56324"))
+
+	_, err = request("")
+	assert.Check(t, err != nil, "expected connections without PROXY protocol header to be closed")
+}
@@ -0,0 +1,86 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/stack"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// panicDumpInterval is the minimum interval between the goroutine
+	// stacks written to Config.PanicDumpDir, and maxPanicDumps the maximum
+	// number of files written to it, so that handlers panicking repeatedly
+	// do not fill the disk.
+	panicDumpInterval = time.Minute
+	maxPanicDumps     = 10
+)
+
+// panicDumpLimiter limits the goroutine stacks written when handlers panic.
+type panicDumpLimiter struct {
+	mu    sync.Mutex
+	count int
+	last  time.Time
+}
+
+// allow returns whether the goroutine stacks can be written at now, and
+// records that they are if so.
+func (l *panicDumpLimiter) allow(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.count >= maxPanicDumps || (!l.last.IsZero() && now.Sub(l.last) < panicDumpInterval) {
+		return false
+	}
+	l.count++
+	l.last = now
+	return true
+}
+
+// recoverHandler recovers from a panic in the handling of the request r,
+// logs it with its stack trace, writes the stacks of all goroutines to
+// Config.PanicDumpDir if set (at most once per panicDumpInterval, and up to
+// maxPanicDumps times), and returns a "500 Internal Server Error" to
+// the client. It must be deferred by the request handler.
+//
+// If the handler already started writing the response, a 500 can no longer
+// be sent: the handling of the request is aborted with http.ErrAbortHandler
+// instead, so that the client gets a truncated response, rather than a
+// response that looks complete. Nothing is written to hijacked connections.
+//
+// Like the net/http server, it does not recover from http.ErrAbortHandler,
+// which is used to abort the handling of a request.
+func (s *Server) recoverHandler(w *hookStatusWriter, r *http.Request, requestID string) {
+	p := recover()
+	if p == nil {
+		return
+	}
+	if p == http.ErrAbortHandler {
+		panic(p)
+	}
+	log.WithFields(logrus.Fields{
+		"request-id": requestID,
+		"panic":      p,
+		"stack":      string(debug.Stack()),
+	}).Errorf("Handler for %s %s panicked", r.Method, r.URL.Path)
+	if s.cfg.PanicDumpDir != "" && s.panicDumps.allow(time.Now()) {
+		if path, err := stack.DumpToFile(s.cfg.PanicDumpDir); err != nil {
+			log.WithError(err).Error("failed to write the goroutine stacks of a panicking handler")
+		} else {
+			log.WithField("request-id", requestID).Errorf("goroutine stacks written to %s", path)
+		}
+	}
+	switch w.status {
+	case 0:
+		s.makeErrorHandler(errdefs.System(errors.New("internal server error")))(w, r)
+	case http.StatusSwitchingProtocols:
+		// the connection was hijacked by the handler
+	default:
+		log.WithField("request-id", requestID).Debug("aborting the response of the panicking handler, which was already written")
+		panic(http.ErrAbortHandler)
+	}
+}
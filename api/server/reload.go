@@ -0,0 +1,180 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// ReloadableConfig holds the settings of the server that can be changed
+// while it is serving, using Server.Reload.
+type ReloadableConfig struct {
+	CorsHeaders       string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	RequestTimeout    time.Duration
+}
+
+// Reload applies rc to the server. The routes of the server are rebuilt
+// with the new CORS headers and request timeout. If the connection timeouts
+// changed, the server of each listener is replaced by a server with the new
+// timeouts, which serves new connections on the same listener, while the
+// active connections complete using the old timeouts, for up to
+// reloadShutdownTimeout, after which they are closed.
+func (s *Server) Reload(rc ReloadableConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	timeoutsChanged := rc.ReadTimeout != s.cfg.ReadTimeout ||
+		rc.ReadHeaderTimeout != s.cfg.ReadHeaderTimeout ||
+		rc.WriteTimeout != s.cfg.WriteTimeout ||
+		rc.IdleTimeout != s.cfg.IdleTimeout
+
+	s.cfg.CorsHeaders = rc.CorsHeaders
+	s.cfg.RequestTimeout = rc.RequestTimeout
+	s.cfg.ReadTimeout = rc.ReadTimeout
+	s.cfg.ReadHeaderTimeout = rc.ReadHeaderTimeout
+	s.cfg.WriteTimeout = rc.WriteTimeout
+	s.cfg.IdleTimeout = rc.IdleTimeout
+
+	if s.handler != nil {
+		s.handler.Swap(s.createMux())
+	}
+	if !s.serving {
+		// not serving yet; the servers can be updated in place.
+		for _, srv := range s.servers {
+			srv.srv.ReadTimeout = rc.ReadTimeout
+			srv.srv.ReadHeaderTimeout = rc.ReadHeaderTimeout
+			srv.srv.WriteTimeout = rc.WriteTimeout
+			srv.srv.IdleTimeout = rc.IdleTimeout
+		}
+		return
+	}
+	if !timeoutsChanged {
+		return
+	}
+	old := s.servers
+	s.servers = make([]*HTTPServer, 0, len(old))
+	for _, o := range old {
+		srv := s.newHTTPServer(o.srv.Addr, o.baseTLSConfig, o.l.shared.ref())
+		s.servers = append(s.servers, srv)
+		s.serve(srv, nil)
+	}
+	go shutdownReplacedServers(old, reloadShutdownTimeout)
+}
+
+// reloadShutdownTimeout is the time given to the active connections of the
+// servers replaced by Reload to complete.
+const reloadShutdownTimeout = time.Minute
+
+// shutdownReplacedServers gracefully shuts down servers, closing the
+// connections that are still active after timeout, so that long-running
+// requests (such as following logs or events) do not keep the replaced
+// servers, and their old timeouts, around indefinitely. Clients of these
+// requests need to reconnect, and get the new timeouts.
+func shutdownReplacedServers(servers []*HTTPServer, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := shutdownServers(ctx, servers); err != nil {
+		log.WithError(err).Warn("failed to gracefully shut down the API servers replaced when reloading the configuration; closing their remaining connections")
+		for _, srv := range servers {
+			_ = srv.srv.Close()
+		}
+	}
+}
+
+// sharedListener is a listener that can be served by multiple servers,
+// each using its own reference to the listener. A single goroutine accepts
+// the connections of the listener, and hands each of them to one of the
+// references that are accepting. The listener is closed once all of its
+// references are closed.
+type sharedListener struct {
+	net.Listener
+	conns  chan acceptResult
+	closed chan struct{}
+	start  sync.Once
+
+	mu   sync.Mutex
+	refs int
+}
+
+type acceptResult struct {
+	conn net.Conn
+	err  error
+}
+
+func newSharedListener(l net.Listener) *sharedListener {
+	return &sharedListener{
+		Listener: l,
+		conns:    make(chan acceptResult),
+		closed:   make(chan struct{}),
+	}
+}
+
+// ref returns a new reference to l.
+func (l *sharedListener) ref() *listenerRef {
+	l.mu.Lock()
+	l.refs++
+	l.mu.Unlock()
+	return &listenerRef{Listener: l.Listener, shared: l, done: make(chan struct{})}
+}
+
+func (l *sharedListener) release() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refs--
+	if l.refs > 0 {
+		return nil
+	}
+	close(l.closed)
+	return l.Listener.Close()
+}
+
+func (l *sharedListener) acceptLoop() {
+	for {
+		conn, err := l.Listener.Accept()
+		select {
+		case l.conns <- acceptResult{conn: conn, err: err}:
+		case <-l.closed:
+			if conn != nil {
+				_ = conn.Close()
+			}
+			return
+		}
+	}
+}
+
+// listenerRef is a reference to a sharedListener. Closing it releases the
+// reference, closing the sharedListener if it was the last one.
+type listenerRef struct {
+	net.Listener
+	shared *sharedListener
+	done   chan struct{}
+	once   sync.Once
+	err    error
+}
+
+// Accept waits for the next connection of the shared listener, or returns
+// net.ErrClosed once r is closed.
+func (r *listenerRef) Accept() (net.Conn, error) {
+	r.shared.start.Do(func() {
+		go r.shared.acceptLoop()
+	})
+	select {
+	case res := <-r.shared.conns:
+		return res.conn, res.err
+	case <-r.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (r *listenerRef) Close() error {
+	r.once.Do(func() {
+		close(r.done)
+		r.err = r.shared.release()
+	})
+	return r.err
+}
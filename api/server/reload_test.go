@@ -0,0 +1,122 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/server/router"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestReload(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+
+	srv := &Server{cfg: &Config{ReadHeaderTimeout: time.Minute}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/ping", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}),
+	}})
+	srv.Accept(l.Addr().String(), l)
+	waitChan := make(chan error, 1)
+	go srv.Wait(waitChan)
+	<-srv.Ready()
+
+	// Keep-alive connections are closed when the server they were accepted
+	// by is replaced, so use a new connection for each request.
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	preflight := func() *http.Response {
+		req, err := http.NewRequest(http.MethodOptions, "http://"+l.Addr().String()+"/ping", nil)
+		assert.NilError(t, err)
+		req.Header.Set("Origin", "https://dashboard.example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		resp, err := client.Do(req)
+		assert.NilError(t, err)
+		_ = resp.Body.Close()
+		return resp
+	}
+	assert.Check(t, is.Equal(preflight().Header.Get("Access-Control-Allow-Origin"), ""))
+
+	srv.Reload(ReloadableConfig{
+		CorsHeaders:       "https://dashboard.example.com",
+		ReadHeaderTimeout: 10 * time.Second,
+	})
+
+	srv.mu.RLock()
+	assert.Assert(t, is.Len(srv.servers, 1))
+	assert.Check(t, is.Equal(srv.servers[0].srv.ReadHeaderTimeout, 10*time.Second))
+	srv.mu.RUnlock()
+
+	resp := preflight()
+	assert.Check(t, is.Equal(resp.StatusCode, http.StatusNoContent))
+	assert.Check(t, is.Equal(resp.Header.Get("Access-Control-Allow-Origin"), "https://dashboard.example.com"))
+
+	assert.Check(t, srv.Shutdown(context.Background()))
+	assert.Check(t, <-waitChan)
+}
+
+func TestShutdownReplacedServers(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+
+	started := make(chan struct{})
+	srv := &Server{cfg: &Config{}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/events", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			close(started)
+			<-ctx.Done()
+			return nil
+		}),
+	}})
+	srv.Accept(l.Addr().String(), l)
+	go srv.Wait(make(chan error, 1))
+	<-srv.Ready()
+
+	resp, err := http.Get("http://" + l.Addr().String() + "/events")
+	assert.NilError(t, err)
+	defer resp.Body.Close()
+	<-started
+
+	srv.mu.RLock()
+	servers := srv.servers
+	srv.mu.RUnlock()
+	done := make(chan struct{})
+	go func() {
+		shutdownReplacedServers(servers, 10*time.Millisecond)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("shutting down the replaced servers waited for the active request")
+	}
+	_, err = io.ReadAll(resp.Body)
+	assert.Check(t, err != nil, "expected the connection of the active request to be closed")
+}
+
+func TestSharedListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+
+	shared := newSharedListener(l)
+	ref1, ref2 := shared.ref(), shared.ref()
+
+	assert.NilError(t, ref1.Close())
+	assert.NilError(t, ref1.Close())
+	conn, err := net.Dial("tcp", l.Addr().String())
+	assert.NilError(t, err, "listener closed while it is still referenced")
+	_ = conn.Close()
+
+	assert.NilError(t, ref2.Close())
+	_, err = l.Accept()
+	assert.Check(t, err != nil, "expected listener to be closed")
+}
@@ -0,0 +1,102 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/server/httputils"
+	"github.com/docker/docker/api/server/middleware"
+	"github.com/docker/docker/api/server/router"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+// TestResponseWriterInterfaces verifies that the ResponseWriter passed to
+// handlers through the middlewares and route options wrapping it still
+// implements http.Flusher and http.Hijacker, so that streaming and hijacking
+// endpoints keep working.
+func TestResponseWriterInterfaces(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+
+	srv := &Server{cfg: &Config{
+		RequestTimeout:    time.Minute,
+		IdempotencyKeyTTL: time.Minute,
+		HijackIdleTimeout: time.Minute,
+		DebugBodyLogging:  true,
+	}}
+	srv.UseMiddleware(middleware.NewCompressionMiddleware(middleware.DefaultCompressionMinSize))
+	srv.UseMiddleware(middleware.NewMetricsMiddleware())
+	srv.UseMiddleware(middleware.NewAccessLogMiddleware(middleware.AccessLogFormatText))
+	srv.UseMiddleware(middleware.NewResponseHeadersMiddleware(map[string]string{"X-Test": "true"}))
+
+	release := make(chan struct{})
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/events", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			f, ok := w.(http.Flusher)
+			if !ok {
+				t.Errorf("ResponseWriter %T does not implement http.Flusher", w)
+				return nil
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(w, "{\"status\":\"start\"}\n")
+			f.Flush()
+			<-release
+			return nil
+		}),
+		router.NewPostRoute("/hijack", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			if _, ok := w.(http.Hijacker); !ok {
+				t.Errorf("ResponseWriter %T does not implement http.Hijacker", w)
+				return nil
+			}
+			in, out, err := httputils.HijackConnection(w)
+			if err != nil {
+				return err
+			}
+			defer httputils.CloseStreams(in, out)
+			_, _ = io.WriteString(out, "HTTP/1.1 200 OK\r\nContent-Type: application/vnd.docker.raw-stream\r\nConnection: close\r\n\r\nhijacked")
+			return nil
+		}, router.WithIdempotency()),
+	}})
+	srv.Accept(l.Addr().String(), l)
+	waitChan := make(chan error, 1)
+	go srv.Wait(waitChan)
+	<-srv.Ready()
+	defer func() {
+		assert.Check(t, srv.Shutdown(context.Background()))
+		assert.Check(t, <-waitChan)
+	}()
+
+	// the start of the stream is received before the handler returns
+	resp, err := http.Get("http://" + l.Addr().String() + "/v1.41/events")
+	assert.NilError(t, err)
+	defer resp.Body.Close()
+	line := make(chan string, 1)
+	go func() {
+		s, _ := bufio.NewReader(resp.Body).ReadString('\n')
+		line <- s
+	}()
+	select {
+	case s := <-line:
+		assert.Check(t, is.Equal(s, "{\"status\":\"start\"}\n"))
+	case <-time.After(10 * time.Second):
+		t.Error("timeout waiting for the flushed response")
+	}
+	close(release)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	assert.NilError(t, err)
+	defer conn.Close()
+	_, err = io.WriteString(conn, "POST /v1.41/hijack HTTP/1.1\r\nHost: docker\r\nIdempotency-Key: key\r\nContent-Length: 0\r\n\r\n")
+	assert.NilError(t, err)
+	_ = conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	raw, err := io.ReadAll(conn)
+	assert.NilError(t, err)
+	assert.Check(t, strings.HasSuffix(string(raw), "\r\n\r\nhijacked"), "unexpected response %q", raw)
+}
@@ -31,8 +31,8 @@ func (r *buildRouter) Routes() []router.Route {
 
 func (r *buildRouter) initRoutes() {
 	r.routes = []router.Route{
-		router.NewPostRoute("/build", r.postBuild),
-		router.NewPostRoute("/build/prune", r.postPrune),
+		router.NewPostRoute("/build", r.postBuild, router.WithMaxBodyBytes(router.UnlimitedBodyBytes), router.WithStreamingBody(), router.WithTimeout(router.NoTimeout)),
+		router.NewPostRoute("/build/prune", r.postPrune, router.WithTimeout(router.NoTimeout)),
 		router.NewPostRoute("/build/cancel", r.postCancel),
 	}
 }
@@ -30,7 +30,7 @@ func (r *checkpointRouter) Routes() []router.Route {
 func (r *checkpointRouter) initRoutes() {
 	r.routes = []router.Route{
 		router.NewGetRoute("/containers/{name:.*}/checkpoints", r.getContainerCheckpoints, router.Experimental),
-		router.NewPostRoute("/containers/{name:.*}/checkpoints", r.postContainerCheckpoint, router.Experimental),
+		router.NewPostRoute("/containers/{name:.*}/checkpoints", r.postContainerCheckpoint, router.Experimental, router.WithTimeout(router.NoTimeout), router.WithJSONBody()),
 		router.NewDeleteRoute("/containers/{name}/checkpoints/{checkpoint}", r.deleteContainerCheckpoint, router.Experimental),
 	}
 }
@@ -35,38 +35,39 @@ func (r *containerRouter) initRoutes() {
 		// HEAD
 		router.NewHeadRoute("/containers/{name:.*}/archive", r.headContainersArchive),
 		// GET
-		router.NewGetRoute("/containers/json", r.getContainersJSON),
-		router.NewGetRoute("/containers/{name:.*}/export", r.getContainersExport),
+		router.NewGetRoute("/containers/json", r.getContainersJSON, router.WithBufferedResponse(), router.WithETag()),
+		router.NewGetRoute("/containers/{name:.*}/export", r.getContainersExport, router.WithTimeout(router.NoTimeout)),
 		router.NewGetRoute("/containers/{name:.*}/changes", r.getContainersChanges),
-		router.NewGetRoute("/containers/{name:.*}/json", r.getContainersByName),
+		router.NewGetRoute("/containers/{name:.*}/json", r.getContainersByName, router.WithBufferedResponse(), router.WithETag()),
 		router.NewGetRoute("/containers/{name:.*}/top", r.getContainersTop),
-		router.NewGetRoute("/containers/{name:.*}/logs", r.getContainersLogs),
-		router.NewGetRoute("/containers/{name:.*}/stats", r.getContainersStats),
-		router.NewGetRoute("/containers/{name:.*}/attach/ws", r.wsContainersAttach),
+		router.NewGetRoute("/containers/{name:.*}/logs", r.getContainersLogs, router.WithTimeout(router.NoTimeout)),
+		router.NewGetRoute("/containers/{name:.*}/stats", r.getContainersStats, router.WithTimeout(router.NoTimeout)),
+		router.NewGetRoute("/containers/{name:.*}/attach/ws", r.wsContainersAttach, router.WithTimeout(router.NoTimeout)),
 		router.NewGetRoute("/exec/{id:.*}/json", r.getExecByID),
-		router.NewGetRoute("/containers/{name:.*}/archive", r.getContainersArchive),
+		router.NewGetRoute("/exec/{name:.*}/start/ws", r.wsContainerExecStart, router.WithTimeout(router.NoTimeout), router.WithAliasOf(execStartOf)),
+		router.NewGetRoute("/containers/{name:.*}/archive", r.getContainersArchive, router.WithTimeout(router.NoTimeout)),
 		// POST
-		router.NewPostRoute("/containers/create", r.postContainersCreate),
+		router.NewPostRoute("/containers/create", r.postContainersCreate, router.WithIdempotency(), router.WithJSONBody()),
 		router.NewPostRoute("/containers/{name:.*}/kill", r.postContainersKill),
 		router.NewPostRoute("/containers/{name:.*}/pause", r.postContainersPause),
 		router.NewPostRoute("/containers/{name:.*}/unpause", r.postContainersUnpause),
-		router.NewPostRoute("/containers/{name:.*}/restart", r.postContainersRestart),
-		router.NewPostRoute("/containers/{name:.*}/start", r.postContainersStart),
-		router.NewPostRoute("/containers/{name:.*}/stop", r.postContainersStop),
-		router.NewPostRoute("/containers/{name:.*}/wait", r.postContainersWait),
+		router.NewPostRoute("/containers/{name:.*}/restart", r.postContainersRestart, router.WithTimeout(router.NoTimeout)),
+		router.NewPostRoute("/containers/{name:.*}/start", r.postContainersStart, router.WithTimeout(router.NoTimeout)),
+		router.NewPostRoute("/containers/{name:.*}/stop", r.postContainersStop, router.WithTimeout(router.NoTimeout)),
+		router.NewPostRoute("/containers/{name:.*}/wait", r.postContainersWait, router.WithTimeout(router.NoTimeout)),
 		router.NewPostRoute("/containers/{name:.*}/resize", r.postContainersResize),
-		router.NewPostRoute("/containers/{name:.*}/attach", r.postContainersAttach),
-		router.NewPostRoute("/containers/{name:.*}/copy", r.postContainersCopy), // Deprecated since 1.8 (API v1.20), errors out since 1.12 (API v1.24)
-		router.NewPostRoute("/containers/{name:.*}/exec", r.postContainerExecCreate),
-		router.NewPostRoute("/exec/{name:.*}/start", r.postContainerExecStart),
+		router.NewPostRoute("/containers/{name:.*}/attach", r.postContainersAttach, router.WithTimeout(router.NoTimeout)),
+		router.NewPostRoute("/containers/{name:.*}/copy", r.postContainersCopy, router.WithJSONBody()), // Deprecated since 1.8 (API v1.20), errors out since 1.12 (API v1.24)
+		router.NewPostRoute("/containers/{name:.*}/exec", r.postContainerExecCreate, router.WithIdempotency(), router.WithJSONBody()),
+		router.NewPostRoute("/exec/{name:.*}/start", r.postContainerExecStart, router.WithTimeout(router.NoTimeout), router.WithJSONBody()),
 		router.NewPostRoute("/exec/{name:.*}/resize", r.postContainerExecResize),
 		router.NewPostRoute("/containers/{name:.*}/rename", r.postContainerRename),
-		router.NewPostRoute("/containers/{name:.*}/update", r.postContainerUpdate),
-		router.NewPostRoute("/containers/prune", r.postContainersPrune),
-		router.NewPostRoute("/commit", r.postCommit),
+		router.NewPostRoute("/containers/{name:.*}/update", r.postContainerUpdate, router.WithJSONBody()),
+		router.NewPostRoute("/containers/prune", r.postContainersPrune, router.WithTimeout(router.NoTimeout)),
+		router.NewPostRoute("/commit", r.postCommit, router.WithTimeout(router.NoTimeout), router.WithJSONBody()),
 		// PUT
-		router.NewPutRoute("/containers/{name:.*}/archive", r.putContainersArchive),
+		router.NewPutRoute("/containers/{name:.*}/archive", r.putContainersArchive, router.WithMaxBodyBytes(router.UnlimitedBodyBytes), router.WithStreamingBody(), router.WithTimeout(router.NoTimeout)),
 		// DELETE
-		router.NewDeleteRoute("/containers/{name:.*}", r.deleteContainers),
+		router.NewDeleteRoute("/containers/{name:.*}", r.deleteContainers, router.WithTimeout(router.NoTimeout)),
 	}
 }
@@ -17,14 +17,12 @@ import (
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/mount"
-	"github.com/docker/docker/api/types/versions"
 	containerpkg "github.com/docker/docker/container"
 	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/pkg/ioutils"
 	specs "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
-	"golang.org/x/net/websocket"
 )
 
 func (s *containerRouter) postCommit(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
@@ -39,7 +37,7 @@ func (s *containerRouter) postCommit(ctx context.Context, w http.ResponseWriter,
 	// TODO: remove pause arg, and always pause in backend
 	pause := httputils.BoolValue(r, "pause")
 	version := httputils.VersionFromContext(ctx)
-	if r.FormValue("pause") == "" && versions.GreaterThanOrEqualTo(version, "1.13") {
+	if r.FormValue("pause") == "" && version.GreaterThanOrEqualTo("1.13") {
 		pause = true
 	}
 
@@ -109,7 +107,7 @@ func (s *containerRouter) getContainersStats(ctx context.Context, w http.Respons
 		w.Header().Set("Content-Type", "application/json")
 	}
 	var oneShot bool
-	if versions.GreaterThanOrEqualTo(httputils.VersionFromContext(ctx), "1.41") {
+	if httputils.VersionFromContext(ctx).GreaterThanOrEqualTo("1.41") {
 		oneShot = httputils.BoolValueOrDefault(r, "one-shot", false)
 	}
 
@@ -117,7 +115,7 @@ func (s *containerRouter) getContainersStats(ctx context.Context, w http.Respons
 		Stream:    stream,
 		OneShot:   oneShot,
 		OutStream: w,
-		Version:   httputils.VersionFromContext(ctx),
+		Version:   httputils.VersionFromContext(ctx).String(),
 	}
 
 	return s.backend.ContainerStats(ctx, vars["name"], config)
@@ -156,7 +154,7 @@ func (s *containerRouter) getContainersLogs(ctx context.Context, w http.Response
 	}
 
 	contentType := types.MediaTypeRawStream
-	if !tty && versions.GreaterThanOrEqualTo(httputils.VersionFromContext(ctx), "1.42") {
+	if !tty && httputils.VersionFromContext(ctx).GreaterThanOrEqualTo("1.42") {
 		contentType = types.MediaTypeMultiplexedStream
 	}
 	w.Header().Set("Content-Type", contentType)
@@ -193,7 +191,7 @@ func (s *containerRouter) postContainersStart(ctx context.Context, w http.Respon
 	var hostConfig *container.HostConfig
 	// A non-nil json object is at least 7 characters.
 	if r.ContentLength > 7 || r.ContentLength == -1 {
-		if versions.GreaterThanOrEqualTo(version, "1.24") {
+		if version.GreaterThanOrEqualTo("1.24") {
 			return bodyOnStartError{}
 		}
 
@@ -231,7 +229,7 @@ func (s *containerRouter) postContainersStop(ctx context.Context, w http.Respons
 		options container.StopOptions
 		version = httputils.VersionFromContext(ctx)
 	)
-	if versions.GreaterThanOrEqualTo(version, "1.42") {
+	if version.GreaterThanOrEqualTo("1.42") {
 		options.Signal = r.Form.Get("signal")
 	}
 	if tmpSeconds := r.Form.Get("t"); tmpSeconds != "" {
@@ -266,7 +264,7 @@ func (s *containerRouter) postContainersKill(ctx context.Context, w http.Respons
 		// Return error if the container is not running and the api is >= 1.20
 		// to keep backwards compatibility.
 		version := httputils.VersionFromContext(ctx)
-		if versions.GreaterThanOrEqualTo(version, "1.20") || !isStopped {
+		if version.GreaterThanOrEqualTo("1.20") || !isStopped {
 			return errors.Wrapf(err, "Cannot kill container: %s", name)
 		}
 	}
@@ -284,7 +282,7 @@ func (s *containerRouter) postContainersRestart(ctx context.Context, w http.Resp
 		options container.StopOptions
 		version = httputils.VersionFromContext(ctx)
 	)
-	if versions.GreaterThanOrEqualTo(version, "1.42") {
+	if version.GreaterThanOrEqualTo("1.42") {
 		options.Signal = r.Form.Get("signal")
 	}
 	if tmpSeconds := r.Form.Get("t"); tmpSeconds != "" {
@@ -335,7 +333,7 @@ func (s *containerRouter) postContainersWait(ctx context.Context, w http.Respons
 	// Behavior changed in version 1.30 to handle wait condition and to
 	// return headers immediately.
 	version := httputils.VersionFromContext(ctx)
-	legacyBehaviorPre130 := versions.LessThan(version, "1.30")
+	legacyBehaviorPre130 := version.LessThan("1.30")
 	legacyRemovalWaitPre134 := false
 
 	// The wait condition defaults to "not-running".
@@ -352,7 +350,7 @@ func (s *containerRouter) postContainersWait(ctx context.Context, w http.Respons
 				waitCondition = containerpkg.WaitConditionNextExit
 			case container.WaitConditionRemoved:
 				waitCondition = containerpkg.WaitConditionRemoved
-				legacyRemovalWaitPre134 = versions.LessThan(version, "1.34")
+				legacyRemovalWaitPre134 = version.LessThan("1.34")
 			default:
 				return errdefs.InvalidParameter(errors.Errorf("invalid condition: %q", v))
 			}
@@ -441,11 +439,11 @@ func (s *containerRouter) postContainerUpdate(ctx context.Context, w http.Respon
 	if err := httputils.ReadJSON(r, &updateConfig); err != nil {
 		return err
 	}
-	if versions.LessThan(httputils.VersionFromContext(ctx), "1.40") {
+	if httputils.VersionFromContext(ctx).LessThan("1.40") {
 		updateConfig.PidsLimit = nil
 	}
 
-	if versions.GreaterThanOrEqualTo(httputils.VersionFromContext(ctx), "1.42") {
+	if httputils.VersionFromContext(ctx).GreaterThanOrEqualTo("1.42") {
 		// Ignore KernelMemory removed in API 1.42.
 		updateConfig.KernelMemory = 0
 	}
@@ -487,14 +485,14 @@ func (s *containerRouter) postContainersCreate(ctx context.Context, w http.Respo
 		return err
 	}
 	version := httputils.VersionFromContext(ctx)
-	adjustCPUShares := versions.LessThan(version, "1.19")
+	adjustCPUShares := version.LessThan("1.19")
 
 	// When using API 1.24 and under, the client is responsible for removing the container
-	if hostConfig != nil && versions.LessThan(version, "1.25") {
+	if hostConfig != nil && version.LessThan("1.25") {
 		hostConfig.AutoRemove = false
 	}
 
-	if hostConfig != nil && versions.LessThan(version, "1.40") {
+	if hostConfig != nil && version.LessThan("1.40") {
 		// Ignore BindOptions.NonRecursive because it was added in API 1.40.
 		for _, m := range hostConfig.Mounts {
 			if bo := m.BindOptions; bo != nil {
@@ -509,14 +507,14 @@ func (s *containerRouter) postContainersCreate(ctx context.Context, w http.Respo
 			hostConfig.IpcMode = container.IPCModeShareable
 		}
 	}
-	if hostConfig != nil && versions.LessThan(version, "1.41") && !s.cgroup2 {
+	if hostConfig != nil && version.LessThan("1.41") && !s.cgroup2 {
 		// Older clients expect the default to be "host" on cgroup v1 hosts
 		if hostConfig.CgroupnsMode.IsEmpty() {
 			hostConfig.CgroupnsMode = container.CgroupnsModeHost
 		}
 	}
 
-	if hostConfig != nil && versions.LessThan(version, "1.42") {
+	if hostConfig != nil && version.LessThan("1.42") {
 		for _, m := range hostConfig.Mounts {
 			// Ignore BindOptions.CreateMountpoint because it was added in API 1.42.
 			if bo := m.BindOptions; bo != nil {
@@ -538,7 +536,7 @@ func (s *containerRouter) postContainersCreate(ctx context.Context, w http.Respo
 		}
 	}
 
-	if hostConfig != nil && versions.GreaterThanOrEqualTo(version, "1.42") {
+	if hostConfig != nil && version.GreaterThanOrEqualTo("1.42") {
 		// Ignore KernelMemory removed in API 1.42.
 		hostConfig.KernelMemory = 0
 		for _, m := range hostConfig.Mounts {
@@ -554,13 +552,13 @@ func (s *containerRouter) postContainersCreate(ctx context.Context, w http.Respo
 		}
 	}
 
-	if hostConfig != nil && runtime.GOOS == "linux" && versions.LessThan(version, "1.42") {
+	if hostConfig != nil && runtime.GOOS == "linux" && version.LessThan("1.42") {
 		// ConsoleSize is not respected by Linux daemon before API 1.42
 		hostConfig.ConsoleSize = [2]uint{0, 0}
 	}
 
 	var platform *specs.Platform
-	if versions.GreaterThanOrEqualTo(version, "1.41") {
+	if version.GreaterThanOrEqualTo("1.41") {
 		if v := r.Form.Get("platform"); v != "" {
 			p, err := platforms.Parse(v)
 			if err != nil {
@@ -657,7 +655,7 @@ func (s *containerRouter) postContainersAttach(ctx context.Context, w http.Respo
 		conn.Write([]byte{})
 
 		if upgrade {
-			if multiplexed && versions.GreaterThanOrEqualTo(httputils.VersionFromContext(ctx), "1.42") {
+			if multiplexed && httputils.VersionFromContext(ctx).GreaterThanOrEqualTo("1.42") {
 				contentType = types.MediaTypeMultiplexedStream
 			}
 			fmt.Fprintf(conn, "HTTP/1.1 101 UPGRADED\r\nContent-Type: "+contentType+"\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
@@ -708,35 +706,26 @@ func (s *containerRouter) wsContainersAttach(ctx context.Context, w http.Respons
 	var err error
 	detachKeys := r.FormValue("detachKeys")
 
-	done := make(chan struct{})
-	started := make(chan struct{})
-
+	var (
+		upgraded bool
+		release  func()
+	)
 	version := httputils.VersionFromContext(ctx)
 
 	setupStreams := func(multiplexed bool) (io.ReadCloser, io.Writer, io.Writer, error) {
-		wsChan := make(chan *websocket.Conn)
-		h := func(conn *websocket.Conn) {
-			wsChan <- conn
-			<-done
-		}
-
-		srv := websocket.Server{Handler: h, Handshake: nil}
-		go func() {
-			close(started)
-			srv.ServeHTTP(w, r)
-		}()
-
-		conn := <-wsChan
+		upgraded = true
 		// In case version 1.28 and above, a binary frame will be sent.
 		// See 28176 for details.
-		if versions.GreaterThanOrEqualTo(version, "1.28") {
-			conn.PayloadType = websocket.BinaryFrame
+		conn, rel, err := httputils.UpgradeWebSocket(w, r, version.GreaterThanOrEqualTo("1.28"))
+		if err != nil {
+			return nil, nil, nil, err
 		}
+		release = rel
 		return conn, conn, conn, nil
 	}
 
 	useStdin, useStdout, useStderr := true, true, true
-	if versions.GreaterThanOrEqualTo(version, "1.42") {
+	if version.GreaterThanOrEqualTo("1.42") {
 		useStdin = httputils.BoolValue(r, "stdin")
 		useStdout = httputils.BoolValue(r, "stdout")
 		useStderr = httputils.BoolValue(r, "stderr")
@@ -754,16 +743,16 @@ func (s *containerRouter) wsContainersAttach(ctx context.Context, w http.Respons
 	}
 
 	err = s.backend.ContainerAttach(containerName, attachConfig)
-	close(done)
-	select {
-	case <-started:
+	if release != nil {
+		release()
+	}
+	if upgraded {
 		if err != nil {
 			logrus.Errorf("Error attaching websocket: %s", err)
 		} else {
 			logrus.Debug("websocket connection was closed by client")
 		}
 		return nil
-	default:
 	}
 	return err
 }
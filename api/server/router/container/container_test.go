@@ -0,0 +1,32 @@
+package container // import "github.com/docker/docker/api/server/router/container"
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/server/router"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestLongRunningRoutesHaveNoTimeout(t *testing.T) {
+	timeouts := make(map[string]bool)
+	for _, r := range NewRouter(nil, nil, false).Routes() {
+		timeouts[r.Method()+" "+r.Path()] = router.OptionsOf(r).Timeout == router.NoTimeout
+	}
+	// these routes wait for containers to stop, or for the timeout set by
+	// the client, which can exceed the default request timeout.
+	for _, route := range []string{
+		"POST /containers/{name:.*}/start",
+		"POST /containers/{name:.*}/stop",
+		"POST /containers/{name:.*}/restart",
+		"POST /containers/{name:.*}/wait",
+		"POST /containers/prune",
+		"POST /commit",
+		"DELETE /containers/{name:.*}",
+	} {
+		noTimeout, ok := timeouts[route]
+		assert.Check(t, ok, route)
+		assert.Check(t, noTimeout, route)
+	}
+	assert.Check(t, is.Equal(timeouts["GET /containers/{name:.*}/json"], false))
+}
@@ -6,13 +6,14 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/docker/docker/api/server/httputils"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/api/types/versions"
 	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 )
 
@@ -48,7 +49,7 @@ func (s *containerRouter) postContainerExecCreate(ctx context.Context, w http.Re
 	}
 
 	version := httputils.VersionFromContext(ctx)
-	if versions.LessThan(version, "1.42") {
+	if version.LessThan("1.42") {
 		// Not supported by API versions before 1.42
 		execConfig.ConsoleSize = nil
 	}
@@ -72,7 +73,7 @@ func (s *containerRouter) postContainerExecStart(ctx context.Context, w http.Res
 	}
 
 	version := httputils.VersionFromContext(ctx)
-	if versions.LessThan(version, "1.22") {
+	if version.LessThan("1.22") {
 		// API versions before 1.22 did not enforce application/json content-type.
 		// Allow older clients to work by patching the content-type.
 		if r.Header.Get("Content-Type") != "application/json" {
@@ -97,7 +98,7 @@ func (s *containerRouter) postContainerExecStart(ctx context.Context, w http.Res
 
 	if execStartCheck.ConsoleSize != nil {
 		// Not supported before 1.42
-		if versions.LessThan(version, "1.42") {
+		if version.LessThan("1.42") {
 			execStartCheck.ConsoleSize = nil
 		}
 
@@ -118,7 +119,7 @@ func (s *containerRouter) postContainerExecStart(ctx context.Context, w http.Res
 
 		if _, ok := r.Header["Upgrade"]; ok {
 			contentType := types.MediaTypeRawStream
-			if !execStartCheck.Tty && versions.GreaterThanOrEqualTo(httputils.VersionFromContext(ctx), "1.42") {
+			if !execStartCheck.Tty && httputils.VersionFromContext(ctx).GreaterThanOrEqualTo("1.42") {
 				contentType = types.MediaTypeMultiplexedStream
 			}
 			fmt.Fprint(outStream, "HTTP/1.1 101 UPGRADED\r\nContent-Type: "+contentType+"\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n")
@@ -159,6 +160,49 @@ func (s *containerRouter) postContainerExecStart(ctx context.Context, w http.Res
 	return nil
 }
 
+// wsContainerExecStart starts an exec instance, streaming its standard
+// streams over a WebSocket connection, for clients (such as browser-based
+// terminals) that cannot use the hijacked connection of
+// postContainerExecStart. The streams are never multiplexed.
+func (s *containerRouter) wsContainerExecStart(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	execName := vars["name"]
+	if exists, err := s.backend.ExecExists(execName); !exists {
+		return err
+	}
+	if !httputils.IsWebSocketUpgrade(r) {
+		return errdefs.InvalidParameter(errors.New("expected a websocket upgrade request"))
+	}
+
+	conn, release, err := httputils.UpgradeWebSocket(w, r, true)
+	if err != nil {
+		logrus.WithError(err).Debugf("Error starting exec %s over websocket", execName)
+		return nil
+	}
+	defer release()
+
+	options := container.ExecStartOptions{
+		Stdin:  conn,
+		Stdout: conn,
+		Stderr: conn,
+	}
+	if err := s.backend.ContainerExecStart(context.Background(), execName, options); err != nil {
+		_, _ = conn.Write([]byte(err.Error() + "\r\n"))
+		logrus.Errorf("Error running exec %s in container: %v", execName, err)
+	}
+	return nil
+}
+
+// execStartOf maps the requests to wsContainerExecStart to the equivalent
+// request to postContainerExecStart, so that authorization plugins cannot be
+// bypassed by starting an exec instance over a WebSocket connection.
+func execStartOf(r *http.Request) (method, requestURI string) {
+	requestURI = strings.TrimSuffix(r.URL.EscapedPath(), "/ws")
+	if r.URL.RawQuery != "" {
+		requestURI += "?" + r.URL.RawQuery
+	}
+	return http.MethodPost, requestURI
+}
+
 func (s *containerRouter) postContainerExecResize(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	if err := httputils.ParseForm(r); err != nil {
 		return err
@@ -0,0 +1,31 @@
+package container // import "github.com/docker/docker/api/server/router/container"
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/docker/docker/api/server/router"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestWebSocketExecStartAuthorization(t *testing.T) {
+	var ws router.Route
+	for _, r := range NewRouter(nil, nil, false).Routes() {
+		if r.Path() == "/exec/{name:.*}/start/ws" {
+			ws = r
+		}
+	}
+	assert.Assert(t, ws != nil)
+	aliasOf := router.OptionsOf(ws).AliasOf
+	assert.Assert(t, aliasOf != nil, "WebSocket exec start must be authorized as POST /exec/{name}/start")
+
+	method, requestURI := aliasOf(httptest.NewRequest(http.MethodGet, "/v1.42/exec/abc/start/ws?stream=1", nil))
+	assert.Check(t, is.Equal(method, http.MethodPost))
+	assert.Check(t, is.Equal(requestURI, "/v1.42/exec/abc/start?stream=1"))
+
+	method, requestURI = aliasOf(httptest.NewRequest(http.MethodGet, "/exec/abc/start/ws", nil))
+	assert.Check(t, is.Equal(method, http.MethodPost))
+	assert.Check(t, is.Equal(requestURI, "/exec/abc/start"))
+}
@@ -31,6 +31,7 @@ func (r *debugRouter) initRoutes() {
 		router.NewGetRoute("/pprof/symbol", frameworkAdaptHandlerFunc(pprof.Symbol)),
 		router.NewGetRoute("/pprof/trace", frameworkAdaptHandlerFunc(pprof.Trace)),
 		router.NewGetRoute("/pprof/{name}", handlePprof),
+		router.NewGetRoute("/memstats", getMemStats),
 	}
 }
 
@@ -4,9 +4,28 @@ import (
 	"context"
 	"net/http"
 	"net/http/pprof"
+	"runtime"
+
+	"github.com/docker/docker/api/server/httputils"
 )
 
 func handlePprof(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
 	pprof.Handler(vars["name"]).ServeHTTP(w, r)
 	return nil
 }
+
+// getMemStats returns the memory statistics of the daemon. Like the heap
+// profile, a garbage collection is run first if the "gc" parameter is set,
+// so that the statistics can be compared with and without garbage, to tell
+// whether memory is used by live data.
+func getMemStats(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	if err := httputils.ParseForm(r); err != nil {
+		return err
+	}
+	if httputils.BoolValue(r, "gc") {
+		runtime.GC()
+	}
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return httputils.WriteJSON(w, http.StatusOK, &stats)
+}
@@ -26,6 +26,6 @@ func (r *distributionRouter) Routes() []router.Route {
 func (r *distributionRouter) initRoutes() {
 	r.routes = []router.Route{
 		// GET
-		router.NewGetRoute("/distribution/{name:.*}/json", r.getDistributionInfo),
+		router.NewGetRoute("/distribution/{name:.*}/json", r.getDistributionInfo, router.WithUpstreamTimeout(router.RegistryTimeout)),
 	}
 }
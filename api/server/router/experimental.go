@@ -59,6 +59,13 @@ func (r *experimentalRoute) Path() string {
 	return r.local.Path()
 }
 
+func (r *experimentalRoute) options() *RouteOptions {
+	if o, ok := r.local.(optionsRoute); ok {
+		return o.options()
+	}
+	return &RouteOptions{}
+}
+
 // Experimental will mark a route as experimental.
 func Experimental(r Route) Route {
 	return &experimentalRoute{
@@ -36,6 +36,6 @@ func (gr *grpcRouter) Routes() []router.Route {
 
 func (gr *grpcRouter) initRoutes() {
 	gr.routes = []router.Route{
-		router.NewPostRoute("/grpc", gr.serveGRPC),
+		router.NewPostRoute("/grpc", gr.serveGRPC, router.WithTimeout(router.NoTimeout)),
 	}
 }
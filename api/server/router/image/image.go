@@ -37,18 +37,18 @@ func (r *imageRouter) Routes() []router.Route {
 func (r *imageRouter) initRoutes() {
 	r.routes = []router.Route{
 		// GET
-		router.NewGetRoute("/images/json", r.getImagesJSON),
-		router.NewGetRoute("/images/search", r.getImagesSearch),
-		router.NewGetRoute("/images/get", r.getImagesGet),
-		router.NewGetRoute("/images/{name:.*}/get", r.getImagesGet),
+		router.NewGetRoute("/images/json", r.getImagesJSON, router.WithBufferedResponse(), router.WithETag()),
+		router.NewGetRoute("/images/search", r.getImagesSearch, router.WithUpstreamTimeout(router.RegistryTimeout)),
+		router.NewGetRoute("/images/get", r.getImagesGet, router.WithTimeout(router.NoTimeout)),
+		router.NewGetRoute("/images/{name:.*}/get", r.getImagesGet, router.WithTimeout(router.NoTimeout)),
 		router.NewGetRoute("/images/{name:.*}/history", r.getImagesHistory),
-		router.NewGetRoute("/images/{name:.*}/json", r.getImagesByName),
+		router.NewGetRoute("/images/{name:.*}/json", r.getImagesByName, router.WithBufferedResponse(), router.WithETag()),
 		// POST
-		router.NewPostRoute("/images/load", r.postImagesLoad),
-		router.NewPostRoute("/images/create", r.postImagesCreate),
-		router.NewPostRoute("/images/{name:.*}/push", r.postImagesPush),
+		router.NewPostRoute("/images/load", r.postImagesLoad, router.WithMaxBodyBytes(router.UnlimitedBodyBytes), router.WithStreamingBody(), router.WithTimeout(router.NoTimeout)),
+		router.NewPostRoute("/images/create", r.postImagesCreate, router.WithMaxBodyBytes(router.UnlimitedBodyBytes), router.WithStreamingBody(), router.WithTimeout(router.NoTimeout)),
+		router.NewPostRoute("/images/{name:.*}/push", r.postImagesPush, router.WithTimeout(router.NoTimeout)),
 		router.NewPostRoute("/images/{name:.*}/tag", r.postImagesTag),
-		router.NewPostRoute("/images/prune", r.postImagesPrune),
+		router.NewPostRoute("/images/prune", r.postImagesPrune, router.WithTimeout(router.NoTimeout)),
 		// DELETE
 		router.NewDeleteRoute("/images/{name:.*}", r.deleteImages),
 	}
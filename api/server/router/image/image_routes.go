@@ -14,7 +14,6 @@ import (
 	"github.com/docker/docker/api/server/httputils"
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/filters"
-	"github.com/docker/docker/api/types/versions"
 	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/image"
 	"github.com/docker/docker/layer"
@@ -45,7 +44,7 @@ func (s *imageRouter) postImagesCreate(ctx context.Context, w http.ResponseWrite
 	w.Header().Set("Content-Type", "application/json")
 
 	version := httputils.VersionFromContext(ctx)
-	if versions.GreaterThanOrEqualTo(version, "1.32") {
+	if version.GreaterThanOrEqualTo("1.32") {
 		if p := r.FormValue("platform"); p != "" {
 			sp, err := platforms.Parse(p)
 			if err != nil {
@@ -307,7 +306,7 @@ func (s *imageRouter) getImagesJSON(ctx context.Context, w http.ResponseWriter,
 	}
 
 	version := httputils.VersionFromContext(ctx)
-	if versions.LessThan(version, "1.41") {
+	if version.LessThan("1.41") {
 		// NOTE: filter is a shell glob string applied to repository names.
 		filterParam := r.Form.Get("filter")
 		if filterParam != "" {
@@ -316,7 +315,7 @@ func (s *imageRouter) getImagesJSON(ctx context.Context, w http.ResponseWriter,
 	}
 
 	var sharedSize bool
-	if versions.GreaterThanOrEqualTo(version, "1.42") {
+	if version.GreaterThanOrEqualTo("1.42") {
 		// NOTE: Support for the "shared-size" parameter was added in API 1.42.
 		sharedSize = httputils.BoolValue(r, "shared-size")
 	}
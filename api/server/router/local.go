@@ -16,26 +16,31 @@ type localRoute struct {
 	method  string
 	path    string
 	handler httputils.APIFunc
+	opts    RouteOptions
 }
 
 // Handler returns the APIFunc to let the server wrap it in middlewares.
-func (l localRoute) Handler() httputils.APIFunc {
+func (l *localRoute) Handler() httputils.APIFunc {
 	return l.handler
 }
 
 // Method returns the http method that the route responds to.
-func (l localRoute) Method() string {
+func (l *localRoute) Method() string {
 	return l.method
 }
 
 // Path returns the subpath where the route responds to.
-func (l localRoute) Path() string {
+func (l *localRoute) Path() string {
 	return l.path
 }
 
+func (l *localRoute) options() *RouteOptions {
+	return &l.opts
+}
+
 // NewRoute initializes a new local route for the router.
 func NewRoute(method, path string, handler httputils.APIFunc, opts ...RouteWrapper) Route {
-	var r Route = localRoute{method, path, handler}
+	var r Route = &localRoute{method: method, path: path, handler: handler}
 	for _, o := range opts {
 		r = o(r)
 	}
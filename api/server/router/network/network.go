@@ -29,14 +29,14 @@ func (r *networkRouter) Routes() []router.Route {
 func (r *networkRouter) initRoutes() {
 	r.routes = []router.Route{
 		// GET
-		router.NewGetRoute("/networks", r.getNetworksList),
+		router.NewGetRoute("/networks", r.getNetworksList, router.WithBufferedResponse()),
 		router.NewGetRoute("/networks/", r.getNetworksList),
 		router.NewGetRoute("/networks/{id:.+}", r.getNetwork),
 		// POST
-		router.NewPostRoute("/networks/create", r.postNetworkCreate),
-		router.NewPostRoute("/networks/{id:.*}/connect", r.postNetworkConnect),
-		router.NewPostRoute("/networks/{id:.*}/disconnect", r.postNetworkDisconnect),
-		router.NewPostRoute("/networks/prune", r.postNetworksPrune),
+		router.NewPostRoute("/networks/create", r.postNetworkCreate, router.WithIdempotency(), router.WithJSONBody()),
+		router.NewPostRoute("/networks/{id:.*}/connect", r.postNetworkConnect, router.WithJSONBody()),
+		router.NewPostRoute("/networks/{id:.*}/disconnect", r.postNetworkDisconnect, router.WithJSONBody()),
+		router.NewPostRoute("/networks/prune", r.postNetworksPrune, router.WithTimeout(router.NoTimeout)),
 		// DELETE
 		router.NewDeleteRoute("/networks/{id:.*}", r.deleteNetwork),
 	}
@@ -0,0 +1,248 @@
+package router // import "github.com/docker/docker/api/server/router"
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// UnlimitedBodyBytes can be passed to WithMaxBodyBytes to exempt a route
+// from the server's default request body size limit.
+const UnlimitedBodyBytes = -1
+
+// NoTimeout can be passed to WithTimeout to exempt a route from the server's
+// default request timeout, for example, for streaming endpoints.
+const NoTimeout time.Duration = -1
+
+// RegistryTimeout is the upstream timeout of routes that query a registry,
+// for use with WithUpstreamTimeout.
+const RegistryTimeout = time.Minute
+
+// RouteOptions holds optional settings of a route, which are applied by the
+// server when the route is registered.
+type RouteOptions struct {
+	// MaxBodyBytes is the maximum size (in bytes) of the request body that
+	// is accepted by the route. A zero value uses the server's default, and
+	// UnlimitedBodyBytes disables the limit.
+	MaxBodyBytes int64
+
+	// Timeout is the maximum duration of requests to the route. A zero
+	// value uses the server's default, and NoTimeout disables the timeout.
+	Timeout time.Duration
+
+	// UpstreamTimeout is the maximum duration of requests to the route
+	// waiting for a dependency of the daemon, such as containerd or a
+	// registry, after which they fail with a "504 Gateway Timeout" status.
+	// Unlike Timeout, it is not meant to bound the duration of requests for
+	// clients, but to surface hung dependencies. A zero value means no
+	// timeout.
+	UpstreamTimeout time.Duration
+
+	// StreamingBody marks routes that accept large streamed request bodies
+	// (such as build contexts and image tarballs), which are read at the
+	// rate limited by the server's configuration.
+	StreamingBody bool
+
+	// DecompressBody marks routes whose request bodies sent with a
+	// "Content-Encoding: gzip" header are decompressed by the server, so
+	// that the handler reads the decompressed body.
+	DecompressBody bool
+
+	// JSONBody marks routes expecting a JSON request body, whose requests
+	// with a body of another Content-Type are rejected with a "415
+	// Unsupported Media Type" status before reaching the handler.
+	JSONBody bool
+
+	// Idempotent marks routes creating resources (such as containers) whose
+	// requests can carry an "Idempotency-Key" header, so that the response
+	// of a request that is retried with the same key is replayed, instead of
+	// creating a duplicate resource, if the server is configured to.
+	Idempotent bool
+
+	// BufferResponse marks non-streaming routes whose response is buffered
+	// (up to a few megabytes), so that the server can send an error
+	// response with the correct status if the handler fails after it
+	// started writing the response, instead of a truncated response.
+	BufferResponse bool
+
+	// ETag marks inspect-style routes whose successful responses carry an
+	// ETag header computed from their body, so that clients can send an
+	// If-None-Match header to get a "304 Not Modified" response, without
+	// a body, if the response is unchanged.
+	ETag bool
+
+	// Deprecated marks routes that are phased out, whose responses carry a
+	// "Deprecation" header with the DeprecatedSince date, and a "Link"
+	// header to the Replacement of the route, if any, so that clients are
+	// notified without breaking them. Replacement is the path template (or
+	// URL) of the endpoint replacing the route.
+	Deprecated      bool
+	DeprecatedSince time.Time
+	Replacement     string
+
+	// Authorize, if set, is called for each request to the route, after
+	// the server's global middlewares, but before the route's handler.
+	Authorize AuthorizeFunc
+
+	// ValidateBody, if set, is called with the request body before the
+	// route's handler, so that invalid requests are rejected before they
+	// are partially processed.
+	ValidateBody BodyValidatorFunc
+
+	// AliasOf, if set, marks the route as an alias of another route, and
+	// returns the method and URI of the request to that route that a
+	// request to this route is equivalent to. Authorization plugins
+	// authorize the requests to the route as that request, so that their
+	// policies for the other route also apply to the alias.
+	AliasOf AliasFunc
+}
+
+// AliasFunc returns the method and request URI of the request that r is an
+// alias of.
+type AliasFunc func(r *http.Request) (method, requestURI string)
+
+// AuthorizeFunc authorizes a request to the route with the given path
+// template and variables. Returning an error rejects the request with a
+// "403 Forbidden" status.
+type AuthorizeFunc func(ctx context.Context, route string, vars map[string]string) error
+
+// BodyValidatorFunc validates the body of a request to the route, such as
+// against the schema of the route for the API version in ctx. Returning an
+// error rejects the request with a "400 Bad Request" status, unless the
+// error maps to another status, such as an errdefs.NotFound.
+type BodyValidatorFunc func(ctx context.Context, body []byte) error
+
+// optionsRoute is implemented by routes that carry RouteOptions.
+type optionsRoute interface {
+	Route
+	options() *RouteOptions
+}
+
+// OptionsOf returns the RouteOptions of the given route.
+func OptionsOf(r Route) RouteOptions {
+	if o, ok := r.(optionsRoute); ok {
+		return *o.options()
+	}
+	return RouteOptions{}
+}
+
+// withOptions returns a RouteWrapper that updates the options of the route
+// using fn. Routes that do not carry options are returned unmodified.
+func withOptions(fn func(*RouteOptions)) RouteWrapper {
+	return func(r Route) Route {
+		if o, ok := r.(optionsRoute); ok {
+			fn(o.options())
+		}
+		return r
+	}
+}
+
+// WithMaxBodyBytes sets the maximum size of the request body that is
+// accepted by the route.
+func WithMaxBodyBytes(n int64) RouteWrapper {
+	return withOptions(func(o *RouteOptions) {
+		o.MaxBodyBytes = n
+	})
+}
+
+// WithTimeout sets the maximum duration of requests to the route.
+func WithTimeout(d time.Duration) RouteWrapper {
+	return withOptions(func(o *RouteOptions) {
+		o.Timeout = d
+	})
+}
+
+// WithUpstreamTimeout sets the maximum duration of requests to the route
+// waiting for a dependency of the daemon, such as a registry.
+func WithUpstreamTimeout(timeout time.Duration) RouteWrapper {
+	return withOptions(func(o *RouteOptions) {
+		o.UpstreamTimeout = timeout
+	})
+}
+
+// WithStreamingBody marks the route as accepting large streamed request
+// bodies, which are read at a limited rate if the server is configured to.
+func WithStreamingBody() RouteWrapper {
+	return withOptions(func(o *RouteOptions) {
+		o.StreamingBody = true
+	})
+}
+
+// WithDecompressBody marks the route as accepting request bodies compressed
+// with gzip, which are decompressed before reaching the handler.
+func WithDecompressBody() RouteWrapper {
+	return withOptions(func(o *RouteOptions) {
+		o.DecompressBody = true
+	})
+}
+
+// WithJSONBody marks the route as expecting a JSON request body, rejecting
+// requests with a body of another Content-Type.
+func WithJSONBody() RouteWrapper {
+	return withOptions(func(o *RouteOptions) {
+		o.JSONBody = true
+	})
+}
+
+// WithIdempotency marks the route as replaying the response of requests
+// that are retried with the same "Idempotency-Key" header.
+func WithIdempotency() RouteWrapper {
+	return withOptions(func(o *RouteOptions) {
+		o.Idempotent = true
+	})
+}
+
+// WithBufferedResponse marks the route as buffering its response, so that
+// late failures of its handler are reported with their error status.
+func WithBufferedResponse() RouteWrapper {
+	return withOptions(func(o *RouteOptions) {
+		o.BufferResponse = true
+	})
+}
+
+// WithETag marks the route as supporting conditional requests, using the
+// ETag and If-None-Match headers.
+func WithETag() RouteWrapper {
+	return withOptions(func(o *RouteOptions) {
+		o.ETag = true
+	})
+}
+
+// WithDeprecation marks the route as deprecated since the given date, in
+// favor of the endpoint with the given path template (such as
+// "/containers/{name:.*}/json"), which can be empty if the route has no
+// replacement. The variables of the template are expanded with the values
+// of the variables of the same name in the path of each request.
+func WithDeprecation(since time.Time, replacement string) RouteWrapper {
+	return withOptions(func(o *RouteOptions) {
+		o.Deprecated = true
+		o.DeprecatedSince = since
+		o.Replacement = replacement
+	})
+}
+
+// WithAuthorization sets a function that authorizes each request to the
+// route, in addition to any authorization plugins used by the server.
+func WithAuthorization(fn AuthorizeFunc) RouteWrapper {
+	return withOptions(func(o *RouteOptions) {
+		o.Authorize = fn
+	})
+}
+
+// WithAliasOf marks the route as an alias of the route that fn maps its
+// requests to, so that authorization plugins authorize them as requests to
+// that route.
+func WithAliasOf(fn AliasFunc) RouteWrapper {
+	return withOptions(func(o *RouteOptions) {
+		o.AliasOf = fn
+	})
+}
+
+// WithBodyValidator sets a function that validates the body of each request
+// to the route before the route's handler. The body is read in memory, so
+// this is not meant for routes accepting large streamed bodies.
+func WithBodyValidator(fn BodyValidatorFunc) RouteWrapper {
+	return withOptions(func(o *RouteOptions) {
+		o.ValidateBody = fn
+	})
+}
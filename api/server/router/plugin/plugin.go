@@ -28,12 +28,12 @@ func (r *pluginRouter) initRoutes() {
 		router.NewGetRoute("/plugins/{name:.*}/json", r.inspectPlugin),
 		router.NewGetRoute("/plugins/privileges", r.getPrivileges),
 		router.NewDeleteRoute("/plugins/{name:.*}", r.removePlugin),
-		router.NewPostRoute("/plugins/{name:.*}/enable", r.enablePlugin),
-		router.NewPostRoute("/plugins/{name:.*}/disable", r.disablePlugin),
-		router.NewPostRoute("/plugins/pull", r.pullPlugin),
-		router.NewPostRoute("/plugins/{name:.*}/push", r.pushPlugin),
-		router.NewPostRoute("/plugins/{name:.*}/upgrade", r.upgradePlugin),
-		router.NewPostRoute("/plugins/{name:.*}/set", r.setPlugin),
-		router.NewPostRoute("/plugins/create", r.createPlugin),
+		router.NewPostRoute("/plugins/{name:.*}/enable", r.enablePlugin, router.WithTimeout(router.NoTimeout)),
+		router.NewPostRoute("/plugins/{name:.*}/disable", r.disablePlugin, router.WithTimeout(router.NoTimeout)),
+		router.NewPostRoute("/plugins/pull", r.pullPlugin, router.WithTimeout(router.NoTimeout), router.WithJSONBody()),
+		router.NewPostRoute("/plugins/{name:.*}/push", r.pushPlugin, router.WithTimeout(router.NoTimeout)),
+		router.NewPostRoute("/plugins/{name:.*}/upgrade", r.upgradePlugin, router.WithTimeout(router.NoTimeout), router.WithJSONBody()),
+		router.NewPostRoute("/plugins/{name:.*}/set", r.setPlugin, router.WithJSONBody()),
+		router.NewPostRoute("/plugins/create", r.createPlugin, router.WithMaxBodyBytes(router.UnlimitedBodyBytes), router.WithStreamingBody(), router.WithTimeout(router.NoTimeout)),
 	}
 }
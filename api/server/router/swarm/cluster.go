@@ -24,40 +24,40 @@ func (sr *swarmRouter) Routes() []router.Route {
 
 func (sr *swarmRouter) initRoutes() {
 	sr.routes = []router.Route{
-		router.NewPostRoute("/swarm/init", sr.initCluster),
-		router.NewPostRoute("/swarm/join", sr.joinCluster),
-		router.NewPostRoute("/swarm/leave", sr.leaveCluster),
+		router.NewPostRoute("/swarm/init", sr.initCluster, router.WithTimeout(router.NoTimeout), router.WithJSONBody()),
+		router.NewPostRoute("/swarm/join", sr.joinCluster, router.WithTimeout(router.NoTimeout), router.WithJSONBody()),
+		router.NewPostRoute("/swarm/leave", sr.leaveCluster, router.WithTimeout(router.NoTimeout)),
 		router.NewGetRoute("/swarm", sr.inspectCluster),
 		router.NewGetRoute("/swarm/unlockkey", sr.getUnlockKey),
-		router.NewPostRoute("/swarm/update", sr.updateCluster),
-		router.NewPostRoute("/swarm/unlock", sr.unlockCluster),
+		router.NewPostRoute("/swarm/update", sr.updateCluster, router.WithJSONBody()),
+		router.NewPostRoute("/swarm/unlock", sr.unlockCluster, router.WithJSONBody()),
 
 		router.NewGetRoute("/services", sr.getServices),
 		router.NewGetRoute("/services/{id}", sr.getService),
-		router.NewPostRoute("/services/create", sr.createService),
-		router.NewPostRoute("/services/{id}/update", sr.updateService),
+		router.NewPostRoute("/services/create", sr.createService, router.WithIdempotency(), router.WithJSONBody()),
+		router.NewPostRoute("/services/{id}/update", sr.updateService, router.WithJSONBody()),
 		router.NewDeleteRoute("/services/{id}", sr.removeService),
-		router.NewGetRoute("/services/{id}/logs", sr.getServiceLogs),
+		router.NewGetRoute("/services/{id}/logs", sr.getServiceLogs, router.WithTimeout(router.NoTimeout)),
 
 		router.NewGetRoute("/nodes", sr.getNodes),
 		router.NewGetRoute("/nodes/{id}", sr.getNode),
 		router.NewDeleteRoute("/nodes/{id}", sr.removeNode),
-		router.NewPostRoute("/nodes/{id}/update", sr.updateNode),
+		router.NewPostRoute("/nodes/{id}/update", sr.updateNode, router.WithJSONBody()),
 
 		router.NewGetRoute("/tasks", sr.getTasks),
 		router.NewGetRoute("/tasks/{id}", sr.getTask),
-		router.NewGetRoute("/tasks/{id}/logs", sr.getTaskLogs),
+		router.NewGetRoute("/tasks/{id}/logs", sr.getTaskLogs, router.WithTimeout(router.NoTimeout)),
 
 		router.NewGetRoute("/secrets", sr.getSecrets),
-		router.NewPostRoute("/secrets/create", sr.createSecret),
+		router.NewPostRoute("/secrets/create", sr.createSecret, router.WithIdempotency(), router.WithJSONBody()),
 		router.NewDeleteRoute("/secrets/{id}", sr.removeSecret),
 		router.NewGetRoute("/secrets/{id}", sr.getSecret),
-		router.NewPostRoute("/secrets/{id}/update", sr.updateSecret),
+		router.NewPostRoute("/secrets/{id}/update", sr.updateSecret, router.WithJSONBody()),
 
 		router.NewGetRoute("/configs", sr.getConfigs),
-		router.NewPostRoute("/configs/create", sr.createConfig),
+		router.NewPostRoute("/configs/create", sr.createConfig, router.WithIdempotency(), router.WithJSONBody()),
 		router.NewDeleteRoute("/configs/{id}", sr.removeConfig),
 		router.NewGetRoute("/configs/{id}", sr.getConfig),
-		router.NewPostRoute("/configs/{id}/update", sr.updateConfig),
+		router.NewPostRoute("/configs/{id}/update", sr.updateConfig, router.WithJSONBody()),
 	}
 }
@@ -11,7 +11,6 @@ import (
 	"github.com/docker/docker/api/types/backend"
 	"github.com/docker/docker/api/types/filters"
 	types "github.com/docker/docker/api/types/swarm"
-	"github.com/docker/docker/api/types/versions"
 	"github.com/docker/docker/errdefs"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -25,12 +24,12 @@ func (sr *swarmRouter) initCluster(ctx context.Context, w http.ResponseWriter, r
 	version := httputils.VersionFromContext(ctx)
 
 	// DefaultAddrPool and SubnetSize were added in API 1.39. Ignore on older API versions.
-	if versions.LessThan(version, "1.39") {
+	if version.LessThan("1.39") {
 		req.DefaultAddrPool = nil
 		req.SubnetSize = 0
 	}
 	// DataPathPort was added in API 1.40. Ignore this option on older API versions.
-	if versions.LessThan(version, "1.40") {
+	if version.LessThan("1.40") {
 		req.DataPathPort = 0
 	}
 	nodeID, err := sr.backend.Init(req)
@@ -157,7 +156,7 @@ func (sr *swarmRouter) getServices(ctx context.Context, w http.ResponseWriter, r
 	// the client is using a lesser version, ignore the parameter.
 	cliVersion := httputils.VersionFromContext(ctx)
 	var status bool
-	if value := r.URL.Query().Get("status"); value != "" && !versions.LessThan(cliVersion, "1.41") {
+	if value := r.URL.Query().Get("status"); value != "" && !cliVersion.LessThan("1.41") {
 		var err error
 		status, err = strconv.ParseBool(value)
 		if err != nil {
@@ -210,7 +209,7 @@ func (sr *swarmRouter) createService(ctx context.Context, w http.ResponseWriter,
 	encodedAuth := r.Header.Get("X-Registry-Auth")
 	queryRegistry := false
 	if v := httputils.VersionFromContext(ctx); v != "" {
-		if versions.LessThan(v, "1.30") {
+		if v.LessThan("1.30") {
 			queryRegistry = true
 		}
 		adjustForAPIVersion(v, &service)
@@ -245,7 +244,7 @@ func (sr *swarmRouter) updateService(ctx context.Context, w http.ResponseWriter,
 	flags.Rollback = r.URL.Query().Get("rollback")
 	queryRegistry := false
 	if v := httputils.VersionFromContext(ctx); v != "" {
-		if versions.LessThan(v, "1.30") {
+		if v.LessThan("1.30") {
 			queryRegistry = true
 		}
 		adjustForAPIVersion(v, &service)
@@ -404,7 +403,7 @@ func (sr *swarmRouter) createSecret(ctx context.Context, w http.ResponseWriter,
 		return err
 	}
 	version := httputils.VersionFromContext(ctx)
-	if secret.Templating != nil && versions.LessThan(version, "1.37") {
+	if secret.Templating != nil && version.LessThan("1.37") {
 		return errdefs.InvalidParameter(errors.Errorf("secret templating is not supported on the specified API version: %s", version))
 	}
 
@@ -476,7 +475,7 @@ func (sr *swarmRouter) createConfig(ctx context.Context, w http.ResponseWriter,
 	}
 
 	version := httputils.VersionFromContext(ctx)
-	if config.Templating != nil && versions.LessThan(version, "1.37") {
+	if config.Templating != nil && version.LessThan("1.37") {
 		return errdefs.InvalidParameter(errors.Errorf("config templating is not supported on the specified API version: %s", version))
 	}
 
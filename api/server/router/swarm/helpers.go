@@ -9,7 +9,6 @@ import (
 	basictypes "github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/backend"
 	"github.com/docker/docker/api/types/swarm"
-	"github.com/docker/docker/api/types/versions"
 )
 
 // swarmLogs takes an http response, request, and selector, and writes the logs
@@ -63,7 +62,7 @@ func (sr *swarmRouter) swarmLogs(ctx context.Context, w http.ResponseWriter, r *
 	}
 
 	contentType := basictypes.MediaTypeRawStream
-	if !tty && versions.GreaterThanOrEqualTo(httputils.VersionFromContext(ctx), "1.42") {
+	if !tty && httputils.VersionFromContext(ctx).GreaterThanOrEqualTo("1.42") {
 		contentType = basictypes.MediaTypeMultiplexedStream
 	}
 	w.Header().Set("Content-Type", contentType)
@@ -73,11 +72,11 @@ func (sr *swarmRouter) swarmLogs(ctx context.Context, w http.ResponseWriter, r *
 
 // adjustForAPIVersion takes a version and service spec and removes fields to
 // make the spec compatible with the specified version.
-func adjustForAPIVersion(cliVersion string, service *swarm.ServiceSpec) {
+func adjustForAPIVersion(cliVersion httputils.APIVersion, service *swarm.ServiceSpec) {
 	if cliVersion == "" {
 		return
 	}
-	if versions.LessThan(cliVersion, "1.40") {
+	if cliVersion.LessThan("1.40") {
 		if service.TaskTemplate.ContainerSpec != nil {
 			// Sysctls for docker swarm services weren't supported before
 			// API version 1.40
@@ -99,7 +98,7 @@ func adjustForAPIVersion(cliVersion string, service *swarm.ServiceSpec) {
 			service.TaskTemplate.Placement.MaxReplicas = 0
 		}
 	}
-	if versions.LessThan(cliVersion, "1.41") {
+	if cliVersion.LessThan("1.41") {
 		if service.TaskTemplate.ContainerSpec != nil {
 			// Capabilities and Ulimits for docker swarm services weren't
 			// supported before API version 1.41
@@ -28,11 +28,11 @@ func NewRouter(b Backend, c ClusterBackend, builder *buildkit.Builder, features
 		router.NewOptionsRoute("/{anyroute:.*}", optionsHandler),
 		router.NewGetRoute("/_ping", r.pingHandler),
 		router.NewHeadRoute("/_ping", r.pingHandler),
-		router.NewGetRoute("/events", r.getEvents),
-		router.NewGetRoute("/info", r.getInfo),
+		router.NewGetRoute("/events", r.getEvents, router.WithTimeout(router.NoTimeout)),
+		router.NewGetRoute("/info", r.getInfo, router.WithBufferedResponse()),
 		router.NewGetRoute("/version", r.getVersion),
-		router.NewGetRoute("/system/df", r.getDiskUsage),
-		router.NewPostRoute("/auth", r.postAuth),
+		router.NewGetRoute("/system/df", r.getDiskUsage, router.WithBufferedResponse(), router.WithTimeout(router.NoTimeout)),
+		router.NewPostRoute("/auth", r.postAuth, router.WithUpstreamTimeout(router.RegistryTimeout)),
 	}
 
 	return r
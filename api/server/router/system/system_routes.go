@@ -15,7 +15,6 @@ import (
 	"github.com/docker/docker/api/types/registry"
 	"github.com/docker/docker/api/types/swarm"
 	timetypes "github.com/docker/docker/api/types/time"
-	"github.com/docker/docker/api/types/versions"
 	"github.com/docker/docker/pkg/ioutils"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -65,7 +64,7 @@ func (s *systemRouter) getInfo(ctx context.Context, w http.ResponseWriter, r *ht
 	}
 
 	version := httputils.VersionFromContext(ctx)
-	if versions.LessThan(version, "1.25") {
+	if version.LessThan("1.25") {
 		// TODO: handle this conversion in engine-api
 		type oldInfo struct {
 			*types.Info
@@ -86,7 +85,7 @@ func (s *systemRouter) getInfo(ctx context.Context, w http.ResponseWriter, r *ht
 		old.SecurityOptions = nameOnlySecurityOptions
 		return httputils.WriteJSON(w, http.StatusOK, old)
 	}
-	if versions.LessThan(version, "1.39") {
+	if version.LessThan("1.39") {
 		if info.KernelVersion == "" {
 			info.KernelVersion = "<unknown>"
 		}
@@ -94,7 +93,7 @@ func (s *systemRouter) getInfo(ctx context.Context, w http.ResponseWriter, r *ht
 			info.OperatingSystem = "<unknown>"
 		}
 	}
-	if versions.GreaterThanOrEqualTo(version, "1.42") {
+	if version.GreaterThanOrEqualTo("1.42") {
 		info.KernelMemory = false
 	}
 	return httputils.WriteJSON(w, http.StatusOK, info)
@@ -115,7 +114,7 @@ func (s *systemRouter) getDiskUsage(ctx context.Context, w http.ResponseWriter,
 
 	var getContainers, getImages, getVolumes, getBuildCache bool
 	typeStrs, ok := r.Form["type"]
-	if versions.LessThan(version, "1.42") || !ok {
+	if version.LessThan("1.42") || !ok {
 		getContainers, getImages, getVolumes, getBuildCache = true, true, true, true
 	} else {
 		for _, typ := range typeStrs {
@@ -171,7 +170,7 @@ func (s *systemRouter) getDiskUsage(ctx context.Context, w http.ResponseWriter,
 	}
 
 	var builderSize int64
-	if versions.LessThan(version, "1.42") {
+	if version.LessThan("1.42") {
 		for _, b := range buildCache {
 			builderSize += b.Size
 		}
@@ -27,13 +27,13 @@ func (r *volumeRouter) Routes() []router.Route {
 func (r *volumeRouter) initRoutes() {
 	r.routes = []router.Route{
 		// GET
-		router.NewGetRoute("/volumes", r.getVolumesList),
+		router.NewGetRoute("/volumes", r.getVolumesList, router.WithBufferedResponse()),
 		router.NewGetRoute("/volumes/{name:.*}", r.getVolumeByName),
 		// POST
-		router.NewPostRoute("/volumes/create", r.postVolumesCreate),
-		router.NewPostRoute("/volumes/prune", r.postVolumesPrune),
+		router.NewPostRoute("/volumes/create", r.postVolumesCreate, router.WithIdempotency(), router.WithJSONBody()),
+		router.NewPostRoute("/volumes/prune", r.postVolumesPrune, router.WithTimeout(router.NoTimeout)),
 		// PUT
-		router.NewPutRoute("/volumes/{name:.*}", r.putVolumesUpdate),
+		router.NewPutRoute("/volumes/{name:.*}", r.putVolumesUpdate, router.WithJSONBody()),
 		// DELETE
 		router.NewDeleteRoute("/volumes/{name:.*}", r.deleteVolumes),
 	}
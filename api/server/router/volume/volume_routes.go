@@ -8,7 +8,6 @@ import (
 
 	"github.com/docker/docker/api/server/httputils"
 	"github.com/docker/docker/api/types/filters"
-	"github.com/docker/docker/api/types/versions"
 	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/errdefs"
 	"github.com/docker/docker/volume/service/opts"
@@ -37,7 +36,7 @@ func (v *volumeRouter) getVolumesList(ctx context.Context, w http.ResponseWriter
 	}
 
 	version := httputils.VersionFromContext(ctx)
-	if versions.GreaterThanOrEqualTo(version, clusterVolumesVersion) && v.cluster.IsManager() {
+	if version.GreaterThanOrEqualTo(clusterVolumesVersion) && v.cluster.IsManager() {
 		clusterVolumes, swarmErr := v.cluster.GetVolumes(volume.ListOptions{Filters: filters})
 		if swarmErr != nil {
 			// if there is a swarm error, we may not want to error out right
@@ -69,7 +68,7 @@ func (v *volumeRouter) getVolumeByName(ctx context.Context, w http.ResponseWrite
 	// if the volume is not found in the regular volume backend, and the client
 	// is using an API version greater than 1.42 (when cluster volumes were
 	// introduced), then check if Swarm has the volume.
-	if errdefs.IsNotFound(err) && versions.GreaterThanOrEqualTo(version, clusterVolumesVersion) && v.cluster.IsManager() {
+	if errdefs.IsNotFound(err) && version.GreaterThanOrEqualTo(clusterVolumesVersion) && v.cluster.IsManager() {
 		swarmVol, err := v.cluster.GetVolume(vars["name"])
 		// if swarm returns an error and that error indicates that swarm is not
 		// initialized, return original NotFound error. Otherwise, we'd return
@@ -115,7 +114,7 @@ func (v *volumeRouter) postVolumesCreate(ctx context.Context, w http.ResponseWri
 	//
 	// Instead, we will allow creating a volume with a duplicate name, which
 	// should not break anything.
-	if req.ClusterVolumeSpec != nil && versions.GreaterThanOrEqualTo(version, clusterVolumesVersion) {
+	if req.ClusterVolumeSpec != nil && version.GreaterThanOrEqualTo(clusterVolumesVersion) {
 		logrus.Debug("using cluster volume")
 		vol, err = v.cluster.CreateVolume(req)
 	} else {
@@ -163,7 +162,7 @@ func (v *volumeRouter) deleteVolumes(ctx context.Context, w http.ResponseWriter,
 
 	err := v.backend.Remove(ctx, vars["name"], opts.WithPurgeOnError(force))
 	if err != nil {
-		if errdefs.IsNotFound(err) && versions.GreaterThanOrEqualTo(version, clusterVolumesVersion) && v.cluster.IsManager() {
+		if errdefs.IsNotFound(err) && version.GreaterThanOrEqualTo(clusterVolumesVersion) && v.cluster.IsManager() {
 			err := v.cluster.RemoveVolume(vars["name"], force)
 			if err != nil {
 				return err
@@ -0,0 +1,89 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/server/httputils"
+	"github.com/docker/docker/api/server/router"
+	"github.com/docker/docker/api/server/router/debug"
+)
+
+const (
+	// debugPathPrefix is the path prefix of the routes of the debug router.
+	debugPathPrefix = "/debug"
+
+	// routeTablePath is the path of the endpoint listing the routes of the
+	// server, if enabled by Config.EnableRouteTable.
+	routeTablePath = "/_admin/routes"
+)
+
+// RouteInfo describes a route of the server.
+type RouteInfo struct {
+	Method string
+	Path   string
+	// Deprecated is set for deprecated routes, and Replacement is the path
+	// of the endpoint replacing them, if any (see router.WithDeprecation).
+	Deprecated  bool   `json:",omitempty"`
+	Replacement string `json:",omitempty"`
+}
+
+// Routes returns the routes of the server's routers, including the debug
+// routes if the profiler is enabled, and excluding the disabled endpoints,
+// sorted by path and method. API routes are listed with their path template,
+// without the API version prefix with which they are also served.
+func (s *Server) Routes() []RouteInfo {
+	s.mu.RLock()
+	routers := s.routers
+	s.mu.RUnlock()
+
+	var routes []RouteInfo
+	for _, apiRouter := range routers {
+		for _, r := range apiRouter.Routes() {
+			if !s.endpointDisabled(r.Path()) {
+				opts := router.OptionsOf(r)
+				routes = append(routes, RouteInfo{
+					Method:      r.Method(),
+					Path:        r.Path(),
+					Deprecated:  opts.Deprecated,
+					Replacement: opts.Replacement,
+				})
+			}
+		}
+	}
+	if s.ProfilerEnabled() {
+		for _, r := range debug.NewRouter().Routes() {
+			if !s.endpointDisabled(debugPathPrefix + r.Path()) {
+				routes = append(routes, RouteInfo{Method: r.Method(), Path: debugPathPrefix + r.Path()})
+			}
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+// endpointDisabled returns whether the route with the given path template is
+// disabled by Config.DisabledEndpoints.
+func (s *Server) endpointDisabled(path string) bool {
+	for _, prefix := range s.cfg.DisabledEndpoints {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if prefix == "" {
+			continue
+		}
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) getRouteTable(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return httputils.WriteJSON(w, http.StatusOK, s.Routes())
+}
@@ -5,47 +5,583 @@ import (
 	"crypto/tls"
 	"net"
 	"net/http"
+	"os"
+	"regexp"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/docker/docker/api"
 	"github.com/docker/docker/api/server/httpstatus"
 	"github.com/docker/docker/api/server/httputils"
 	"github.com/docker/docker/api/server/middleware"
 	"github.com/docker/docker/api/server/router"
 	"github.com/docker/docker/api/server/router/debug"
+	"github.com/docker/docker/api/types/versions"
 	"github.com/docker/docker/dockerversion"
+	"github.com/docker/docker/errdefs"
+	"github.com/docker/docker/pkg/authorization"
+	"github.com/docker/docker/pkg/stringid"
 	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
 )
 
+// log is the logger of the API server. Its entries have a "component"
+// field, so that they can be filtered from the logs of the daemon.
+var log = logrus.WithField("component", "api")
+
 // versionMatcher defines a variable matcher to be parsed by the router
 // when a request is about to be served.
 const versionMatcher = "/v{version:[0-9.]+}"
 
+// apiVersionFormat matches the API versions that can be configured, such as
+// "1.41".
+var apiVersionFormat = regexp.MustCompile(`^[0-9]+\.[0-9]+$`)
+
+// pingPath is the path of the endpoint that clients ping to negotiate the
+// API version, which is served without a version prefix even when
+// versioned paths are required.
+const pingPath = "/_ping"
+
+// preReleaseVersionMatcher is the same as versionMatcher, but additionally
+// accepts an optional pre-release suffix (such as "/v1.40-beta"), which is
+// made available to handlers as the "prerelease" variable.
+const preReleaseVersionMatcher = versionMatcher + "{prerelease:(?:-[0-9A-Za-z.]+)?}"
+
 // Config provides the configuration for the API server
 type Config struct {
 	CorsHeaders string
 	Version     string
 	SocketGroup string
 	TLSConfig   *tls.Config
+
+	// PathVarPatterns maps the names of path variables (such as "name" in
+	// "/containers/{name:.*}/json") to the regular expressions their values
+	// must fully match. Requests with values not matching the pattern of a
+	// variable are rejected with a "400 Bad Request" status before they are
+	// handled. Values of all variables are normalized before they are
+	// matched, and must not contain control characters, regardless of the
+	// patterns. Invalid patterns are rejected by Config.Validate.
+	PathVarPatterns map[string]string
+
+	// RequireVersionedPaths disables serving the API at paths without a
+	// version prefix (such as "/containers/json"), so that clients must
+	// specify the API version in the path of their requests, as in
+	// "/v1.41/containers/json". "/_ping" is still served, as clients ping
+	// it to negotiate the API version.
+	RequireVersionedPaths bool
+
+	// DefaultAPIVersion is the API version of the requests to paths without
+	// a version prefix (such as "/containers/json"), which is stored in the
+	// context of the request as if it was in the path, so that clients not
+	// specifying a version keep the same contract after the daemon is
+	// upgraded. When unset, the requests use the latest version. It must be
+	// between MinAPIVersion and MaxAPIVersion, which is checked by
+	// Config.Validate.
+	DefaultAPIVersion string
+
+	// SocketUser and SocketMode are the owner and file mode of the unix
+	// sockets the server listens on. When unset, sockets are owned by root,
+	// with mode 0660.
+	SocketUser string
+	SocketMode os.FileMode
+
+	// TCPBacklog is the size of the accept queue of the TCP sockets the
+	// server listens on, and TCPReusePort enables SO_REUSEPORT on them, so
+	// that multiple processes can listen on the same port. They are not
+	// supported on Windows.
+	TCPBacklog   int
+	TCPReusePort bool
+
+	// MinTLSVersion is the minimum TLS version ("1.2" or "1.3") accepted
+	// by TLS listeners, and TLSCipherSuites the names of the cipher suites
+	// allowed for TLS 1.2 connections, as named by the crypto/tls package.
+	// When unset, TLS 1.2 is the minimum version (unless TLSConfig requires
+	// a higher one), and the cipher suites of TLSConfig are used.
+	MinTLSVersion   string
+	TLSCipherSuites []string
+
+	// ClientCANames is the list of common names allowed for client
+	// certificates. When set, TLS connections require a verified client
+	// certificate whose common name is in the list.
+	ClientCANames []string
+
+	// TLSCertFile, TLSKeyFile, and TLSCAFile are the paths of the TLS
+	// certificate, key, and client CA certificates. When set, the TLS
+	// configuration obtains them from disk, and reloads them when the
+	// files are modified, so that certificates can be rotated without
+	// restarting the server.
+	TLSCertFile string
+	TLSKeyFile  string
+	TLSCAFile   string
+
+	// SNICerts maps server names (such as "docker.example.com", or
+	// "*.example.com" to match any subdomain) to the certificates presented
+	// by TLS listeners to clients requesting them using SNI, to serve the
+	// API under several names. The default certificate is presented to
+	// clients requesting other names. The certificates are reloaded when
+	// their files are modified.
+	SNICerts map[string]CertPaths
+
+	// OCSPResponderURL is the URL of the OCSP responder queried for the
+	// revocation status of the TLS certificate, and OCSPStapleFile the path
+	// of a file containing a DER-encoded OCSP response for the certificate,
+	// such as obtained by an external tool. If either is set, TLS listeners
+	// staple the OCSP response to the default certificate they present (not
+	// to the SNICerts), which is refreshed halfway through its validity.
+	// Failing to obtain a response is logged, and the certificate is
+	// presented without a response. OCSPStapleFile takes precedence.
+	OCSPResponderURL string
+	OCSPStapleFile   string
+
+	// ReadTimeout, ReadHeaderTimeout, WriteTimeout, and IdleTimeout are
+	// passed to the http.Server of each listener. A zero value means no
+	// timeout.
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	// MaxHeaderBytes is the maximum size (in bytes) of the headers of
+	// requests, including the request line. Requests with larger headers
+	// are rejected with a "431 Request Header Fields Too Large" status. A
+	// zero value uses the default of net/http (http.DefaultMaxHeaderBytes,
+	// 1 MB).
+	MaxHeaderBytes int
+
+	// MaxPathLength is the maximum length (in bytes) of the path of
+	// requests, which is checked before routing the requests, so that
+	// pathological paths are not matched against the routes. Requests with
+	// longer paths are rejected with a "414 URI Too Long" status. A zero
+	// value means no limit.
+	MaxPathLength int
+
+	// MaxPathSegments is the maximum number of segments of the path of
+	// requests (such as 4 for "/v1.41/containers/foo/json"), which is
+	// checked before any other processing of the requests, so that
+	// adversarial paths are not matched against the routes. Requests with
+	// more segments are rejected with a "400 Bad Request" status. A zero
+	// value means no limit.
+	MaxPathSegments int
+
+	// MaxUploadBytesPerSec is the maximum rate (in bytes per second) at
+	// which the request bodies of routes accepting large streams (such as
+	// build and image load) are read. The rate is shared by all these
+	// requests, so that concurrent uploads are read at this rate in total.
+	// A zero value means no limit.
+	MaxUploadBytesPerSec int
+
+	// DisableTCPKeepAlive disables TCP keep-alive on the connections of TCP
+	// listeners, which is otherwise enabled with probes sent every
+	// TCPKeepAlivePeriod (DefaultTCPKeepAlivePeriod if unset). It does not
+	// apply to unix sockets.
+	DisableTCPKeepAlive bool
+	TCPKeepAlivePeriod  time.Duration
+
+	// MaxAcceptRate is the maximum number of connections per second accepted
+	// by each listener, with bursts of up to as many connections, so that
+	// spikes of connections (for example, during deployments) are smoothed
+	// out: connections beyond the rate wait to be accepted, instead of being
+	// rejected. A zero value means no limit.
+	MaxAcceptRate float64
+
+	// MaxConnectionsPerListener is the maximum number of connections open
+	// on each listener, including hijacked connections. Connections beyond
+	// the limit are closed as soon as they are accepted. It is coarser, but
+	// cheaper, than limiting the concurrency of requests. A zero value
+	// means no limit.
+	MaxConnectionsPerListener int
+
+	// TrustedProxyProtocol enables the PROXY protocol (v1 and v2) on TCP
+	// listeners, for servers behind a load balancer: connections must start
+	// with a PROXY protocol header, and the client address it contains is
+	// used as the remote address of the requests. The header is decoded
+	// before TLS is terminated. It must only be enabled if the listeners
+	// can only be reached through the load balancer, as clients connecting
+	// directly could otherwise pretend to connect from any address.
+	TrustedProxyProtocol bool
+
+	// MaxRequestBodyBytes is the default maximum size (in bytes) of request
+	// bodies, for routes that do not set their own limit. A zero value
+	// means no limit.
+	MaxRequestBodyBytes int64
+
+	// DecompressRequestBodies enables the decompression of the request
+	// bodies sent with a "Content-Encoding: gzip" header for all routes,
+	// instead of only for the routes marked with router.WithDecompressBody,
+	// so that handlers read the decompressed body. Requests whose body
+	// decompresses to more than MaxDecompressedBodyBytes bytes (or
+	// DefaultMaxDecompressedBodyBytes, if zero) fail with a "413 Request
+	// Entity Too Large" status. MaxRequestBodyBytes limits the size of the
+	// compressed body.
+	DecompressRequestBodies  bool
+	MaxDecompressedBodyBytes int64
+
+	// Logging enables logging of every request handled by the server, in
+	// the format set by AccessLogFormat ("text" or "json").
+	Logging         bool
+	AccessLogFormat string
+
+	// AccessLogSampleRate, if greater than 1, only logs one in
+	// AccessLogSampleRate requests, except for the requests that are always
+	// logged: the requests taking longer than AccessLogSlowThreshold, if
+	// set, and the requests failing with a non-2xx status, unless
+	// AccessLogSampleErrors is set.
+	AccessLogSampleRate    int
+	AccessLogSlowThreshold time.Duration
+	AccessLogSampleErrors  bool
+
+	// ListenLogLevel is the level (as parsed by logrus.ParseLevel) at which
+	// the server logs the addresses it listens on, or "none" to not log
+	// them. When unset, they are logged at the info level.
+	ListenLogLevel string
+
+	// MinAPIVersion is the minimum API version accepted by the server, and
+	// DeprecatedAPIVersion the API version below which clients are warned
+	// that the version they use is deprecated.
+	MinAPIVersion        string
+	DeprecatedAPIVersion string
+
+	// MaxAPIVersion, if set, is the maximum API version accepted by the
+	// server, which is used by the requests without a version, instead of
+	// the latest version. Requests for a higher version are rejected with a
+	// "400 Bad Request" status.
+	MaxAPIVersion string
+
+	// AllowPreReleaseVersions makes the server accept API versions with a
+	// pre-release suffix in the request path, such as "/v1.40-beta".
+	AllowPreReleaseVersions bool
+
+	// RateLimit is the number of requests per second allowed for each
+	// client, with bursts of up to RateLimitBurst requests. A zero value
+	// disables rate limiting. Requests on unix sockets are not limited.
+	RateLimit      float64
+	RateLimitBurst int
+
+	// MaxConcurrentRequests is the maximum number of requests handled
+	// concurrently. Requests beyond the limit wait for up to
+	// RequestQueueTimeout before they are rejected with a "503 Service
+	// Unavailable" status. A zero value disables the limit.
+	MaxConcurrentRequests int
+	RequestQueueTimeout   time.Duration
+
+	// AllowedHosts is the list of values of the Host header (such as
+	// "docker.example.com", or "docker.example.com:2376") accepted on TCP
+	// sockets, to protect them against DNS rebinding attacks. Requests
+	// with other hosts are rejected with a "421 Misdirected Request"
+	// status. An empty list allows all hosts.
+	AllowedHosts []string
+
+	// ResponseHeaders are headers (such as "X-Content-Type-Options", or
+	// "Strict-Transport-Security") that are set on every response, overriding
+	// the values set by the handlers.
+	ResponseHeaders map[string]string
+
+	// AllowedCIDRs and DeniedCIDRs restrict the source addresses of requests
+	// received on TCP sockets, as CIDRs (such as "192.168.0.0/16") or IP
+	// addresses. Requests from denied addresses, or, if AllowedCIDRs is not
+	// empty, from addresses that are not allowed, are rejected with a "403
+	// Forbidden" status. Requests received on unix sockets are not checked.
+	AllowedCIDRs []string
+	DeniedCIDRs  []string
+
+	// DrainAllowlist is the list of path templates of the routes (such as
+	// "/containers/{name:.*}/stop") that are allowed for all methods while
+	// the server is draining (see Server.SetDraining).
+	DrainAllowlist []string
+
+	// RejectUntilInitialized makes the API listeners, and the handler
+	// returned by Server.Handler, reject requests with a "503 Service
+	// Unavailable" status until the daemon is initialized, as signaled by
+	// Server.SetInitialized, so that the server can start serving before
+	// the daemon is initialized. The health endpoints and "/_ping" are
+	// served regardless.
+	RejectUntilInitialized bool
+
+	// EnableTracing enables the creation of OpenTelemetry spans for API
+	// requests, using the global TracerProvider. Spans continue the traces
+	// propagated by clients in the "traceparent" header.
+	EnableTracing bool
+
+	// EnableCompression enables the compression of JSON and text responses
+	// using gzip or deflate, for clients that accept it.
+	EnableCompression bool
+
+	// ProfilerAllowedCNs and ProfilerToken restrict access to the debug
+	// routes, which include the Go profiler, to clients with a verified
+	// certificate whose common name is in the list, or that send the token
+	// as bearer token in the "Authorization" header. When either is set, the
+	// profiler can also be enabled and disabled at runtime, using the
+	// "/_admin/profiler" endpoint.
+	ProfilerAllowedCNs []string
+	ProfilerToken      string
+
+	// ProfilerWarmup, if set, enables the profiler when the server is
+	// created, and disables it once the duration elapsed, to capture
+	// profiles of the startup of the daemon without leaving the profiler
+	// exposed afterwards. Enabling or disabling the profiler using the
+	// "/_admin/profiler" endpoint cancels the warm-up.
+	//
+	// As requests can be rejected until the daemon is initialized (see
+	// RejectUntilInitialized), the startup cannot always be profiled using
+	// the debug routes: ProfilerWarmupCPUProfile can be set to the path of a
+	// file to which a CPU profile of the warm-up period is written, starting
+	// when the server is created. The profile is complete
+	// once the warm-up ends, or is cancelled. Other CPU profiles, such as
+	// those of "/debug/pprof/profile", cannot be taken during that period.
+	ProfilerWarmup           time.Duration
+	ProfilerWarmupCPUProfile string
+
+	// EnableRouteTable enables an endpoint listing the routes of the
+	// server, which exposes internals of the server, such as debug routes.
+	EnableRouteTable bool
+
+	// EnableMiddlewareList enables an endpoint listing the middlewares of
+	// the request chain, in the order in which they are evaluated, to
+	// diagnose their interactions.
+	EnableMiddlewareList bool
+
+	// EnableOpenAPI enables an endpoint serving a machine-readable
+	// description of the API, for client code generation: OpenAPISpec (such
+	// as the api/swagger.yaml file of the release of the daemon) if set, or
+	// otherwise a minimal Swagger 2.0 description generated from the routes
+	// of the server, which only describes their paths and parameters.
+	EnableOpenAPI bool
+	OpenAPISpec   []byte
+
+	// DisabledEndpoints is a list of path template prefixes (such as
+	// "/build", or "/containers/{name:.*}/exec") of the routes that are not
+	// registered, so that requests to them fail with a "404 Not Found", as
+	// for unknown endpoints. Prefixes match whole path segments, so that
+	// "/exec" disables "/exec/{name:.*}/start" but not "/executions".
+	DisabledEndpoints []string
+
+	// EnableConfigEndpoint enables an endpoint returning the effective
+	// configuration of the daemon, as set by Server.SetConfigSource, for
+	// debugging.
+	EnableConfigEndpoint bool
+
+	// ErrorFormat is the format of error responses: ErrorFormatJSON (the
+	// default) for a JSON object with a "message" field, or
+	// ErrorFormatProblemJSON for RFC 7807 problem details.
+	ErrorFormat string
+
+	// CircuitBreakerThreshold is the number of consecutive requests to a
+	// group of routes failing with a server error (5xx) after which the
+	// requests to the group are rejected with a "503 Service Unavailable"
+	// status for CircuitBreakerCooldown, to let the subsystem they depend
+	// on recover. CircuitBreakerGroups are the path template prefixes (such
+	// as "/containers/{name:.*}/exec") of the groups; routes not in a group
+	// are grouped by the first segment of their path. A zero threshold
+	// disables the circuit breaker.
+	CircuitBreakerThreshold int
+	CircuitBreakerCooldown  time.Duration
+	CircuitBreakerGroups    []string
+
+	// RequestTimeout is the default maximum duration of requests, after
+	// which their context is cancelled. Routes can override it, and
+	// streaming routes (such as attach, logs, and events) are exempt, as
+	// are routes whose duration depends on the request or on the state of
+	// the daemon (such as container stop, which waits for the timeout set
+	// by the client, and the prune routes). A zero value disables the
+	// timeout.
+	RequestTimeout time.Duration
+
+	// MaxClientTimeout, if set, allows clients to set the maximum duration
+	// of their requests using the "X-Docker-Timeout" header (such as
+	// "X-Docker-Timeout: 30s"), which is clamped to MaxClientTimeout. Like
+	// RequestTimeout, it does not apply to streaming routes.
+	MaxClientTimeout time.Duration
+
+	// DebugBodyLogging enables logging the headers and bodies of requests
+	// and of their responses, for debugging, for the routes with the path
+	// templates in DebugBodyLogRoutes (such as "/containers/create"), or all
+	// routes if empty. Up to DebugBodyLogMaxBytes bytes of each body are
+	// logged (middleware.DefaultBodyLogMaxBytes if zero). Credentials in
+	// headers and JSON bodies are redacted.
+	DebugBodyLogging     bool
+	DebugBodyLogRoutes   []string
+	DebugBodyLogMaxBytes int
+
+	// IdempotencyKeyTTL is the duration for which the responses of requests
+	// with an "Idempotency-Key" header are cached, for the routes that
+	// support it (such as container create), so that the response of a
+	// request retried with the same key is replayed instead of creating a
+	// duplicate resource. IdempotencyCacheSize is the maximum number of
+	// cached responses (DefaultIdempotencyCacheSize if zero). A zero TTL
+	// disables the cache.
+	IdempotencyKeyTTL    time.Duration
+	IdempotencyCacheSize int
+
+	// SlowRequestThreshold is the duration after which a warning, including
+	// the stacks of all goroutines, is logged for requests that are still
+	// being handled, to surface stuck handlers. Routes exempt from the
+	// request timeout are not watched. A zero value disables the warning.
+	SlowRequestThreshold time.Duration
+
+	// HijackIdleTimeout is the duration after which hijacked connections
+	// (such as those of container attach and exec start, including their
+	// websocket variants, and of BuildKit sessions) are closed if no data
+	// was read from or written to them, so that the streams of clients that
+	// went away without closing them do not leak. A zero value means no
+	// timeout.
+	HijackIdleTimeout time.Duration
+
+	// EnableHTTP2 enables HTTP/2 on TLS listeners, negotiated using ALPN.
+	// Unix sockets and plain-text TCP listeners only serve HTTP/1.1.
+	//
+	// Endpoints that hijack the connection to stream raw data require
+	// HTTP/1.1, and fail over HTTP/2: container attach and exec start,
+	// including their websocket variants, and the "/session" and "/grpc"
+	// endpoints. Clients using them must not negotiate HTTP/2. Other
+	// streaming endpoints, such as logs and events, work over both.
+	EnableHTTP2 bool
+
+	// PanicDumpDir is the directory to which the stacks of all goroutines
+	// are written when a handler panics, for postmortem analysis, in a file
+	// named "goroutine-stacks-<timestamp>.log". If empty, only the stack of
+	// the panicking handler is logged. The stacks are written at most once
+	// per minute, and at most 10 times, after which only the stack of the
+	// panicking handler is logged.
+	PanicDumpDir string
+
+	// MetricsAddr is the TCP address of a dedicated listener serving the
+	// "/metrics" endpoint, so that it can be firewalled separately from the
+	// API. If set, the endpoint is no longer served on the API listeners.
+	// MetricsListenerHealth and MetricsListenerProfiler additionally serve
+	// the health endpoints, and the profiler endpoints (which remain subject
+	// to Server.EnableProfiler and Server.DisableProfiler), on the listener.
+	MetricsAddr             string
+	MetricsListenerHealth   bool
+	MetricsListenerProfiler bool
+}
+
+// Validate validates the configuration. The server fails to start serving
+// with an invalid configuration.
+func (cfg *Config) Validate() error {
+	if err := cfg.ValidateTLSOptions(); err != nil {
+		return errors.Wrap(err, "invalid TLS configuration")
+	}
+	if err := cfg.ValidatePathVarPatterns(); err != nil {
+		return err
+	}
+	return cfg.validateDefaultAPIVersion()
+}
+
+// validateDefaultAPIVersion checks that the DefaultAPIVersion is a version
+// accepted by the server, between MinAPIVersion (or api.MinVersion) and
+// MaxAPIVersion (or api.DefaultVersion).
+func (cfg *Config) validateDefaultAPIVersion() error {
+	if cfg.DefaultAPIVersion == "" {
+		return nil
+	}
+	if !apiVersionFormat.MatchString(cfg.DefaultAPIVersion) {
+		return errors.Errorf("invalid default API version %q: must be of the form 1.41", cfg.DefaultAPIVersion)
+	}
+	minVersion, maxVersion := cfg.MinAPIVersion, cfg.MaxAPIVersion
+	if minVersion == "" {
+		minVersion = api.MinVersion
+	}
+	if maxVersion == "" {
+		maxVersion = api.DefaultVersion
+	}
+	if versions.LessThan(cfg.DefaultAPIVersion, minVersion) || versions.GreaterThan(cfg.DefaultAPIVersion, maxVersion) {
+		return errors.Errorf("invalid default API version %s: must be between %s and %s", cfg.DefaultAPIVersion, minVersion, maxVersion)
+	}
+	return nil
 }
 
 // Server contains instance details for the server
 type Server struct {
 	cfg         *Config
 	servers     []*HTTPServer
+	metrics     *HTTPServer // serves the metrics listener, if Config.MetricsAddr is set
 	routers     []router.Router
 	middlewares []middleware.Middleware
+	hooks       []requestHook
+	panicDumps  panicDumpLimiter
+
+	mu                sync.RWMutex
+	healthCheck       func() error
+	initialized       bool
+	configSource      func() (interface{}, error)
+	profilerDisabled  bool
+	profilerWarmup    *time.Timer
+	profilerWarmupCPU *os.File // the CPU profile of the warm-up, if any
+	draining          bool
+
+	// handler is the handler shared by all servers. It is set once the
+	// server starts serving, or Handler is called, and its router is
+	// swapped when routers are added. apiHandler is handler behind the
+	// checks applied to requests before routing them, which is what the
+	// servers serve, and what Handler returns.
+	handler    *routerSwapper
+	apiHandler http.Handler
+	serving    bool
+	running    int
+	serveErrs  chan error
+	serveDone  chan struct{}
+	ready      chan struct{}
+
+	idempotencyOnce  sync.Once
+	idempotencyCache *idempotencyCache
+
+	uploadLimiterOnce sync.Once
+	uploadLimiter     *rate.Limiter
+
+	pathVarsOnce   sync.Once
+	pathVarRegexps map[string]*regexp.Regexp
+
+	// requests are the requests in flight, which are logged if they are
+	// abandoned on shutdown.
+	requests activeRequests
 }
 
 // New returns a new instance of the server based on the specified configuration.
 // It allocates resources which will be needed for ServeAPI(ports, unix-sockets).
 func New(cfg *Config) *Server {
-	return &Server{
+	if cfg.TLSCertFile != "" && cfg.TLSKeyFile != "" && cfg.TLSConfig == nil {
+		cfg.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if cfg.TLSConfig != nil {
+		if err := applyTLSOptions(cfg.TLSConfig, cfg.MinTLSVersion, cfg.TLSCipherSuites); err != nil {
+			// The server fails to start serving with invalid options (see
+			// Config.Validate); apply the defaults meanwhile, so that the
+			// TLS configuration is never left with weaker settings.
+			_ = applyTLSOptions(cfg.TLSConfig, "", nil)
+		}
+	}
+	if cfg.TLSConfig != nil && len(cfg.ClientCANames) > 0 {
+		requireClientCANames(cfg.TLSConfig, cfg.ClientCANames)
+	}
+	if cfg.TLSCertFile != "" && cfg.TLSKeyFile != "" {
+		reloader := newTLSFileReloader(cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSCAFile)
+		if err := reloader.reload(); err != nil {
+			log.WithError(err).Error("failed to load TLS files")
+		}
+		reloader.configure(cfg.TLSConfig)
+	}
+	if cfg.TLSConfig != nil && (cfg.OCSPResponderURL != "" || cfg.OCSPStapleFile != "") {
+		configureOCSPStapling(cfg.TLSConfig, cfg.OCSPResponderURL, cfg.OCSPStapleFile)
+	}
+	if cfg.TLSConfig != nil && len(cfg.SNICerts) > 0 {
+		configureSNICertificates(cfg.TLSConfig, cfg.SNICerts)
+	}
+	s := &Server{
 		cfg: cfg,
 	}
+	if cfg.ProfilerWarmup > 0 {
+		s.startProfilerWarmup(cfg.ProfilerWarmup, cfg.ProfilerWarmupCPUProfile)
+	}
+	return s
 }
 
-// UseMiddleware appends a new middleware to the request chain.
+// UseMiddleware appends a new middleware to the request chain, which is
+// evaluated before the middlewares that were added before it. Middlewares
+// implementing middleware.NamedMiddleware can be referred to by name, using
+// UseMiddlewareBefore, UseMiddlewareAfter, and ReplaceMiddleware.
 // This needs to be called before the API routes are configured.
 func (s *Server) UseMiddleware(m middleware.Middleware) {
 	s.middlewares = append(s.middlewares, m)
@@ -53,62 +589,311 @@ func (s *Server) UseMiddleware(m middleware.Middleware) {
 
 // Accept sets a listener the server accepts connections into.
 func (s *Server) Accept(addr string, listeners ...net.Listener) {
+	s.AcceptTLS(addr, nil, listeners...)
+}
+
+// AcceptTLS sets a listener the server accepts connections into, using
+// tlsConfig to serve TLS on those listeners. If tlsConfig is nil, the
+// listeners are used as-is, which allows serving plain-text on some
+// listeners (such as a unix socket), and TLS on others.
+func (s *Server) AcceptTLS(addr string, tlsConfig *tls.Config, listeners ...net.Listener) {
+	bindings := make([]Binding, 0, len(listeners))
 	for _, listener := range listeners {
-		httpServer := &HTTPServer{
-			srv: &http.Server{
-				Addr: addr,
-			},
-			l: listener,
+		bindings = append(bindings, Binding{Addr: addr, Listener: listener})
+	}
+	s.AcceptBindings(tlsConfig, bindings...)
+}
+
+// Binding is a listener, and the address it was created for.
+type Binding struct {
+	// Addr is the address the listener was created for, such as
+	// "tcp://0.0.0.0:2376". It is used to refer to the listener in
+	// ReplaceListener, and may differ from the address the listener is
+	// actually bound to.
+	Addr     string
+	Listener net.Listener
+}
+
+// AcceptBindings sets listeners the server accepts connections into, each
+// with its own address. If tlsConfig is not nil, it is used to serve TLS on
+// the listeners.
+func (s *Server) AcceptBindings(tlsConfig *tls.Config, bindings ...Binding) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, b := range bindings {
+		s.servers = append(s.servers, s.newHTTPServer(b.Addr, tlsConfig, b.Listener))
+	}
+}
+
+// newHTTPServer returns an HTTPServer serving listener. The listener is
+// used as-is if it is a reference to a listener shared with another
+// HTTPServer.
+func (s *Server) newHTTPServer(addr string, tlsConfig *tls.Config, listener net.Listener) *HTTPServer {
+	ref, ok := listener.(*listenerRef)
+	if !ok {
+		ref = newSharedListener(withConnLimit(withAcceptBackoff(withAcceptRate(withProxyProtocol(withTCPKeepAlive(listener, s.cfg), s.cfg), s.cfg)), s.cfg)).ref()
+	}
+	baseTLSConfig := tlsConfig
+	stats := newConnStats()
+	srv := &http.Server{
+		Addr:              addr,
+		ReadTimeout:       s.cfg.ReadTimeout,
+		ReadHeaderTimeout: s.cfg.ReadHeaderTimeout,
+		WriteTimeout:      s.cfg.WriteTimeout,
+		IdleTimeout:       s.cfg.IdleTimeout,
+		MaxHeaderBytes:    s.cfg.MaxHeaderBytes,
+		ConnState:         stats.track,
+		ConnContext:       connContext,
+	}
+	if tlsConfig != nil && s.cfg.EnableHTTP2 {
+		h2Config, err := configureHTTP2(srv, tlsConfig)
+		if err != nil {
+			log.WithError(err).Warnf("failed to enable HTTP/2 on %s; serving HTTP/1.1 only", addr)
+		} else {
+			tlsConfig = h2Config
 		}
-		s.servers = append(s.servers, httpServer)
+	}
+	return &HTTPServer{
+		srv:           srv,
+		l:             ref,
+		addr:          listener.Addr().String(),
+		tlsConfig:     tlsConfig,
+		baseTLSConfig: baseTLSConfig,
+		stats:         stats,
 	}
 }
 
-// Close closes servers and thus stop receiving requests
-func (s *Server) Close() {
+// connContext adds the credentials of the peer process of unix socket
+// connections to the context of their requests.
+func connContext(ctx context.Context, c net.Conn) context.Context {
+	if lc, ok := c.(*limitedConn); ok {
+		c = lc.Conn
+	}
+	uc, ok := c.(*net.UnixConn)
+	if !ok {
+		return ctx
+	}
+	cred, err := peerCred(uc)
+	if err != nil {
+		log.WithError(err).Debug("failed to get the peer credentials of a unix socket connection")
+		return ctx
+	}
+	return context.WithValue(ctx, httputils.PeerCredKey{}, cred)
+}
+
+// ReplaceListener replaces the listeners for addr with newListener, without
+// dropping connections. If the server is serving, it starts serving on
+// newListener, then gracefully shuts down the servers of the old listeners,
+// blocking until their active connections complete. If the old listeners
+// serve TLS, TLS is served on newListener with the same configuration, so
+// that newListener must not be wrapped using tls.NewListener; certificates
+// are rotated by reloading the files of Config.TLSCertFile and
+// Config.TLSKeyFile instead.
+func (s *Server) ReplaceListener(addr string, newListener net.Listener) error {
+	s.mu.Lock()
+	var old, servers []*HTTPServer
 	for _, srv := range s.servers {
-		if err := srv.Close(); err != nil {
-			logrus.Error(err)
+		if srv.srv.Addr == addr {
+			old = append(old, srv)
+		} else {
+			servers = append(servers, srv)
 		}
 	}
+	if len(old) == 0 {
+		s.mu.Unlock()
+		return errdefs.NotFound(errors.Errorf("no listener found for address %s", addr))
+	}
+	srv := s.newHTTPServer(addr, old[0].baseTLSConfig, newListener)
+	s.servers = append(servers, srv)
+	serving := s.serving
+	if serving {
+		s.serve(srv, nil)
+	}
+	s.mu.Unlock()
+
+	if !serving {
+		return closeServers(old)
+	}
+	return shutdownServers(context.Background(), old)
+}
+
+// Close closes servers and thus stop receiving requests. It is equivalent to
+// calling Shutdown with a background context, and logs any error.
+func (s *Server) Close() {
+	if err := s.Shutdown(context.Background()); err != nil {
+		log.Error(err)
+	}
+}
+
+// Shutdown gracefully shuts down the servers without interrupting any active
+// connections, mirroring http.Server.Shutdown. It stops accepting new
+// connections, then waits for in-flight requests to complete, or until ctx
+// is done, whichever happens first, in which case the requests still in
+// flight are logged and their connections are forcibly closed. If any of
+// the servers fails to shut down, it returns a *ShutdownError with the
+// results of each listener.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.stopProfilerWarmup()
+	s.mu.RLock()
+	servers := s.servers
+	if s.metrics != nil {
+		servers = append(servers[:len(servers):len(servers)], s.metrics)
+	}
+	s.mu.RUnlock()
+	err := shutdownServers(ctx, servers)
+	if err != nil && ctx.Err() != nil {
+		s.abandonRequests(servers)
+	}
+	return err
+}
+
+// Ready returns a channel that is closed once the server has started serving
+// on all of its listeners.
+func (s *Server) Ready() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.readyLocked()
+}
+
+func (s *Server) readyLocked() chan struct{} {
+	if s.ready == nil {
+		s.ready = make(chan struct{})
+	}
+	return s.ready
 }
 
 // serveAPI loops through all initialized servers and spawns goroutine
 // with Serve method for each. It sets createMux() as Handler also.
 func (s *Server) serveAPI() error {
-	var chErrors = make(chan error, len(s.servers))
+	if err := s.cfg.Validate(); err != nil {
+		return errors.Wrap(err, "invalid API server configuration")
+	}
+	s.mu.Lock()
+	if s.handler != nil {
+		s.handler.Swap(s.createMux())
+	}
+	s.apiHandlerLocked()
+	metricsServer, err := s.metricsServerLocked()
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.serving = true
+	s.serveErrs = make(chan error, len(s.servers)+1)
+	s.serveDone = make(chan struct{})
+	var started sync.WaitGroup
 	for _, srv := range s.servers {
-		srv.srv.Handler = s.createMux()
-		go func(srv *HTTPServer) {
-			var err error
-			logrus.Infof("API listen on %s", srv.l.Addr())
-			if err = srv.Serve(); err != nil && strings.Contains(err.Error(), "use of closed network connection") {
-				err = nil
-			}
-			chErrors <- err
-		}(srv)
+		started.Add(1)
+		s.serve(srv, started.Done)
 	}
+	if metricsServer != nil {
+		started.Add(1)
+		s.serve(metricsServer, started.Done)
+	}
+	ready := s.readyLocked()
+	s.mu.Unlock()
+	go func() {
+		started.Wait()
+		close(ready)
+	}()
+	defer close(s.serveDone)
+
+	for {
+		s.mu.RLock()
+		running := s.running
+		s.mu.RUnlock()
+		if running == 0 {
+			return nil
+		}
 
-	for range s.servers {
-		err := <-chErrors
+		err := <-s.serveErrs
+		s.mu.Lock()
+		s.running--
+		s.mu.Unlock()
 		if err != nil {
 			return err
 		}
 	}
-	return nil
+}
+
+// serve spawns a goroutine with the Serve method of srv, which reports its
+// result to serveAPI. If started is not nil, it is called right before srv
+// starts serving. It must be called with s.mu held.
+func (s *Server) serve(srv *HTTPServer, started func()) {
+	srv.srv.Handler = s.apiHandlerLocked()
+	if srv.handler != nil {
+		srv.srv.Handler = srv.handler
+	}
+	s.running++
+
+	serveErrs, serveDone := s.serveErrs, s.serveDone
+	go func() {
+		var err error
+		s.logListen(srv)
+		if started != nil {
+			started()
+		}
+		if err = srv.Serve(); err == http.ErrServerClosed || (err != nil && strings.Contains(err.Error(), "use of closed network connection")) {
+			err = nil
+		}
+		select {
+		case serveErrs <- err:
+		case <-serveDone:
+		}
+	}()
+}
+
+// logListen announces that srv is about to serve, at the level set by
+// Config.ListenLogLevel.
+func (s *Server) logListen(srv *HTTPServer) {
+	level := logrus.InfoLevel
+	switch s.cfg.ListenLogLevel {
+	case "":
+	case "none":
+		return
+	default:
+		l, err := logrus.ParseLevel(s.cfg.ListenLogLevel)
+		if err != nil {
+			log.WithError(err).Warn("invalid API listen log level; using info")
+			break
+		}
+		level = l
+	}
+	log.WithFields(logrus.Fields{
+		"addr":  srv.addr,
+		"proto": srv.l.Addr().Network(),
+		"tls":   srv.tlsConfig != nil,
+	}).Logf(level, "API listen on %s", srv.addr)
 }
 
 // HTTPServer contains an instance of http server and the listener.
 // srv *http.Server, contains configuration to create an http server and a mux router with all api end points.
-// l   net.Listener, is a TCP or Socket listener that dispatches incoming request to the router.
+// l   *listenerRef, is a reference to a TCP or Socket listener that dispatches incoming request to the router.
+// tlsConfig *tls.Config, if set, is used to serve TLS on the listener, and baseTLSConfig is the tlsConfig it was derived from.
+// stats *connStats, tracks the state of the connections of the listener.
+// handler http.Handler, if set, is served instead of the API, such as for the metrics listener.
 type HTTPServer struct {
-	srv *http.Server
-	l   net.Listener
+	srv           *http.Server
+	l             *listenerRef
+	addr          string
+	tlsConfig     *tls.Config
+	baseTLSConfig *tls.Config
+	stats         *connStats
+	handler       http.Handler
+}
+
+// Addr returns the address the listener of the HTTPServer is bound to.
+func (s *HTTPServer) Addr() string {
+	return s.addr
 }
 
 // Serve starts listening for inbound requests.
 func (s *HTTPServer) Serve() error {
-	return s.srv.Serve(s.l)
+	var l net.Listener = s.l
+	if s.tlsConfig != nil {
+		l = tls.NewListener(l, s.tlsConfig)
+	}
+	return s.srv.Serve(l)
 }
 
 // Close closes the HTTPServer from listening for the inbound requests.
@@ -116,8 +901,75 @@ func (s *HTTPServer) Close() error {
 	return s.l.Close()
 }
 
-func (s *Server) makeHTTPHandler(handler httputils.APIFunc) http.HandlerFunc {
+// Shutdown gracefully shuts down the HTTPServer, waiting for active
+// connections to become idle, or until ctx is done.
+func (s *HTTPServer) Shutdown(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
+
+func (s *Server) makeHTTPHandler(handler httputils.APIFunc, path string, opts router.RouteOptions) http.HandlerFunc {
+	if opts.UpstreamTimeout > 0 {
+		handler = upstreamTimeoutHandler(handler, opts.UpstreamTimeout)
+	}
+	if opts.ValidateBody != nil {
+		handler = validateBodyHandler(handler, opts.ValidateBody)
+	}
+	if opts.JSONBody {
+		handler = jsonBodyHandler(handler)
+	}
+	if opts.Authorize != nil {
+		handler = authorizeHandler(handler, path, opts.Authorize)
+	}
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = s.cfg.RequestTimeout
+	}
+	if timeout > 0 {
+		handler = timeoutHandler(handler, timeout)
+	}
+	if s.cfg.MaxClientTimeout > 0 && opts.Timeout != router.NoTimeout {
+		handler = clientTimeoutHandler(handler, s.cfg.MaxClientTimeout)
+	}
+	if opts.Idempotent && s.cfg.IdempotencyKeyTTL > 0 {
+		handler = idempotentHandler(handler, s.idempotency())
+	}
+	if opts.ETag {
+		handler = etagHandler(handler)
+	}
+	maxBodyBytes := opts.MaxBodyBytes
+	if maxBodyBytes == 0 {
+		maxBodyBytes = s.cfg.MaxRequestBodyBytes
+	}
+	var maxDecompressedBytes int64
+	if opts.DecompressBody || s.cfg.DecompressRequestBodies {
+		maxDecompressedBytes = s.cfg.MaxDecompressedBodyBytes
+		if maxDecompressedBytes <= 0 {
+			maxDecompressedBytes = DefaultMaxDecompressedBodyBytes
+		}
+	}
+	var uploadLimiter *rate.Limiter
+	if opts.StreamingBody && s.cfg.MaxUploadBytesPerSec > 0 {
+		uploadLimiter = s.uploadRateLimiter()
+	}
+	var slowThreshold time.Duration
+	if opts.Timeout != router.NoTimeout {
+		slowThreshold = s.cfg.SlowRequestThreshold
+	}
+	var deprecation string
+	if opts.Deprecated {
+		deprecation = deprecationHeader(opts.DeprecatedSince)
+	}
+	hooks := s.hooks
+
 	return func(w http.ResponseWriter, r *http.Request) {
+		sw := &hookStatusWriter{ResponseWriter: w}
+		w = sw
+		if len(hooks) > 0 {
+			// Deferred first, so that the hooks see the response written
+			// by recoverHandler if the handler panics.
+			defer func() { runAfterHooks(hooks, r, sw.Status()) }()
+		}
+
 		// Define the context that we'll pass around to share info
 		// like the docker-request-id.
 		//
@@ -129,34 +981,256 @@ func (s *Server) makeHTTPHandler(handler httputils.APIFunc) http.HandlerFunc {
 		// use intermediate variable to prevent "should not use basic type
 		// string as key in context.WithValue" golint errors
 		ctx := context.WithValue(r.Context(), dockerversion.UAStringKey{}, r.Header.Get("User-Agent"))
+
+		requestID := requestIDFromRequest(r)
+		defer s.recoverHandler(sw, r, requestID)
+		defer s.requests.add(requestID, r)()
+		ctx = context.WithValue(ctx, httputils.RequestIDKey{}, requestID)
+		ctx = context.WithValue(ctx, httputils.RouteTemplateKey{}, routeTemplate(r))
+		if opts.AliasOf != nil {
+			method, requestURI := opts.AliasOf(r)
+			ctx = authorization.WithRequestAlias(ctx, method, requestURI)
+		}
+		w.Header().Set(httputils.RequestIDHeader, requestID)
+		if opts.Deprecated {
+			// See RFC 9745, "The Deprecation HTTP Response Header Field".
+			w.Header().Set("Deprecation", deprecation)
+			if opts.Replacement != "" {
+				w.Header().Add("Link", successorLink(r, opts.Replacement))
+			}
+		}
 		r = r.WithContext(ctx)
+		runBeforeHooks(hooks, r)
+		if slowThreshold > 0 {
+			stop := watchSlowRequest(r, requestID, slowThreshold)
+			defer stop()
+		}
 		handlerFunc := s.handlerWithGlobalMiddlewares(handler)
 
 		vars := mux.Vars(r)
 		if vars == nil {
 			vars = make(map[string]string)
 		}
+		if _, ok := vars["version"]; !ok && s.cfg.DefaultAPIVersion != "" {
+			vars["version"] = s.cfg.DefaultAPIVersion
+		}
+		if err := s.normalizePathVars(vars); err != nil {
+			s.makeErrorHandler(err)(w, r)
+			return
+		}
+
+		if maxBodyBytes > 0 && r.ContentLength > maxBodyBytes {
+			// Reject the request before reading its body, so that clients
+			// expecting a "100 Continue" do not send it.
+			s.makeErrorHandler(requestBodyTooLargeError{limit: maxBodyBytes})(w, r)
+			return
+		}
+		var body *maxBodyReader
+		if maxBodyBytes > 0 && r.Body != nil && r.Body != http.NoBody {
+			body = newMaxBodyReader(w, r.Body, maxBodyBytes)
+			r.Body = body
+		}
+		if uploadLimiter != nil && r.Body != nil && r.Body != http.NoBody {
+			r.Body = newRateLimitedReader(ctx, r.Body, uploadLimiter)
+		}
+		var gzBody *gzipBodyReader
+		if maxDecompressedBytes > 0 && r.Body != nil && r.Body != http.NoBody && isGzipEncoded(r) {
+			var err error
+			if gzBody, err = newGzipBodyReader(r.Body, maxDecompressedBytes); err != nil {
+				if body != nil && body.exceeded {
+					err = requestBodyTooLargeError{limit: maxBodyBytes}
+				}
+				s.makeErrorHandler(err)(w, r)
+				return
+			}
+			r.Body = gzBody
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+			r.ContentLength = -1
+		}
 
-		if err := handlerFunc(ctx, w, r, vars); err != nil {
+		rw := w
+		if s.cfg.HijackIdleTimeout > 0 {
+			rw = &hijackIdleWriter{ResponseWriter: rw, timeout: s.cfg.HijackIdleTimeout}
+		}
+		var buf *responseBuffer
+		if opts.BufferResponse {
+			buf = newResponseBuffer(rw)
+			rw = buf
+		}
+		err := handlerFunc(ctx, rw, r, vars)
+		if buf != nil {
+			if err != nil {
+				buf.discard()
+			} else {
+				_ = buf.commit()
+			}
+		}
+		if err != nil {
+			if body != nil && body.exceeded {
+				err = requestBodyTooLargeError{limit: maxBodyBytes}
+			} else if gzBody != nil && gzBody.exceeded {
+				err = decompressedBodyTooLargeError{limit: maxDecompressedBytes}
+			}
 			statusCode := httpstatus.FromError(err)
 			if statusCode >= 500 {
-				logrus.Errorf("Handler for %s %s returned error: %v", r.Method, r.URL.Path, err)
+				log.WithField("request-id", requestID).Errorf("Handler for %s %s returned error: %v", r.Method, r.URL.Path, err)
+			}
+			s.makeErrorHandler(err)(w, r)
+		}
+	}
+}
+
+// idempotency returns the cache of the responses of requests with an
+// idempotency key, which is shared by the routers the server creates.
+func (s *Server) idempotency() *idempotencyCache {
+	s.idempotencyOnce.Do(func() {
+		s.idempotencyCache = newIdempotencyCache(s.cfg.IdempotencyCacheSize, s.cfg.IdempotencyKeyTTL)
+	})
+	return s.idempotencyCache
+}
+
+// uploadRateLimiter returns the limiter of the rate at which the request
+// bodies of streaming routes are read, which is shared by the routers the
+// server creates.
+func (s *Server) uploadRateLimiter() *rate.Limiter {
+	s.uploadLimiterOnce.Do(func() {
+		s.uploadLimiter = rate.NewLimiter(rate.Limit(s.cfg.MaxUploadBytesPerSec), s.cfg.MaxUploadBytesPerSec)
+	})
+	return s.uploadLimiter
+}
+
+// authorizeHandler returns a handler that calls authorize before handler,
+// and rejects the request if authorize returns an error.
+func authorizeHandler(handler httputils.APIFunc, path string, authorize router.AuthorizeFunc) httputils.APIFunc {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		if err := authorize(ctx, path, vars); err != nil {
+			return errdefs.Forbidden(err)
+		}
+		return handler(ctx, w, r, vars)
+	}
+}
+
+// routeTemplate returns the path template of the route that matched r,
+// without the API version prefix, to keep the cardinality of the labels
+// that use it bounded.
+func routeTemplate(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ""
+	}
+	tpl, err := route.GetPathTemplate()
+	if err != nil {
+		return ""
+	}
+	if strings.HasPrefix(tpl, "/v{version") {
+		if i := strings.Index(tpl, "}"); i != -1 {
+			tpl = tpl[i+1:]
+		}
+		if strings.HasPrefix(tpl, "{prerelease") {
+			if i := strings.Index(tpl, "}/"); i != -1 {
+				tpl = tpl[i+1:]
 			}
-			makeErrorHandler(err)(w, r)
 		}
 	}
+	return tpl
+}
+
+// maxRequestIDLength is the maximum length of a request ID provided by the
+// client; longer IDs are replaced by a generated one.
+const maxRequestIDLength = 128
+
+// requestIDFormat matches the request IDs accepted from clients. Request IDs
+// are logged, and echoed in the response headers, so that other characters
+// (such as newlines) could forge log entries.
+var requestIDFormat = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// requestIDFromRequest returns the request ID sent by the client, or
+// generates a new, unique, request ID if none was provided, or if the one
+// provided is too long, or contains characters other than letters, digits,
+// ".", "_", and "-".
+func requestIDFromRequest(r *http.Request) string {
+	if id := r.Header.Get(httputils.RequestIDHeader); len(id) <= maxRequestIDLength && requestIDFormat.MatchString(id) {
+		return id
+	}
+	return stringid.GenerateRandomID()
 }
 
 // InitRouter initializes the list of routers for the server.
 // This method also enables the Go profiler.
 func (s *Server) InitRouter(routers ...router.Router) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.routers = append(s.routers, routers...)
+	if s.handler != nil {
+		s.handler.Swap(s.createMux())
+	}
 }
 
-type pageNotFoundError struct{}
+// Handler returns the http.Handler serving the routes of the server
+// through its middlewares, which is the handler of its listeners. It allows
+// programs embedding the daemon to mount the API in their own mux, for
+// example, using http.StripPrefix to serve it under a path prefix. The
+// returned handler serves the routers added to the server afterwards, and
+// reflects the settings applied by Reload.
+func (s *Server) Handler() http.Handler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.apiHandlerLocked()
+}
 
-func (pageNotFoundError) Error() string {
-	return "page not found"
+// apiHandlerLocked returns the handler serving the API, creating it if
+// needed. The checks applied before routing (the limits on the path of
+// requests, and the rejection of requests until the daemon is initialized)
+// are part of it, so that they also apply to the handler returned by
+// Handler.
+func (s *Server) apiHandlerLocked() http.Handler {
+	if s.apiHandler != nil {
+		return s.apiHandler
+	}
+	if s.handler == nil {
+		s.handler = &routerSwapper{router: s.createMux()}
+	}
+	var handler http.Handler = s.handler
+	if s.cfg.RejectUntilInitialized {
+		handler = s.startupHandler(handler)
+	}
+	if s.cfg.MaxPathLength > 0 {
+		handler = s.maxPathLengthHandler(handler, s.cfg.MaxPathLength)
+	}
+	if s.cfg.MaxPathSegments > 0 {
+		handler = s.maxPathSegmentsHandler(handler, s.cfg.MaxPathSegments)
+	}
+	s.apiHandler = handler
+	return handler
+}
+
+// AddRouter adds r to the routers of the server. Unlike InitRouter, it can
+// be called after the server has started serving: the router of the server
+// is then rebuilt, and swapped for the current one. Requests that are being
+// handled complete using the current router.
+func (s *Server) AddRouter(r router.Router) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routers = append(s.routers, r)
+	if s.handler != nil {
+		s.handler.Swap(s.createMux())
+	}
+}
+
+type pageNotFoundError struct {
+	suggestions []string
+}
+
+func (e pageNotFoundError) Error() string {
+	switch len(e.suggestions) {
+	case 0:
+		return "page not found"
+	case 1:
+		return "page not found; did you mean " + e.suggestions[0] + "?"
+	default:
+		return "page not found; did you mean one of " + strings.Join(e.suggestions, ", ") + "?"
+	}
 }
 
 func (pageNotFoundError) NotFound() {}
@@ -164,29 +1238,82 @@ func (pageNotFoundError) NotFound() {}
 // createMux initializes the main router the server uses.
 func (s *Server) createMux() *mux.Router {
 	m := mux.NewRouter()
+	// Match the routes against the escaped path, so that encoded slashes
+	// (as in "library%2Fubuntu") do not separate path segments, and are not
+	// cleaned by the router. The values of the path variables are decoded,
+	// and normalized, by normalizePathVars.
+	m.UseEncodedPath()
 
-	logrus.Debug("Registering routers")
+	versionPath := versionMatcher
+	if s.cfg.AllowPreReleaseVersions {
+		versionPath = preReleaseVersionMatcher
+	}
+
+	allowed := newAllowedMethods(s.makeErrorHandler)
+	var templates []string
+
+	log.Debug("Registering routers")
 	for _, apiRouter := range s.routers {
 		for _, r := range apiRouter.Routes() {
-			f := s.makeHTTPHandler(r.Handler())
+			if s.endpointDisabled(r.Path()) {
+				log.Debugf("Not registering disabled route %s, %s", r.Method(), r.Path())
+				continue
+			}
+			f := s.makeHTTPHandler(r.Handler(), r.Path(), router.OptionsOf(r))
+
+			log.Debugf("Registering %s, %s", r.Method(), r.Path())
+			m.Path(versionPath + r.Path()).Methods(r.Method()).Handler(f)
+			allowed.add(versionPath+r.Path(), r.Method())
+			if !s.cfg.RequireVersionedPaths || r.Path() == pingPath {
+				m.Path(r.Path()).Methods(r.Method()).Handler(f)
+				allowed.add(r.Path(), r.Method())
+			}
+			templates = append(templates, r.Path())
+		}
+	}
 
-			logrus.Debugf("Registering %s, %s", r.Method(), r.Path())
-			m.Path(versionMatcher + r.Path()).Methods(r.Method()).Handler(f)
-			m.Path(r.Path()).Methods(r.Method()).Handler(f)
+	for _, r := range debug.NewRouter().Routes() {
+		if s.endpointDisabled(debugPathPrefix + r.Path()) {
+			continue
 		}
+		f := s.makeHTTPHandler(s.profilerHandler(r.Handler()), debugPathPrefix+r.Path(), router.OptionsOf(r))
+		m.Path(debugPathPrefix + r.Path()).Handler(f)
+	}
+	if s.profilerRestricted() {
+		f := s.makeHTTPHandler(s.profilerAdmin, profilerAdminPath, router.RouteOptions{})
+		m.Path(profilerAdminPath).Methods(http.MethodGet, http.MethodPost).Handler(f)
 	}
 
-	debugRouter := debug.NewRouter()
-	s.routers = append(s.routers, debugRouter)
-	for _, r := range debugRouter.Routes() {
-		f := s.makeHTTPHandler(r.Handler())
-		m.Path("/debug" + r.Path()).Handler(f)
+	s.registerHealthRoutes(m)
+	if s.cfg.EnableRouteTable {
+		f := s.makeHTTPHandler(s.getRouteTable, routeTablePath, router.RouteOptions{})
+		m.Path(routeTablePath).Methods(http.MethodGet).Handler(f)
+	}
+	if s.cfg.EnableMiddlewareList {
+		f := s.makeHTTPHandler(s.getMiddlewares, middlewaresPath, router.RouteOptions{})
+		m.Path(middlewaresPath).Methods(http.MethodGet).Handler(f)
+	}
+	if s.cfg.EnableOpenAPI {
+		f := s.makeHTTPHandler(s.getOpenAPI, openAPIPath, router.RouteOptions{})
+		m.Path(openAPIPath).Methods(http.MethodGet).Handler(f)
+	}
+	if s.cfg.EnableConfigEndpoint {
+		f := s.makeHTTPHandler(s.getConfig, configAdminPath, router.RouteOptions{})
+		m.Path(configAdminPath).Methods(http.MethodGet).Handler(f)
+	}
+	if s.cfg.MetricsAddr == "" {
+		f := s.makeHTTPHandler(getMetrics, metricsPath, router.RouteOptions{})
+		m.Path(metricsPath).Methods(http.MethodGet).Handler(f)
 	}
 
-	notFoundHandler := makeErrorHandler(pageNotFoundError{})
-	m.HandleFunc(versionMatcher+"/{path:.*}", notFoundHandler)
+	notFoundHandler := s.notFoundHandler(templates)
+	methodNotAllowedHandler := allowed.handler(notFoundHandler)
+	if s.cfg.CorsHeaders != "" {
+		methodNotAllowedHandler = allowed.preflight(middleware.NewCORSMiddleware(s.cfg.CorsHeaders), methodNotAllowedHandler)
+	}
+	m.HandleFunc(versionPath+"/{path:.*}", methodNotAllowedHandler)
 	m.NotFoundHandler = notFoundHandler
-	m.MethodNotAllowedHandler = notFoundHandler
+	m.MethodNotAllowedHandler = methodNotAllowedHandler
 
 	return m
 }
@@ -196,7 +1323,7 @@ func (s *Server) createMux() *mux.Router {
 // the API execution.
 func (s *Server) Wait(waitChan chan error) {
 	if err := s.serveAPI(); err != nil {
-		logrus.Errorf("ServeAPI error: %v", err)
+		log.Errorf("ServeAPI error: %v", err)
 		waitChan <- err
 		return
 	}
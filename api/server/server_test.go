@@ -1,15 +1,34 @@
 package server // import "github.com/docker/docker/api/server"
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/docker/docker/api"
 	"github.com/docker/docker/api/server/httputils"
 	"github.com/docker/docker/api/server/middleware"
+	"github.com/docker/docker/api/server/router"
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
 )
 
 func TestMiddlewares(t *testing.T) {
@@ -43,3 +62,1669 @@ func TestMiddlewares(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+type recordingMiddleware struct {
+	name  string
+	calls *[]string
+}
+
+func (m recordingMiddleware) Name() string {
+	return m.name
+}
+
+func (m recordingMiddleware) WrapHandler(handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error) func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		*m.calls = append(*m.calls, m.name)
+		return handler(ctx, w, r, vars)
+	}
+}
+
+// denyingMiddleware rejects all requests, as an authorization plugin would.
+type denyingMiddleware struct{}
+
+func (denyingMiddleware) WrapHandler(handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error) func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		return errdefs.Forbidden(errors.New("denied"))
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var calls []string
+	named := func(name string) recordingMiddleware {
+		return recordingMiddleware{name: name, calls: &calls}
+	}
+
+	srv := &Server{cfg: &Config{}}
+	srv.UseMiddleware(named("authz"))
+	srv.UseMiddleware(named("logging"))
+	assert.NilError(t, srv.UseMiddlewareBefore("authz", named("plugin")))
+	assert.NilError(t, srv.UseMiddlewareAfter("logging", named("request-id")))
+	assert.NilError(t, srv.ReplaceMiddleware("authz", named("authz-v2")))
+	assert.Check(t, is.ErrorContains(srv.UseMiddlewareBefore("no-such-middleware", named("x")), "no middleware found"))
+
+	expected := []string{"logging", "request-id", "plugin", "authz-v2"}
+	assert.Check(t, is.DeepEqual(srv.Middlewares(), expected))
+
+	handlerFunc := srv.handlerWithGlobalMiddlewares(func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		return nil
+	})
+	assert.NilError(t, handlerFunc(context.Background(), httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil), nil))
+	assert.Check(t, is.DeepEqual(calls, expected))
+}
+
+func TestMiddlewareList(t *testing.T) {
+	get := func(srv *Server) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		srv.createMux().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/_admin/middlewares", nil))
+		return resp
+	}
+
+	var calls []string
+	srv := &Server{cfg: &Config{}}
+	srv.UseMiddleware(recordingMiddleware{name: "authz", calls: &calls})
+	assert.Check(t, is.Equal(get(srv).Code, http.StatusNotFound))
+
+	srv = &Server{cfg: &Config{EnableMiddlewareList: true, DebugBodyLogging: true}}
+	srv.UseMiddleware(recordingMiddleware{name: "authz", calls: &calls})
+	srv.UseMiddleware(recordingMiddleware{name: "logging", calls: &calls})
+	resp := get(srv)
+	assert.Check(t, is.Equal(resp.Code, http.StatusOK))
+	assert.Check(t, is.Equal(strings.TrimSpace(resp.Body.String()), `["body-logging","logging","authz"]`))
+	// the endpoint is subject to the middlewares of API requests
+	assert.Check(t, is.DeepEqual(calls, []string{"logging", "authz"}))
+
+	srv.UseMiddleware(denyingMiddleware{})
+	assert.Check(t, is.Equal(get(srv).Code, http.StatusForbidden))
+}
+
+func TestDefaultAPIVersion(t *testing.T) {
+	version := func(srv *Server, path string) string {
+		resp := httptest.NewRecorder()
+		srv.createMux().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Check(t, is.Equal(resp.Code, http.StatusOK), resp.Body.String())
+		return resp.Body.String()
+	}
+	newServer := func(defaultVersion string) *Server {
+		srv := &Server{cfg: &Config{DefaultAPIVersion: defaultVersion}}
+		srv.UseMiddleware(middleware.NewVersionMiddleware("0.1omega2", "1.41", "1.12"))
+		srv.InitRouter(fakeRouter{routes: []router.Route{
+			router.NewGetRoute("/containers/json", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+				_, err := w.Write([]byte(httputils.VersionFromContext(ctx)))
+				return err
+			}),
+		}})
+		return srv
+	}
+
+	srv := newServer("")
+	assert.Check(t, is.Equal(version(srv, "/containers/json"), "1.41"))
+
+	srv = newServer("1.40")
+	assert.Check(t, is.Equal(version(srv, "/containers/json"), "1.40"))
+	assert.Check(t, is.Equal(version(srv, "/v1.41/containers/json"), "1.41"))
+}
+
+func TestValidateDefaultAPIVersion(t *testing.T) {
+	for _, tc := range []struct {
+		cfg      Config
+		expected string
+	}{
+		{cfg: Config{}},
+		{cfg: Config{DefaultAPIVersion: api.MinVersion}},
+		{cfg: Config{DefaultAPIVersion: api.DefaultVersion}},
+		{cfg: Config{DefaultAPIVersion: "1.40", MinAPIVersion: "1.40", MaxAPIVersion: "1.40"}},
+		{cfg: Config{DefaultAPIVersion: "v1.40"}, expected: `invalid default API version "v1.40": must be of the form 1.41`},
+		{cfg: Config{DefaultAPIVersion: "1.11"}, expected: "invalid default API version 1.11: must be between " + api.MinVersion + " and " + api.DefaultVersion},
+		{cfg: Config{DefaultAPIVersion: "1.99"}, expected: "invalid default API version 1.99: must be between " + api.MinVersion + " and " + api.DefaultVersion},
+		{cfg: Config{DefaultAPIVersion: "1.39", MinAPIVersion: "1.40"}, expected: "invalid default API version 1.39: must be between 1.40 and " + api.DefaultVersion},
+		{cfg: Config{DefaultAPIVersion: "1.41", MaxAPIVersion: "1.40"}, expected: "invalid default API version 1.41: must be between " + api.MinVersion + " and 1.40"},
+	} {
+		err := tc.cfg.Validate()
+		if tc.expected == "" {
+			assert.Check(t, err, tc.cfg.DefaultAPIVersion)
+		} else {
+			assert.Check(t, is.Error(err, tc.expected))
+		}
+	}
+}
+
+func TestValidatePathVars(t *testing.T) {
+	srv := &Server{cfg: &Config{PathVarPatterns: map[string]string{
+		"name": `[a-zA-Z0-9][a-zA-Z0-9_./-]*`,
+		"id":   `[`,
+	}}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/containers/{name:.*}/json", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			_, err := io.WriteString(w, vars["name"])
+			return err
+		}),
+		router.NewGetRoute("/secrets/{id}", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			_, err := io.WriteString(w, vars["id"])
+			return err
+		}),
+	}})
+	m := srv.createMux()
+
+	for _, tc := range []struct {
+		path     string
+		expected int
+		message  string
+	}{
+		{path: "/v1.41/containers/web.1/json", expected: http.StatusOK},
+		{path: "/v1.41/containers/-web/json", expected: http.StatusBadRequest, message: "does not match"},
+		{path: "/v1.41/containers/web%00/json", expected: http.StatusBadRequest, message: "contains control characters"},
+		// encoded slashes are decoded, and the values cleaned
+		{path: "/v1.41/containers/web%2F%2F1%2F/json", expected: http.StatusOK, message: "web/1"},
+		{path: "/v1.41/containers/web%2F.%2F1/json", expected: http.StatusOK, message: "web/1"},
+		{path: "/v1.41/containers/web%2F..%2F1/json", expected: http.StatusBadRequest, message: "segment"},
+		// invalid patterns are ignored if the configuration is not validated
+		{path: "/v1.41/secrets/abc", expected: http.StatusOK},
+	} {
+		resp := httptest.NewRecorder()
+		m.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		assert.Check(t, is.Equal(resp.Code, tc.expected), tc.path)
+		assert.Check(t, is.Contains(resp.Body.String(), tc.message), tc.path)
+	}
+
+	assert.Check(t, is.ErrorContains(srv.cfg.Validate(), "invalid pattern for API path variable id"))
+	assert.Check(t, is.ErrorContains(srv.serveAPI(), "invalid API server configuration: invalid pattern for API path variable id"))
+}
+
+func TestRequireVersionedPaths(t *testing.T) {
+	noop := func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	srv := &Server{cfg: &Config{RequireVersionedPaths: true}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/_ping", noop),
+		router.NewGetRoute("/containers/json", noop),
+	}})
+	m := srv.createMux()
+
+	for _, tc := range []struct {
+		path     string
+		expected int
+	}{
+		{path: "/v1.41/containers/json", expected: http.StatusNoContent},
+		{path: "/containers/json", expected: http.StatusNotFound},
+		{path: "/_ping", expected: http.StatusNoContent},
+		{path: "/v1.41/_ping", expected: http.StatusNoContent},
+	} {
+		resp := httptest.NewRecorder()
+		m.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		assert.Check(t, is.Equal(resp.Code, tc.expected), tc.path)
+		if resp.Code == http.StatusNotFound {
+			assert.Check(t, is.Contains(resp.Body.String(), "did you mean /v"+api.DefaultVersion+"/containers/json?"))
+		}
+	}
+}
+
+func TestMaxPathLength(t *testing.T) {
+	srv := &Server{cfg: &Config{}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/containers/{name:.*}/json", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}),
+	}})
+	h := srv.maxPathLengthHandler(srv.createMux(), 32)
+
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/v1.41/containers/foo/json", nil))
+	assert.Check(t, is.Equal(resp.Code, http.StatusNoContent))
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/v1.41/containers/"+strings.Repeat("a", 32)+"/json", nil))
+	assert.Check(t, is.Equal(resp.Code, http.StatusRequestURITooLong))
+	assert.Check(t, is.Contains(resp.Body.String(), "request path exceeds the maximum length of 32 bytes"))
+}
+
+func TestMaxPathSegments(t *testing.T) {
+	srv := &Server{cfg: &Config{}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/containers/{name:.*}/json", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}),
+	}})
+	h := srv.maxPathSegmentsHandler(srv.createMux(), 4)
+
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/v1.41/containers/foo/json", nil))
+	assert.Check(t, is.Equal(resp.Code, http.StatusNoContent))
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/v1.41/containers/"+strings.Repeat("a/", 8)+"json", nil))
+	assert.Check(t, is.Equal(resp.Code, http.StatusBadRequest))
+	assert.Check(t, is.Contains(resp.Body.String(), "request path exceeds the maximum of 4 segments"))
+}
+
+func TestShutdownWaitsForActiveRequests(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	srv := &Server{cfg: &Config{}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/slow", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			close(started)
+			<-release
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}),
+	}})
+	srv.Accept(l.Addr().String(), l)
+
+	waitChan := make(chan error, 1)
+	go srv.Wait(waitChan)
+
+	respChan := make(chan *http.Response, 1)
+	go func() {
+		resp, err := http.Get("http://" + l.Addr().String() + "/slow")
+		assert.Check(t, err)
+		respChan <- resp
+	}()
+	<-started
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		shutdownErr <- srv.Shutdown(context.Background())
+	}()
+
+	select {
+	case err := <-shutdownErr:
+		t.Fatalf("Shutdown returned before the active request completed: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	resp := <-respChan
+	assert.Assert(t, resp != nil)
+	assert.Check(t, is.Equal(resp.StatusCode, http.StatusNoContent))
+	_ = resp.Body.Close()
+	assert.Check(t, <-shutdownErr)
+	assert.Check(t, <-waitChan)
+}
+
+func TestShutdownAbandonsRequestsOnTimeout(t *testing.T) {
+	logger := logrus.StandardLogger()
+	hooks := logger.ReplaceHooks(logrus.LevelHooks{})
+	defer logger.ReplaceHooks(hooks)
+	hook := &levelHook{}
+	logger.AddHook(hook)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+
+	started := make(chan struct{})
+	srv := &Server{cfg: &Config{}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/stuck", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		}),
+	}})
+	srv.Accept(l.Addr().String(), l)
+	waitChan := make(chan error, 1)
+	go srv.Wait(waitChan)
+
+	respErr := make(chan error, 1)
+	go func() {
+		resp, err := http.Get("http://" + l.Addr().String() + "/stuck")
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		respErr <- err
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var shutdownErr *ShutdownError
+	assert.Check(t, errors.As(srv.Shutdown(ctx), &shutdownErr))
+	assert.Check(t, <-respErr != nil, "expected the connection of the abandoned request to be closed")
+	assert.Check(t, <-waitChan)
+
+	var abandoned []string
+	for _, e := range hook.entries {
+		if e.Level == logrus.WarnLevel {
+			abandoned = append(abandoned, e.Message)
+		}
+	}
+	assert.Check(t, is.DeepEqual(abandoned, []string{"Abandoning request GET /stuck on shutdown"}))
+}
+
+type fakeRouter struct {
+	routes []router.Route
+}
+
+func (r fakeRouter) Routes() []router.Route {
+	return r.routes
+}
+
+func TestRequestBodyLimit(t *testing.T) {
+	readBody := func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		var v map[string]string
+		if err := httputils.ReadJSON(r, &v); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+
+	srv := &Server{cfg: &Config{MaxRequestBodyBytes: 16}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewPostRoute("/default", readBody),
+		router.NewPostRoute("/small", readBody, router.WithMaxBodyBytes(4)),
+		router.NewPostRoute("/unlimited", readBody, router.WithMaxBodyBytes(router.UnlimitedBodyBytes)),
+	}})
+	m := srv.createMux()
+
+	tests := []struct {
+		path     string
+		body     string
+		expected int
+	}{
+		{path: "/default", body: `{"a":"b"}`, expected: http.StatusNoContent},
+		{path: "/default", body: `{"a":"bbbbbbbbbbbbbbbbbbbbbbbb"}`, expected: http.StatusRequestEntityTooLarge},
+		{path: "/small", body: `{"a":"b"}`, expected: http.StatusRequestEntityTooLarge},
+		{path: "/unlimited", body: `{"a":"bbbbbbbbbbbbbbbbbbbbbbbb"}`, expected: http.StatusNoContent},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		m.ServeHTTP(resp, req)
+		assert.Check(t, is.Equal(resp.Code, tc.expected), "%s: %s", tc.path, resp.Body.String())
+	}
+}
+
+func TestGzipRequestBody(t *testing.T) {
+	readBody := func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		if r.Header.Get("Content-Encoding") != "" {
+			return errors.New("unexpected Content-Encoding header")
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(body)
+		return err
+	}
+
+	srv := &Server{cfg: &Config{MaxDecompressedBodyBytes: 16}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewPostRoute("/gzip", readBody, router.WithDecompressBody()),
+		router.NewPostRoute("/raw", readBody),
+	}})
+	m := srv.createMux()
+
+	gzipped := func(s string) *bytes.Buffer {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, _ = zw.Write([]byte(s))
+		_ = zw.Close()
+		return &buf
+	}
+	tests := []struct {
+		path     string
+		body     io.Reader
+		expected int
+		response string
+	}{
+		{path: "/gzip", body: gzipped("hello"), expected: http.StatusOK, response: "hello"},
+		{path: "/gzip", body: gzipped(strings.Repeat("a", 16)), expected: http.StatusOK, response: strings.Repeat("a", 16)},
+		{path: "/gzip", body: gzipped(strings.Repeat("a", 1024)), expected: http.StatusRequestEntityTooLarge},
+		{path: "/gzip", body: strings.NewReader("hello"), expected: http.StatusBadRequest},
+		{path: "/raw", body: gzipped("hello"), expected: http.StatusInternalServerError},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest(http.MethodPost, tc.path, tc.body)
+		req.Header.Set("Content-Encoding", "gzip")
+		resp := httptest.NewRecorder()
+		m.ServeHTTP(resp, req)
+		assert.Check(t, is.Equal(resp.Code, tc.expected), "%s: %s", tc.path, resp.Body.String())
+		if tc.response != "" {
+			assert.Check(t, is.Equal(resp.Body.String(), tc.response))
+		}
+	}
+}
+
+func TestBodyValidator(t *testing.T) {
+	validate := func(ctx context.Context, body []byte) error {
+		var v map[string]string
+		if err := json.Unmarshal(body, &v); err != nil {
+			return err
+		}
+		if v["Name"] == "" {
+			return errors.New("missing Name")
+		}
+		if v["Name"] == "missing" {
+			return errdefs.NotFound(errors.New("no such object"))
+		}
+		return nil
+	}
+	var called bool
+	readBody := func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		called = true
+		var v map[string]string
+		if err := httputils.ReadJSON(r, &v); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, v["Name"])
+		return err
+	}
+
+	srv := &Server{cfg: &Config{MaxRequestBodyBytes: 64}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewPostRoute("/create", readBody, router.WithJSONBody(), router.WithBodyValidator(validate)),
+	}})
+	m := srv.createMux()
+
+	tests := []struct {
+		body     string
+		expected int
+		called   bool
+	}{
+		{body: `{"Name":"foo"}`, expected: http.StatusOK, called: true},
+		{body: `{}`, expected: http.StatusBadRequest},
+		{body: `{"Name":`, expected: http.StatusBadRequest},
+		{body: `{"Name":"missing"}`, expected: http.StatusNotFound},
+		{body: `{"Name":"` + strings.Repeat("a", 64) + `"}`, expected: http.StatusRequestEntityTooLarge},
+	}
+	for _, tc := range tests {
+		called = false
+		req := httptest.NewRequest(http.MethodPost, "/create", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		m.ServeHTTP(resp, req)
+		assert.Check(t, is.Equal(resp.Code, tc.expected), "%s: %s", tc.body, resp.Body.String())
+		assert.Check(t, is.Equal(called, tc.called), tc.body)
+		if tc.called {
+			assert.Check(t, is.Equal(resp.Body.String(), "foo"))
+		}
+	}
+}
+
+func TestExpectContinue(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+
+	upload := func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		if _, err := io.Copy(io.Discard, r.Body); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	srv := &Server{cfg: &Config{MaxRequestBodyBytes: 1024}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewPostRoute("/upload", upload, router.WithStreamingBody()),
+		router.NewPostRoute("/denied", upload, router.WithStreamingBody(), router.WithAuthorization(func(ctx context.Context, path string, vars map[string]string) error {
+			return errors.New("denied")
+		})),
+	}})
+	srv.Accept(l.Addr().String(), l)
+	waitChan := make(chan error, 1)
+	go srv.Wait(waitChan)
+	<-srv.Ready()
+	defer func() {
+		assert.Check(t, srv.Shutdown(context.Background()))
+		assert.Check(t, <-waitChan)
+	}()
+
+	// status returns the status of the first response to a request with
+	// the given path and Content-Length expecting a "100 Continue".
+	status := func(path string, length int) int {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		assert.NilError(t, err)
+		defer conn.Close()
+		_, err = fmt.Fprintf(conn, "POST %s HTTP/1.1\r\nHost: docker\r\nContent-Type: application/x-tar\r\nContent-Length: %d\r\nExpect: 100-continue\r\n\r\n", path, length)
+		assert.NilError(t, err)
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		assert.NilError(t, err)
+		var code int
+		_, err = fmt.Sscanf(line, "HTTP/1.1 %d", &code)
+		assert.NilError(t, err)
+		return code
+	}
+	assert.Check(t, is.Equal(status("/upload", 512), http.StatusContinue))
+	assert.Check(t, is.Equal(status("/upload", 1<<30), http.StatusRequestEntityTooLarge))
+	assert.Check(t, is.Equal(status("/denied", 512), http.StatusForbidden))
+}
+
+func TestBufferResponse(t *testing.T) {
+	write := func(body string, err error) httputils.APIFunc {
+		return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			w.Header().Set("X-Partial", "true")
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			w.WriteHeader(http.StatusOK)
+			_, _ = io.WriteString(w, body)
+			return err
+		}
+	}
+	failure := errdefs.System(errors.New("late failure"))
+	large := strings.Repeat("x", maxBufferedResponseBytes+1)
+	srv := &Server{cfg: &Config{}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/ok", write("done", nil), router.WithBufferedResponse()),
+		router.NewGetRoute("/late", write(`{"partial":`, failure), router.WithBufferedResponse()),
+		router.NewGetRoute("/large", write(large, failure), router.WithBufferedResponse()),
+		router.NewGetRoute("/unbuffered", write(`{"partial":`, failure)),
+	}})
+	m := srv.createMux()
+	get := func(path string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		m.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, path, nil))
+		return resp
+	}
+
+	resp := get("/ok")
+	assert.Check(t, is.Equal(resp.Code, http.StatusOK))
+	assert.Check(t, is.Equal(resp.Body.String(), "done"))
+	assert.Check(t, is.Equal(resp.Header().Get("X-Partial"), "true"))
+	assert.Check(t, resp.Header().Get(httputils.RequestIDHeader) != "")
+
+	resp = get("/late")
+	assert.Check(t, is.Equal(resp.Code, http.StatusInternalServerError))
+	assert.Check(t, is.Equal(strings.TrimSpace(resp.Body.String()), `{"code":"internal","message":"late failure"}`))
+	// the headers set before the failure are kept, except for those of the
+	// discarded body
+	assert.Check(t, is.Equal(resp.Header().Get("X-Partial"), "true"))
+	assert.Check(t, is.Equal(resp.Header().Get("Content-Type"), "application/json"))
+	assert.Check(t, is.Equal(resp.Header().Get("Content-Length"), ""))
+	assert.Check(t, resp.Header().Get(httputils.RequestIDHeader) != "")
+
+	// responses exceeding the limit, and unbuffered responses, are written
+	// before the handler fails.
+	assert.Check(t, is.Equal(get("/large").Code, http.StatusOK))
+	assert.Check(t, is.Equal(get("/unbuffered").Code, http.StatusOK))
+}
+
+func TestETag(t *testing.T) {
+	body := `{"Id":"abc"}`
+	srv := &Server{cfg: &Config{}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/containers/{name:.*}/json", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			if vars["name"] == "missing" {
+				return errdefs.NotFound(errors.New("no such container"))
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, err := io.WriteString(w, body)
+			return err
+		}, router.WithETag()),
+	}})
+	m := srv.createMux()
+	get := func(name, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1.41/containers/"+name+"/json", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		resp := httptest.NewRecorder()
+		m.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := get("web", "")
+	assert.Check(t, is.Equal(resp.Code, http.StatusOK))
+	assert.Check(t, is.Equal(resp.Body.String(), body))
+	etag := resp.Header().Get("ETag")
+	assert.Check(t, is.Equal(etag, httputils.ETag([]byte(body))))
+
+	resp = get("web", etag)
+	assert.Check(t, is.Equal(resp.Code, http.StatusNotModified))
+	assert.Check(t, is.Equal(resp.Body.String(), ""))
+	assert.Check(t, is.Equal(resp.Header().Get("ETag"), etag))
+
+	body = `{"Id":"abc","State":"running"}`
+	resp = get("web", etag)
+	assert.Check(t, is.Equal(resp.Code, http.StatusOK))
+	assert.Check(t, is.Equal(resp.Body.String(), body))
+
+	resp = get("missing", "*")
+	assert.Check(t, is.Equal(resp.Code, http.StatusNotFound))
+	assert.Check(t, is.Equal(resp.Header().Get("ETag"), ""))
+}
+
+func TestUploadRateLimit(t *testing.T) {
+	var limited bool
+	srv := &Server{cfg: &Config{MaxUploadBytesPerSec: 100 << 10}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewPostRoute("/load", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			_, limited = r.Body.(*rateLimitedReader)
+			start := time.Now()
+			n, err := io.Copy(io.Discard, r.Body)
+			assert.Check(t, err)
+			assert.Check(t, is.Equal(n, int64(120<<10)))
+			// the first 100KiB are read immediately, the next 20KiB
+			// take (at least) 200ms.
+			assert.Check(t, time.Since(start) >= 150*time.Millisecond, "body was read in %s", time.Since(start))
+			return nil
+		}, router.WithStreamingBody()),
+		router.NewPostRoute("/create", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			_, limited = r.Body.(*rateLimitedReader)
+			return nil
+		}),
+	}})
+	m := srv.createMux()
+
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/load", strings.NewReader(strings.Repeat("x", 120<<10))))
+	assert.Check(t, limited, "expected the body of a streaming route to be rate-limited")
+
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/create", strings.NewReader("{}")))
+	assert.Check(t, !limited, "expected the body of other routes not to be rate-limited")
+}
+
+func TestUploadRateLimitShared(t *testing.T) {
+	srv := &Server{cfg: &Config{MaxUploadBytesPerSec: 100 << 10}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewPostRoute("/load", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			_, err := io.Copy(io.Discard, r.Body)
+			return err
+		}, router.WithStreamingBody()),
+	}})
+	m := srv.createMux()
+
+	// two concurrent uploads of 60KiB exceed the burst of 100KiB in total,
+	// so that the last 20KiB take (at least) 200ms.
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/load", strings.NewReader(strings.Repeat("x", 60<<10))))
+		}()
+	}
+	wg.Wait()
+	assert.Check(t, time.Since(start) >= 150*time.Millisecond, "bodies were read in %s", time.Since(start))
+}
+
+func TestRequestID(t *testing.T) {
+	var ctxID string
+	srv := &Server{cfg: &Config{}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/id", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			ctxID = httputils.RequestIDFromContext(ctx)
+			return nil
+		}),
+	}})
+	m := srv.createMux()
+
+	req := httptest.NewRequest(http.MethodGet, "/id", nil)
+	resp := httptest.NewRecorder()
+	m.ServeHTTP(resp, req)
+	generated := resp.Header().Get(httputils.RequestIDHeader)
+	assert.Check(t, generated != "")
+	assert.Check(t, is.Equal(ctxID, generated))
+
+	req = httptest.NewRequest(http.MethodGet, "/id", nil)
+	req.Header.Set(httputils.RequestIDHeader, "my-request")
+	resp = httptest.NewRecorder()
+	m.ServeHTTP(resp, req)
+	assert.Check(t, is.Equal(resp.Header().Get(httputils.RequestIDHeader), "my-request"))
+	assert.Check(t, is.Equal(ctxID, "my-request"))
+
+	for _, id := range []string{"my request", "my-request\nlevel=error", "my-request;", "é", strings.Repeat("a", maxRequestIDLength+1)} {
+		req = httptest.NewRequest(http.MethodGet, "/id", nil)
+		req.Header.Set(httputils.RequestIDHeader, id)
+		resp = httptest.NewRecorder()
+		m.ServeHTTP(resp, req)
+		assert.Check(t, resp.Header().Get(httputils.RequestIDHeader) != id, id)
+		assert.Check(t, is.Len(ctxID, 64), id)
+	}
+}
+
+func TestRouteTemplate(t *testing.T) {
+	var tpl string
+	srv := &Server{cfg: &Config{}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/containers/{name:.*}/logs", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			tpl = httputils.RouteTemplateFromContext(ctx)
+			return nil
+		}),
+	}})
+	m := srv.createMux()
+
+	for _, path := range []string{"/containers/foo/logs", "/v1.41/containers/foo/logs"} {
+		tpl = ""
+		m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Check(t, is.Equal(tpl, "/containers/{name:.*}/logs"), path)
+	}
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	srv := &Server{cfg: &Config{}}
+	m := srv.createMux()
+
+	get := func(path string) int {
+		resp := httptest.NewRecorder()
+		m.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, path, nil))
+		return resp.Code
+	}
+
+	assert.Check(t, is.Equal(get(livenessPath), http.StatusOK))
+	assert.Check(t, is.Equal(get(readinessPath), http.StatusServiceUnavailable))
+	assert.Check(t, !srv.Initialized())
+
+	ready := errors.New("still initializing")
+	srv.SetHealthCheck(func() error { return ready })
+	assert.Check(t, !srv.Initialized(), "setting a health check must not mark the daemon as initialized")
+	assert.Check(t, is.Equal(get(readinessPath), http.StatusServiceUnavailable))
+	srv.SetInitialized()
+	assert.Check(t, srv.Initialized())
+
+	ready = nil
+	assert.Check(t, is.Equal(get(livenessPath), http.StatusOK))
+	assert.Check(t, is.Equal(get(readinessPath), http.StatusOK))
+
+	srv.SetDraining(true)
+	assert.Check(t, is.Equal(get(livenessPath), http.StatusOK))
+	assert.Check(t, is.Equal(get(readinessPath), http.StatusServiceUnavailable))
+}
+
+func TestStartupHandler(t *testing.T) {
+	noop := func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		return nil
+	}
+	srv := &Server{cfg: &Config{Version: "0.1omega2", RejectUntilInitialized: true}}
+	h := srv.startupHandler(srv.createMux())
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, httptest.NewRequest(method, path, nil))
+		return resp
+	}
+
+	// the routes are not configured yet while the daemon initializes
+	resp := do(http.MethodGet, "/containers/json")
+	assert.Check(t, is.Equal(resp.Code, http.StatusServiceUnavailable))
+	assert.Check(t, is.Contains(resp.Body.String(), "the daemon is starting"))
+	assert.Check(t, is.Equal(do(http.MethodPost, "/_ping").Code, http.StatusServiceUnavailable))
+	assert.Check(t, is.Equal(do(http.MethodGet, livenessPath).Code, http.StatusOK))
+	assert.Check(t, is.Equal(do(http.MethodGet, readinessPath).Code, http.StatusServiceUnavailable))
+	for _, path := range []string{"/_ping", "/v1.41/_ping"} {
+		resp = do(http.MethodGet, path)
+		assert.Check(t, is.Equal(resp.Code, http.StatusOK), path)
+		assert.Check(t, is.Equal(resp.Body.String(), "OK"), path)
+		assert.Check(t, is.Equal(resp.Header().Get("API-Version"), api.DefaultVersion), path)
+	}
+
+	srv.InitRouter(fakeRouter{routes: []router.Route{router.NewGetRoute("/containers/json", noop)}})
+	h = srv.startupHandler(srv.Handler())
+	assert.Check(t, is.Equal(do(http.MethodGet, "/containers/json").Code, http.StatusServiceUnavailable))
+
+	srv.SetHealthCheck(func() error { return nil })
+	assert.Check(t, is.Equal(do(http.MethodGet, "/containers/json").Code, http.StatusServiceUnavailable))
+	srv.SetInitialized()
+	assert.Check(t, is.Equal(do(http.MethodGet, "/containers/json").Code, http.StatusOK))
+	assert.Check(t, is.Equal(do(http.MethodGet, readinessPath).Code, http.StatusOK))
+
+	// a failing health check, such as when shutting down, does not
+	// reject requests as if the daemon was starting again.
+	srv.SetHealthCheck(func() error { return errors.New("daemon is shutting down") })
+	assert.Check(t, is.Equal(do(http.MethodGet, "/containers/json").Code, http.StatusOK))
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	noop := func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		return nil
+	}
+	srv := &Server{cfg: &Config{}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/containers/{name:.*}/json", noop),
+		router.NewPostRoute("/containers/{name:.*}/start", noop),
+		router.NewDeleteRoute("/containers/{name:.*}", noop),
+		router.NewGetRoute("/containers/{name}", noop),
+		router.NewGetRoute("/images/{name:.*}/json", noop),
+		router.NewDeleteRoute("/images/{name:.*}", noop),
+	}})
+	m := srv.createMux()
+
+	tests := []struct {
+		method, path string
+		expected     int
+		allow        string
+	}{
+		{method: http.MethodGet, path: "/containers/foo/json", expected: http.StatusOK},
+		{method: http.MethodPost, path: "/containers/foo/json", expected: http.StatusMethodNotAllowed, allow: "GET"},
+		{method: http.MethodPost, path: "/v1.41/containers/foo/json", expected: http.StatusMethodNotAllowed, allow: "GET"},
+		{method: http.MethodPut, path: "/containers/foo", expected: http.StatusMethodNotAllowed, allow: "DELETE, GET"},
+		{method: http.MethodGet, path: "/containers/foo/start", expected: http.StatusMethodNotAllowed, allow: "POST"},
+		{method: http.MethodGet, path: "/v1.41/containers/foo/jsn", expected: http.StatusNotFound},
+		{method: http.MethodPost, path: "/v1.41/containers/foo/", expected: http.StatusNotFound},
+		{method: http.MethodPost, path: "/images/library/busybox/json", expected: http.StatusMethodNotAllowed, allow: "GET"},
+		{method: http.MethodPut, path: "/images/busybox", expected: http.StatusMethodNotAllowed, allow: "DELETE"},
+		{method: http.MethodPut, path: "/images/library/busybox", expected: http.StatusNotFound},
+		{method: http.MethodGet, path: "/no/such/path", expected: http.StatusNotFound},
+		{method: http.MethodGet, path: "/v1.41/no/such/path", expected: http.StatusNotFound},
+	}
+	for _, tc := range tests {
+		resp := httptest.NewRecorder()
+		m.ServeHTTP(resp, httptest.NewRequest(tc.method, tc.path, nil))
+		assert.Check(t, is.Equal(resp.Code, tc.expected), "%s %s", tc.method, tc.path)
+		assert.Check(t, is.Equal(resp.Header().Get("Allow"), tc.allow), "%s %s", tc.method, tc.path)
+	}
+}
+
+func TestErrorFormat(t *testing.T) {
+	srv := &Server{cfg: &Config{}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/containers/{name}/json", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			return errdefs.NotFound(errors.New("no such container: " + vars["name"]))
+		}),
+	}})
+
+	resp := httptest.NewRecorder()
+	srv.createMux().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/containers/foo/json", nil))
+	assert.Check(t, is.Equal(resp.Code, http.StatusNotFound))
+	assert.Check(t, is.Equal(resp.Header().Get("Content-Type"), "application/json"))
+	assert.Check(t, is.Equal(resp.Body.String(), `{"code":"not_found","message":"no such container: foo"}`+"\n"))
+
+	srv.cfg.ErrorFormat = ErrorFormatProblemJSON
+	resp = httptest.NewRecorder()
+	srv.createMux().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/containers/foo/json", nil))
+	assert.Check(t, is.Equal(resp.Code, http.StatusNotFound))
+	assert.Check(t, is.Equal(resp.Header().Get("Content-Type"), "application/problem+json"))
+	var problem problemDetails
+	assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &problem))
+	assert.Check(t, is.DeepEqual(problem, problemDetails{
+		Type:    "about:blank",
+		Title:   "Not Found",
+		Status:  http.StatusNotFound,
+		Detail:  "no such container: foo",
+		Message: "no such container: foo",
+		Code:    "not_found",
+	}))
+
+	resp = httptest.NewRecorder()
+	srv.createMux().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/v1.22/containers/foo/json", nil))
+	assert.Check(t, is.Equal(resp.Code, http.StatusNotFound))
+	assert.Check(t, is.Equal(resp.Body.String(), "no such container: foo\n"))
+}
+
+func TestNotFoundSuggestions(t *testing.T) {
+	noop := func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		return nil
+	}
+	srv := &Server{cfg: &Config{}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/containers/json", noop),
+		router.NewGetRoute("/containers/{name:.*}/json", noop),
+		router.NewGetRoute("/images/json", noop),
+	}})
+	m := srv.createMux()
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{path: "/containrs/json", expected: "page not found; did you mean /containers/json?"},
+		{path: "/v1.41/containrs/json", expected: "page not found; did you mean /containers/json?"},
+		{path: "/containers/foo/jsn", expected: "page not found; did you mean /containers/foo/json?"},
+		{path: "/no/such/path", expected: "page not found"},
+		{path: "/containers/" + strings.Repeat("a", 20000) + "/jsn", expected: "page not found"},
+	}
+	for _, tc := range tests {
+		resp := httptest.NewRecorder()
+		m.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		assert.Check(t, is.Equal(resp.Code, http.StatusNotFound), tc.path)
+		var body struct{ Message string }
+		assert.Check(t, json.Unmarshal(resp.Body.Bytes(), &body), tc.path)
+		assert.Check(t, is.Equal(body.Message, tc.expected), tc.path)
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	for _, tc := range []struct {
+		a, b     string
+		max      int
+		expected int
+	}{
+		{a: "/containers/json", b: "/containers/json", max: 3, expected: 0},
+		{a: "/containrs/json", b: "/containers/json", max: 3, expected: 1},
+		{a: "/containers/foo/jsn", b: "/containers/foo/json", max: 3, expected: 1},
+		{a: "kitten", b: "sitting", max: 3, expected: 3},
+		{a: "kitten", b: "sitting", max: 2, expected: 3},
+		{a: "/images/json", b: "/containers/json", max: 3, expected: 4},
+		{a: "abc", b: "abcdefgh", max: 3, expected: 4},
+	} {
+		assert.Check(t, is.Equal(levenshtein(tc.a, tc.b, tc.max), tc.expected), "%s %s", tc.a, tc.b)
+	}
+}
+
+func TestRouteAuthorization(t *testing.T) {
+	var calls []string
+	var called bool
+	noop := func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		called = true
+		return nil
+	}
+	authorize := func(ctx context.Context, route string, vars map[string]string) error {
+		calls = append(calls, route)
+		if vars["name"] != "allowed" {
+			return errors.New("exec is not allowed")
+		}
+		return nil
+	}
+
+	srv := &Server{cfg: &Config{}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewPostRoute("/containers/{name:.*}/exec", noop, router.WithAuthorization(authorize)),
+	}})
+	m := srv.createMux()
+
+	resp := httptest.NewRecorder()
+	m.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/v1.41/containers/denied/exec", nil))
+	assert.Check(t, is.Equal(resp.Code, http.StatusForbidden))
+	assert.Check(t, !called)
+
+	resp = httptest.NewRecorder()
+	m.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/v1.41/containers/allowed/exec", nil))
+	assert.Check(t, is.Equal(resp.Code, http.StatusOK))
+	assert.Check(t, called)
+	assert.Check(t, is.DeepEqual(calls, []string{"/containers/{name:.*}/exec", "/containers/{name:.*}/exec"}))
+}
+
+func TestRequestTimeout(t *testing.T) {
+	waitForCancel := func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}
+	}
+	srv := &Server{cfg: &Config{RequestTimeout: 10 * time.Millisecond}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/default", waitForCancel),
+		router.NewGetRoute("/streaming", waitForCancel, router.WithTimeout(router.NoTimeout)),
+	}})
+	m := srv.createMux()
+
+	resp := httptest.NewRecorder()
+	m.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/default", nil))
+	assert.Check(t, is.Equal(resp.Code, http.StatusServiceUnavailable))
+	assert.Check(t, is.Contains(resp.Body.String(), "request timed out after 10ms"))
+
+	resp = httptest.NewRecorder()
+	m.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/streaming", nil))
+	assert.Check(t, is.Equal(resp.Code, http.StatusNoContent))
+}
+
+func TestUpstreamTimeout(t *testing.T) {
+	waitForCancel := func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}
+	}
+	srv := &Server{cfg: &Config{RequestTimeout: time.Minute}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/images/search", waitForCancel, router.WithUpstreamTimeout(10*time.Millisecond)),
+		router.NewGetRoute("/images/json", waitForCancel),
+	}})
+	m := srv.createMux()
+
+	resp := httptest.NewRecorder()
+	m.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/images/search", nil))
+	assert.Check(t, is.Equal(resp.Code, http.StatusGatewayTimeout))
+	assert.Check(t, is.Contains(resp.Body.String(), "timed out after 10ms waiting for an upstream service"))
+
+	resp = httptest.NewRecorder()
+	m.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/images/json", nil))
+	assert.Check(t, is.Equal(resp.Code, http.StatusNoContent))
+}
+
+func TestClientTimeout(t *testing.T) {
+	waitForCancel := func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}
+	}
+	srv := &Server{cfg: &Config{MaxClientTimeout: 20 * time.Millisecond}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/default", waitForCancel),
+		router.NewGetRoute("/streaming", waitForCancel, router.WithTimeout(router.NoTimeout)),
+	}})
+	m := srv.createMux()
+
+	tests := []struct {
+		path     string
+		timeout  string
+		expected int
+		message  string
+	}{
+		{path: "/default", expected: http.StatusNoContent},
+		{path: "/default", timeout: "10ms", expected: http.StatusGatewayTimeout, message: "request timed out after 10ms"},
+		{path: "/default", timeout: "1h", expected: http.StatusGatewayTimeout, message: "request timed out after 20ms"},
+		{path: "/default", timeout: "30", expected: http.StatusBadRequest, message: "must be a positive duration"},
+		{path: "/default", timeout: "-1s", expected: http.StatusBadRequest, message: "must be a positive duration"},
+		{path: "/streaming", timeout: "10ms", expected: http.StatusNoContent},
+	}
+	for _, tc := range tests {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		if tc.timeout != "" {
+			req.Header.Set("X-Docker-Timeout", tc.timeout)
+		}
+		resp := httptest.NewRecorder()
+		m.ServeHTTP(resp, req)
+		assert.Check(t, is.Equal(resp.Code, tc.expected), "%s %s", tc.path, tc.timeout)
+		assert.Check(t, is.Contains(resp.Body.String(), tc.message), "%s %s", tc.path, tc.timeout)
+	}
+}
+
+func TestRouteTable(t *testing.T) {
+	noop := func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		return nil
+	}
+	routes := fakeRouter{routes: []router.Route{
+		router.NewPostRoute("/containers/create", noop),
+		router.NewGetRoute("/containers/json", noop),
+	}}
+
+	srv := &Server{cfg: &Config{}}
+	srv.InitRouter(routes)
+	resp := httptest.NewRecorder()
+	srv.createMux().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/_admin/routes", nil))
+	assert.Check(t, is.Equal(resp.Code, http.StatusNotFound))
+
+	srv = &Server{cfg: &Config{EnableRouteTable: true}}
+	srv.InitRouter(routes)
+	resp = httptest.NewRecorder()
+	srv.createMux().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/_admin/routes", nil))
+	assert.Check(t, is.Equal(resp.Code, http.StatusOK))
+
+	var table []RouteInfo
+	assert.NilError(t, json.NewDecoder(resp.Body).Decode(&table))
+	assert.Check(t, is.DeepEqual(table, srv.Routes()))
+	assert.Check(t, is.DeepEqual(table[:2], []RouteInfo{
+		{Method: http.MethodPost, Path: "/containers/create"},
+		{Method: http.MethodGet, Path: "/containers/json"},
+	}))
+	assert.Check(t, is.Contains(table, RouteInfo{Method: http.MethodGet, Path: "/debug/vars"}))
+
+	// the endpoint is subject to the middlewares of API requests
+	srv.UseMiddleware(denyingMiddleware{})
+	resp = httptest.NewRecorder()
+	srv.createMux().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/_admin/routes", nil))
+	assert.Check(t, is.Equal(resp.Code, http.StatusForbidden))
+}
+
+func TestDeprecatedRoute(t *testing.T) {
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	since := time.Date(2022, time.March, 1, 0, 0, 0, 0, time.UTC)
+	srv := &Server{cfg: &Config{}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/containers/{name:.*}/old", handler, router.WithDeprecation(since, "/containers/{name:.*}/new")),
+		router.NewGetRoute("/containers/{name:.*}/gone", handler, router.WithDeprecation(since, "")),
+		router.NewGetRoute("/containers/{name:.*}/new", handler),
+	}})
+	m := srv.createMux()
+
+	resp := httptest.NewRecorder()
+	m.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/v1.41/containers/foo/old", nil))
+	assert.Check(t, is.Equal(resp.Code, http.StatusNoContent))
+	assert.Check(t, is.Equal(resp.Header().Get("Deprecation"), "@1646092800"))
+	assert.Check(t, is.Equal(resp.Header().Get("Link"), `</v1.41/containers/foo/new>; rel="successor-version"`))
+
+	resp = httptest.NewRecorder()
+	m.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/containers/foo%20bar/old", nil))
+	assert.Check(t, is.Equal(resp.Header().Get("Link"), `</containers/foo%20bar/new>; rel="successor-version"`))
+
+	resp = httptest.NewRecorder()
+	m.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/v1.41/containers/foo/gone", nil))
+	assert.Check(t, is.Equal(resp.Header().Get("Deprecation"), "@1646092800"))
+	assert.Check(t, is.Equal(resp.Header().Get("Link"), ""))
+
+	resp = httptest.NewRecorder()
+	m.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/v1.41/containers/foo/new", nil))
+	assert.Check(t, is.Equal(resp.Header().Get("Deprecation"), ""))
+
+	assert.Check(t, is.DeepEqual(srv.Routes()[:3], []RouteInfo{
+		{Method: http.MethodGet, Path: "/containers/{name:.*}/gone", Deprecated: true},
+		{Method: http.MethodGet, Path: "/containers/{name:.*}/new"},
+		{Method: http.MethodGet, Path: "/containers/{name:.*}/old", Deprecated: true, Replacement: "/containers/{name:.*}/new"},
+	}))
+}
+
+func TestRequestHooks(t *testing.T) {
+	var calls []string
+	srv := &Server{cfg: &Config{}}
+	for _, name := range []string{"first", "second"} {
+		name := name
+		srv.AddRequestHook(func(ctx context.Context, r *http.Request) {
+			calls = append(calls, fmt.Sprintf("%s before %s %s", name, r.URL.Path, httputils.RouteTemplateFromContext(ctx)))
+		}, func(ctx context.Context, r *http.Request, status int) {
+			calls = append(calls, fmt.Sprintf("%s after %s %d", name, r.URL.Path, status))
+		})
+	}
+	srv.AddRequestHook(nil, nil)
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/containers/{name:.*}/json", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			return errdefs.NotFound(errors.New("no such container"))
+		}),
+		router.NewGetRoute("/panic", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			panic("something went wrong")
+		}),
+	}})
+	m := srv.createMux()
+
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1.41/containers/foo/json", nil))
+	assert.Check(t, is.DeepEqual(calls, []string{
+		"first before /v1.41/containers/foo/json /containers/{name:.*}/json",
+		"second before /v1.41/containers/foo/json /containers/{name:.*}/json",
+		"first after /v1.41/containers/foo/json 404",
+		"second after /v1.41/containers/foo/json 404",
+	}))
+
+	calls = nil
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1.41/panic", nil))
+	assert.Check(t, is.DeepEqual(calls[2:], []string{
+		"first after /v1.41/panic 500",
+		"second after /v1.41/panic 500",
+	}))
+}
+
+func TestPreReleaseVersions(t *testing.T) {
+	var vars map[string]string
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request, v map[string]string) error {
+		vars = v
+		return nil
+	}
+	routes := fakeRouter{routes: []router.Route{router.NewGetRoute("/info", handler)}}
+
+	srv := &Server{cfg: &Config{}}
+	srv.InitRouter(routes)
+	resp := httptest.NewRecorder()
+	srv.createMux().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/v1.40-beta/info", nil))
+	assert.Check(t, is.Equal(resp.Code, http.StatusNotFound))
+
+	srv = &Server{cfg: &Config{AllowPreReleaseVersions: true}}
+	srv.InitRouter(routes)
+	m := srv.createMux()
+
+	resp = httptest.NewRecorder()
+	m.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/v1.40-beta/info", nil))
+	assert.Check(t, is.Equal(resp.Code, http.StatusOK))
+	assert.Check(t, is.Equal(vars["version"], "1.40"))
+	assert.Check(t, is.Equal(vars["prerelease"], "-beta"))
+
+	resp = httptest.NewRecorder()
+	m.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/v1.40/info", nil))
+	assert.Check(t, is.Equal(resp.Code, http.StatusOK))
+	assert.Check(t, is.Equal(vars["version"], "1.40"))
+	assert.Check(t, is.Equal(vars["prerelease"], ""))
+}
+
+func TestHandlerPanic(t *testing.T) {
+	srv := &Server{cfg: &Config{}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/panic", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			panic("something went wrong")
+		}),
+	}})
+	m := srv.createMux()
+
+	resp := httptest.NewRecorder()
+	m.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/v1.41/panic", nil))
+	assert.Check(t, is.Equal(resp.Code, http.StatusInternalServerError))
+	assert.Check(t, is.Contains(resp.Body.String(), "internal server error"))
+	assert.Check(t, resp.Header().Get(httputils.RequestIDHeader) != "")
+
+	// the stacks of all goroutines are written to the panic dump directory
+	dir := t.TempDir()
+	srv.cfg.PanicDumpDir = dir
+	resp = httptest.NewRecorder()
+	m.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/v1.41/panic", nil))
+	assert.Check(t, is.Equal(resp.Code, http.StatusInternalServerError))
+	dumps, err := filepath.Glob(filepath.Join(dir, "goroutine-stacks-*.log"))
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(dumps, 1))
+	dump, err := os.ReadFile(dumps[0])
+	assert.NilError(t, err)
+	assert.Check(t, is.Contains(string(dump), "TestHandlerPanic"))
+
+	// but not again within panicDumpInterval
+	resp = httptest.NewRecorder()
+	m.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/v1.41/panic", nil))
+	assert.Check(t, is.Equal(resp.Code, http.StatusInternalServerError))
+	dumps, err = filepath.Glob(filepath.Join(dir, "goroutine-stacks-*.log"))
+	assert.NilError(t, err)
+	assert.Check(t, is.Len(dumps, 1))
+}
+
+func TestHandlerPanicAfterWrite(t *testing.T) {
+	srv := &Server{cfg: &Config{}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/panic", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			_, _ = io.WriteString(w, `{"Id":`)
+			panic("something went wrong")
+		}),
+	}})
+	m := srv.createMux()
+
+	// the response cannot be replaced by an error: its handling is aborted
+	resp := httptest.NewRecorder()
+	func() {
+		defer func() {
+			assert.Check(t, is.Equal(recover(), http.ErrAbortHandler))
+		}()
+		m.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/v1.41/panic", nil))
+	}()
+	assert.Check(t, is.Equal(resp.Code, http.StatusOK))
+	assert.Check(t, is.Equal(resp.Body.String(), `{"Id":`))
+}
+
+func TestPanicDumpLimiter(t *testing.T) {
+	var l panicDumpLimiter
+	now := time.Now()
+	assert.Check(t, l.allow(now))
+	assert.Check(t, !l.allow(now.Add(panicDumpInterval/2)))
+	for i := 1; i < maxPanicDumps; i++ {
+		now = now.Add(panicDumpInterval)
+		assert.Check(t, l.allow(now), i)
+	}
+	assert.Check(t, !l.allow(now.Add(time.Hour)), "expected at most %d dumps", maxPanicDumps)
+}
+
+func TestCORSPreflight(t *testing.T) {
+	noop := func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		return nil
+	}
+	srv := &Server{cfg: &Config{CorsHeaders: "https://dashboard.example.com, https://other.example.com"}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/containers/json", noop),
+		router.NewPostRoute("/containers/create", noop),
+	}})
+	m := srv.createMux()
+
+	preflight := func(path, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, path, nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		resp := httptest.NewRecorder()
+		m.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := preflight("/v1.41/containers/json", "https://other.example.com")
+	assert.Check(t, is.Equal(resp.Code, http.StatusNoContent))
+	assert.Check(t, is.Equal(resp.Header().Get("Access-Control-Allow-Origin"), "https://other.example.com"))
+	assert.Check(t, is.Equal(resp.Header().Get("Access-Control-Allow-Methods"), "GET, OPTIONS"))
+	assert.Check(t, resp.Header().Get("Access-Control-Allow-Headers") != "")
+
+	resp = preflight("/v1.41/containers/json", "https://evil.example.com")
+	assert.Check(t, is.Equal(resp.Code, http.StatusForbidden))
+	assert.Check(t, is.Equal(resp.Header().Get("Access-Control-Allow-Origin"), ""))
+
+	resp = preflight("/v1.41/no/such/path", "https://other.example.com")
+	assert.Check(t, is.Equal(resp.Code, http.StatusNotFound))
+}
+
+func TestReplaceListener(t *testing.T) {
+	l1, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	l2, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+
+	srv := &Server{cfg: &Config{}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/ping", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}),
+	}})
+	srv.Accept("tcp://api", l1)
+
+	waitChan := make(chan error, 1)
+	go srv.Wait(waitChan)
+
+	get := func(l net.Listener) (int, error) {
+		resp, err := http.Get("http://" + l.Addr().String() + "/ping")
+		if err != nil {
+			return 0, err
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	<-srv.Ready()
+	code, err := get(l1)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(code, http.StatusNoContent))
+
+	assert.Check(t, is.ErrorContains(srv.ReplaceListener("tcp://other", l2), "no listener found"))
+	assert.NilError(t, srv.ReplaceListener("tcp://api", l2))
+
+	code, err = get(l2)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(code, http.StatusNoContent))
+	_, err = get(l1)
+	assert.Check(t, err != nil, "expected old listener to be closed")
+
+	assert.Check(t, srv.Shutdown(context.Background()))
+	assert.Check(t, <-waitChan)
+}
+
+func TestReplaceTLSListener(t *testing.T) {
+	l1, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	l2, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+
+	ca := newTestCA(t)
+	srv := &Server{cfg: &Config{}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/ping", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}),
+	}})
+	srv.AcceptTLS("tcp://api", &tls.Config{Certificates: []tls.Certificate{*ca.issue(t, 2)}}, l1)
+
+	waitChan := make(chan error, 1)
+	go srv.Wait(waitChan)
+	defer func() {
+		assert.Check(t, srv.Shutdown(context.Background()))
+		assert.Check(t, <-waitChan)
+	}()
+	<-srv.Ready()
+
+	assert.NilError(t, srv.ReplaceListener("tcp://api", l2))
+
+	// the new listener serves TLS, as the listener it replaced
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec // G402: test certificate without SAN
+	}}
+	resp, err := client.Get("https://" + l2.Addr().String() + "/ping")
+	assert.NilError(t, err)
+	_ = resp.Body.Close()
+	assert.Check(t, is.Equal(resp.StatusCode, http.StatusNoContent))
+	client.CloseIdleConnections()
+}
+
+func TestAddRouter(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+
+	ping := func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	srv := &Server{cfg: &Config{}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{router.NewGetRoute("/ping", ping)}})
+	srv.Accept(l.Addr().String(), l)
+	go srv.Wait(make(chan error, 1))
+	defer srv.Close()
+	<-srv.Ready()
+
+	get := func(path string) int {
+		resp, err := http.Get("http://" + l.Addr().String() + path)
+		assert.NilError(t, err)
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Check(t, is.Equal(get("/plugin/ping"), http.StatusNotFound))
+
+	srv.AddRouter(fakeRouter{routes: []router.Route{router.NewGetRoute("/plugin/ping", ping)}})
+	assert.Check(t, is.Equal(get("/plugin/ping"), http.StatusNoContent))
+	assert.Check(t, is.Equal(get("/ping"), http.StatusNoContent))
+}
+
+func TestHandler(t *testing.T) {
+	ping := func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	srv := &Server{cfg: &Config{}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{router.NewGetRoute("/ping", ping)}})
+
+	m := http.NewServeMux()
+	m.Handle("/docker/", http.StripPrefix("/docker", srv.Handler()))
+	get := func(path string) int {
+		resp := httptest.NewRecorder()
+		m.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, path, nil))
+		return resp.Code
+	}
+	assert.Check(t, is.Equal(get("/docker/v1.41/ping"), http.StatusNoContent))
+	assert.Check(t, is.Equal(get("/docker/plugin/ping"), http.StatusNotFound))
+
+	srv.AddRouter(fakeRouter{routes: []router.Route{router.NewGetRoute("/plugin/ping", ping)}})
+	assert.Check(t, is.Equal(get("/docker/plugin/ping"), http.StatusNoContent))
+}
+
+func TestHandlerChecksRequests(t *testing.T) {
+	srv := &Server{cfg: &Config{MaxPathLength: 32, MaxPathSegments: 4, RejectUntilInitialized: true}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/containers/{name:.*}/json", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}),
+	}})
+	h := srv.Handler()
+	get := func(path string) int {
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, path, nil))
+		return resp.Code
+	}
+	assert.Check(t, is.Equal(get("/v1.41/containers/foo/json"), http.StatusServiceUnavailable))
+
+	srv.SetInitialized()
+	assert.Check(t, is.Equal(get("/v1.41/containers/foo/json"), http.StatusNoContent))
+	assert.Check(t, is.Equal(get("/v1.41/containers/"+strings.Repeat("a", 32)+"/json"), http.StatusRequestURITooLong))
+	assert.Check(t, is.Equal(get("/v1.41/containers/a/b/c/json"), http.StatusBadRequest))
+}
+
+func TestMaxHeaderBytes(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+
+	srv := &Server{cfg: &Config{MaxHeaderBytes: 1024}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/ping", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			w.WriteHeader(http.StatusNoContent)
+			return nil
+		}),
+	}})
+	srv.Accept(l.Addr().String(), l)
+	go srv.Wait(make(chan error, 1))
+	defer srv.Close()
+	<-srv.Ready()
+
+	get := func(header string) int {
+		req, err := http.NewRequest(http.MethodGet, "http://"+l.Addr().String()+"/ping", nil)
+		assert.NilError(t, err)
+		req.Header.Set("X-Padding", header)
+		resp, err := http.DefaultClient.Do(req)
+		assert.NilError(t, err)
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Check(t, is.Equal(get("small"), http.StatusNoContent))
+	// net/http allows some slack on top of MaxHeaderBytes.
+	assert.Check(t, is.Equal(get(strings.Repeat("x", 64<<10)), http.StatusRequestHeaderFieldsTooLarge))
+}
+
+// levelHook records the entries logged at any level.
+type levelHook struct {
+	entries []*logrus.Entry
+}
+
+func (h *levelHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *levelHook) Fire(e *logrus.Entry) error {
+	h.entries = append(h.entries, e)
+	return nil
+}
+
+func TestLogListen(t *testing.T) {
+	logger := logrus.StandardLogger()
+	hooks := logger.ReplaceHooks(logrus.LevelHooks{})
+	defer logger.ReplaceHooks(hooks)
+	defer logger.SetLevel(logger.GetLevel())
+	logger.SetLevel(logrus.DebugLevel)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	defer l.Close()
+	addr := l.Addr().String()
+
+	for _, tc := range []struct {
+		level    string
+		expected []logrus.Level
+	}{
+		{level: "", expected: []logrus.Level{logrus.InfoLevel}},
+		{level: "debug", expected: []logrus.Level{logrus.DebugLevel}},
+		{level: "none"},
+		{level: "invalid", expected: []logrus.Level{logrus.WarnLevel, logrus.InfoLevel}},
+	} {
+		t.Run(tc.level, func(t *testing.T) {
+			hook := &levelHook{}
+			logger.ReplaceHooks(logrus.LevelHooks{})
+			logger.AddHook(hook)
+
+			srv := &Server{cfg: &Config{ListenLogLevel: tc.level}}
+			srv.logListen(srv.newHTTPServer(addr, nil, l))
+
+			var levels []logrus.Level
+			for _, e := range hook.entries {
+				levels = append(levels, e.Level)
+			}
+			assert.Check(t, is.DeepEqual(levels, tc.expected))
+			if n := len(hook.entries); n > 0 {
+				e := hook.entries[n-1]
+				assert.Check(t, is.Equal(e.Message, "API listen on "+addr))
+				assert.Check(t, is.DeepEqual(e.Data, logrus.Fields{"component": "api", "addr": addr, "proto": "tcp", "tls": false}))
+			}
+		})
+	}
+}
+
+func TestJSONBody(t *testing.T) {
+	noop := func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	srv := &Server{cfg: &Config{}}
+	srv.UseMiddleware(middleware.NewVersionMiddleware("0.1omega2", "1.42", api.MinVersion))
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewPostRoute("/containers/create", noop, router.WithJSONBody()),
+		router.NewPostRoute("/build", noop),
+	}})
+	m := srv.createMux()
+
+	for _, tc := range []struct {
+		path        string
+		contentType string
+		body        string
+		expected    int
+	}{
+		{path: "/v1.42/containers/create", contentType: "application/json", body: "{}", expected: http.StatusNoContent},
+		{path: "/v1.42/containers/create", contentType: "application/json; charset=utf-8", body: "{}", expected: http.StatusNoContent},
+		{path: "/v1.42/containers/create", expected: http.StatusNoContent},
+		{path: "/v1.42/containers/create", body: "{}", expected: http.StatusUnsupportedMediaType},
+		{path: "/v1.42/containers/create", contentType: "text/plain", body: "{}", expected: http.StatusUnsupportedMediaType},
+		{path: "/v1.42/containers/create", contentType: "application/", body: "{}", expected: http.StatusUnsupportedMediaType},
+		{path: "/containers/create", contentType: "text/plain", body: "{}", expected: http.StatusUnsupportedMediaType},
+		{path: "/v1.42/build", contentType: "application/x-tar", body: "tar", expected: http.StatusNoContent},
+
+		// API versions before 1.42 return a "400 Bad Request", as before
+		{path: "/v1.41/containers/create", contentType: "application/json", body: "{}", expected: http.StatusNoContent},
+		{path: "/v1.41/containers/create", contentType: "text/plain", body: "{}", expected: http.StatusBadRequest},
+		{path: "/v1.41/containers/create", body: "{}", expected: http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+		if tc.contentType != "" {
+			req.Header.Set("Content-Type", tc.contentType)
+		}
+		resp := httptest.NewRecorder()
+		m.ServeHTTP(resp, req)
+		assert.Check(t, is.Equal(resp.Code, tc.expected), "%s with Content-Type %q", tc.path, tc.contentType)
+	}
+}
+
+func TestConfigEndpoint(t *testing.T) {
+	get := func(srv *Server) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		srv.createMux().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/_admin/config", nil))
+		return resp
+	}
+
+	srv := &Server{cfg: &Config{}}
+	srv.SetConfigSource(func() (interface{}, error) { return map[string]interface{}{"debug": true}, nil })
+	assert.Check(t, is.Equal(get(srv).Code, http.StatusNotFound))
+
+	srv = &Server{cfg: &Config{EnableConfigEndpoint: true}}
+	assert.Check(t, is.Equal(get(srv).Code, http.StatusServiceUnavailable))
+
+	srv.SetConfigSource(func() (interface{}, error) { return map[string]interface{}{"debug": true}, nil })
+	resp := get(srv)
+	assert.Check(t, is.Equal(resp.Code, http.StatusOK))
+	assert.Check(t, is.Equal(strings.TrimSpace(resp.Body.String()), `{"debug":true}`))
+
+	srv.SetConfigSource(func() (interface{}, error) { return nil, errors.New("boom") })
+	assert.Check(t, is.Equal(get(srv).Code, http.StatusInternalServerError))
+}
+
+func TestDisabledEndpoints(t *testing.T) {
+	noop := func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	srv := &Server{cfg: &Config{DisabledEndpoints: []string{"/build", "/containers/{name:.*}/exec", "/exec/", "/debug"}}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewPostRoute("/build", noop),
+		router.NewPostRoute("/build/prune", noop),
+		router.NewPostRoute("/containers/{name:.*}/exec", noop),
+		router.NewPostRoute("/containers/{name:.*}/start", noop),
+		router.NewGetRoute("/containers/{name:.*}/json", noop),
+		router.NewDeleteRoute("/containers/{name:.*}", noop),
+		router.NewPostRoute("/exec/{name:.*}/start", noop),
+		router.NewGetRoute("/executions", noop),
+	}})
+	m := srv.createMux()
+
+	for _, tc := range []struct {
+		method, path string
+		expected     int
+	}{
+		{method: http.MethodPost, path: "/v1.41/build", expected: http.StatusNotFound},
+		{method: http.MethodPost, path: "/build/prune", expected: http.StatusNotFound},
+		{method: http.MethodPost, path: "/v1.41/containers/foo/exec", expected: http.StatusNotFound},
+		{method: http.MethodPost, path: "/containers/foo/exec", expected: http.StatusNotFound},
+		{method: http.MethodPost, path: "/v1.41/containers/foo/start", expected: http.StatusNoContent},
+		{method: http.MethodPost, path: "/v1.41/exec/foo/start", expected: http.StatusNotFound},
+		{method: http.MethodGet, path: "/v1.41/executions", expected: http.StatusNoContent},
+		{method: http.MethodGet, path: "/debug/vars", expected: http.StatusNotFound},
+	} {
+		resp := httptest.NewRecorder()
+		m.ServeHTTP(resp, httptest.NewRequest(tc.method, tc.path, nil))
+		assert.Check(t, is.Equal(resp.Code, tc.expected), "%s %s", tc.method, tc.path)
+		assert.Check(t, is.Equal(resp.Header().Get("Allow"), ""), "%s %s", tc.method, tc.path)
+	}
+
+	assert.Check(t, is.DeepEqual(srv.Routes(), []RouteInfo{
+		{Method: http.MethodDelete, Path: "/containers/{name:.*}"},
+		{Method: http.MethodGet, Path: "/containers/{name:.*}/json"},
+		{Method: http.MethodPost, Path: "/containers/{name:.*}/start"},
+		{Method: http.MethodGet, Path: "/executions"},
+	}))
+}
@@ -0,0 +1,56 @@
+// Package servertest provides helpers to test the API server, its
+// middlewares, and routers, with a server listening on an ephemeral port.
+package servertest // import "github.com/docker/docker/api/server/servertest"
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/docker/docker/api/server"
+	"github.com/docker/docker/api/server/router"
+	"gotest.tools/v3/assert"
+)
+
+// Router is a router.Router serving a fixed set of routes.
+type Router []router.Route
+
+// Routes returns the routes of r.
+func (r Router) Routes() []router.Route {
+	return r
+}
+
+// New starts a server configured with cfg (or the default configuration if
+// cfg is nil), serving routers on an ephemeral port of the loopback
+// interface. See Start.
+func New(t testing.TB, cfg *server.Config, routers ...router.Router) (baseURL string, cleanup func()) {
+	t.Helper()
+	if cfg == nil {
+		cfg = &server.Config{}
+	}
+	return Start(t, server.New(cfg), routers...)
+}
+
+// Start starts srv, serving routers on an ephemeral port of the loopback
+// interface, and returns the base URL of the server (such as
+// "http://127.0.0.1:32768"), and a function shutting the server down, which
+// must be called once the test is done. Middlewares must be added to srv
+// before it is started. The server accepts connections and handles requests
+// as it does in the daemon, through Server.Accept and Server.Wait.
+func Start(t testing.TB, srv *server.Server, routers ...router.Router) (baseURL string, cleanup func()) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+
+	srv.InitRouter(routers...)
+	srv.Accept(l.Addr().String(), l)
+	waitChan := make(chan error, 1)
+	go srv.Wait(waitChan)
+	<-srv.Ready()
+
+	return "http://" + l.Addr().String(), func() {
+		t.Helper()
+		assert.Check(t, srv.Shutdown(context.Background()))
+		assert.Check(t, <-waitChan)
+	}
+}
@@ -0,0 +1,48 @@
+package servertest // import "github.com/docker/docker/api/server/servertest"
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/docker/docker/api/server"
+	"github.com/docker/docker/api/server/router"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+type headerMiddleware struct{}
+
+func (headerMiddleware) WrapHandler(handler func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error) func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		w.Header().Set("X-Test", "wrapped")
+		return handler(ctx, w, r, vars)
+	}
+}
+
+func TestStart(t *testing.T) {
+	srv := server.New(&server.Config{})
+	srv.UseMiddleware(headerMiddleware{})
+	baseURL, cleanup := Start(t, srv, Router{
+		router.NewGetRoute("/hello/{name:.*}", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			_, err := io.WriteString(w, "hello "+vars["name"])
+			return err
+		}),
+	})
+	defer cleanup()
+
+	resp, err := http.Get(baseURL + "/v1.41/hello/world")
+	assert.NilError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	assert.NilError(t, err)
+	assert.Check(t, is.Equal(resp.StatusCode, http.StatusOK))
+	assert.Check(t, is.Equal(string(body), "hello world"))
+	assert.Check(t, is.Equal(resp.Header.Get("X-Test"), "wrapped"))
+
+	resp, err = http.Get(baseURL + "/v1.41/missing")
+	assert.NilError(t, err)
+	resp.Body.Close()
+	assert.Check(t, is.Equal(resp.StatusCode, http.StatusNotFound))
+}
@@ -0,0 +1,133 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ListenerShutdownResult is the result of shutting down the server of a
+// listener. Err is nil if the server shut down successfully.
+type ListenerShutdownResult struct {
+	Addr string
+	Err  error
+}
+
+// ShutdownError is returned when the servers of one or more listeners
+// failed to shut down. Results holds the results of all the listeners,
+// including the ones that shut down successfully.
+type ShutdownError struct {
+	Results []ListenerShutdownResult
+}
+
+// Failed returns the results of the listeners that failed to shut down.
+func (e *ShutdownError) Failed() []ListenerShutdownResult {
+	var failed []ListenerShutdownResult
+	for _, r := range e.Results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+func (e *ShutdownError) Error() string {
+	failed := e.Failed()
+	errs := make([]string, 0, len(failed))
+	for _, r := range failed {
+		errs = append(errs, r.Addr+": "+r.Err.Error())
+	}
+	return fmt.Sprintf("failed to shut down %d of %d API listeners: %s", len(failed), len(e.Results), strings.Join(errs, "; "))
+}
+
+func shutdownServers(ctx context.Context, servers []*HTTPServer) error {
+	return stopServers(servers, func(srv *HTTPServer) error {
+		return srv.Shutdown(ctx)
+	})
+}
+
+func closeServers(servers []*HTTPServer) error {
+	return stopServers(servers, (*HTTPServer).Close)
+}
+
+// stopServers calls stop for each of servers, and returns a *ShutdownError
+// if any of them failed.
+func stopServers(servers []*HTTPServer, stop func(*HTTPServer) error) error {
+	var failed bool
+	results := make([]ListenerShutdownResult, 0, len(servers))
+	for _, srv := range servers {
+		err := stop(srv)
+		if err != nil {
+			failed = true
+		}
+		results = append(results, ListenerShutdownResult{Addr: srv.addr, Err: err})
+	}
+	if failed {
+		return &ShutdownError{Results: results}
+	}
+	return nil
+}
+
+// activeRequest is a request in flight, which is logged if it is abandoned
+// when the server shuts down.
+type activeRequest struct {
+	id     string
+	method string
+	path   string
+	start  time.Time
+}
+
+// activeRequests tracks the requests in flight. Its zero value is ready to
+// use.
+type activeRequests struct {
+	mu       sync.Mutex
+	requests map[*activeRequest]struct{}
+}
+
+// add adds the request r with the given ID, and returns a function removing
+// it, to be called once the request completes.
+func (a *activeRequests) add(id string, r *http.Request) (remove func()) {
+	req := &activeRequest{id: id, method: r.Method, path: r.URL.Path, start: time.Now()}
+	a.mu.Lock()
+	if a.requests == nil {
+		a.requests = make(map[*activeRequest]struct{})
+	}
+	a.requests[req] = struct{}{}
+	a.mu.Unlock()
+	return func() {
+		a.mu.Lock()
+		delete(a.requests, req)
+		a.mu.Unlock()
+	}
+}
+
+// list returns the requests in flight.
+func (a *activeRequests) list() []*activeRequest {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	requests := make([]*activeRequest, 0, len(a.requests))
+	for r := range a.requests {
+		requests = append(requests, r)
+	}
+	return requests
+}
+
+// abandonRequests logs the requests still in flight, and forcibly closes the
+// listeners and connections of servers, abandoning the requests. Hijacked
+// connections (such as those of attach requests) are not closed.
+func (s *Server) abandonRequests(servers []*HTTPServer) {
+	for _, r := range s.requests.list() {
+		log.WithFields(logrus.Fields{
+			"request-id": r.id,
+			"duration":   time.Since(r.start),
+		}).Warnf("Abandoning request %s %s on shutdown", r.method, r.path)
+	}
+	for _, srv := range servers {
+		_ = srv.srv.Close()
+	}
+}
@@ -0,0 +1,33 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"net"
+	"testing"
+
+	"github.com/pkg/errors"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestShutdownError(t *testing.T) {
+	l1, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	l2, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	defer l2.Close()
+
+	srv := &Server{cfg: &Config{}}
+	srv.AcceptBindings(nil, Binding{Addr: "tcp://first", Listener: l1}, Binding{Addr: "tcp://second", Listener: l2})
+
+	// close the first listener, so that closing it again fails.
+	assert.NilError(t, l1.Close())
+	err = closeServers(srv.servers)
+
+	var shutdownErr *ShutdownError
+	assert.Assert(t, errors.As(err, &shutdownErr))
+	assert.Check(t, is.Len(shutdownErr.Results, 2))
+	failed := shutdownErr.Failed()
+	assert.Assert(t, is.Len(failed, 1))
+	assert.Check(t, is.Equal(failed[0].Addr, l1.Addr().String()))
+	assert.Check(t, is.ErrorContains(err, "failed to shut down 1 of 2 API listeners: "+l1.Addr().String()+": "))
+}
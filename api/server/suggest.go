@@ -0,0 +1,182 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api"
+	"github.com/gorilla/mux"
+)
+
+// maxSuggestions is the maximum number of routes suggested in "404 page not
+// found" responses.
+const maxSuggestions = 3
+
+// maxSuggestPathLength is the maximum length of the paths for which routes
+// are suggested, to bound the cost of 404 responses.
+const maxSuggestPathLength = 256
+
+// notFoundHandler returns an http.HandlerFunc that returns a "404 page not
+// found" error, suggesting the paths of the routes in templates that are
+// the closest to the request's path.
+func (s *Server) notFoundHandler(templates []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := r.URL.Path
+		p, versioned := mux.Vars(r)["path"]
+		if versioned {
+			// The path was stripped of its API version prefix.
+			path = "/" + p
+		}
+		suggestions := suggestRoutes(path, templates)
+		if !versioned && s.cfg.RequireVersionedPaths {
+			// Only versioned paths are served: suggest the versioned path
+			// of the route matching path, if any, or of similar routes.
+			for _, tmpl := range templates {
+				if expandTemplate(tmpl, path) == path {
+					suggestions = []string{path}
+					break
+				}
+			}
+			for i := range suggestions {
+				suggestions[i] = "/v" + api.DefaultVersion + suggestions[i]
+			}
+		}
+		s.makeErrorHandler(pageNotFoundError{suggestions: suggestions})(w, r)
+	}
+}
+
+// suggestRoutes returns the paths of the route templates that are similar to
+// path, closest first. The variables of the templates are substituted by the
+// corresponding segments of path, so that "/containrs/foo/json" suggests
+// "/containers/foo/json".
+func suggestRoutes(path string, templates []string) []string {
+	if len(path) > maxSuggestPathLength {
+		return nil
+	}
+	type suggestion struct {
+		path     string
+		distance int
+	}
+	maxDistance := len(path) / 3
+	if maxDistance > 3 {
+		maxDistance = 3
+	}
+	seen := make(map[string]struct{})
+	var suggestions []suggestion
+	for _, tmpl := range templates {
+		candidate := expandTemplate(tmpl, path)
+		if _, ok := seen[candidate]; ok {
+			continue
+		}
+		seen[candidate] = struct{}{}
+		if abs(len(candidate)-len(path)) > maxDistance {
+			continue
+		}
+		if d := levenshtein(path, candidate, maxDistance); d > 0 && d <= maxDistance {
+			suggestions = append(suggestions, suggestion{path: candidate, distance: d})
+		}
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].distance != suggestions[j].distance {
+			return suggestions[i].distance < suggestions[j].distance
+		}
+		return suggestions[i].path < suggestions[j].path
+	})
+	if len(suggestions) > maxSuggestions {
+		suggestions = suggestions[:maxSuggestions]
+	}
+	paths := make([]string, 0, len(suggestions))
+	for _, s := range suggestions {
+		paths = append(paths, s.path)
+	}
+	return paths
+}
+
+// expandTemplate substitutes the variables of the path template tmpl (such
+// as "{name:.*}") by the segments at the same position in path. Templates
+// that do not have as many segments as path are returned unchanged.
+func expandTemplate(tmpl, path string) string {
+	tmplSegments := strings.Split(tmpl, "/")
+	pathSegments := strings.Split(path, "/")
+	if len(tmplSegments) != len(pathSegments) {
+		return tmpl
+	}
+	for i, segment := range tmplSegments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			tmplSegments[i] = pathSegments[i]
+		}
+	}
+	return strings.Join(tmplSegments, "/")
+}
+
+// levenshtein returns the edit distance between a and b, or max+1 if it is
+// larger than max. Only the cells within max of the diagonal are computed,
+// and the computation stops once every cell of a row exceeds max.
+func levenshtein(a, b string, max int) int {
+	if abs(len(a)-len(b)) > max {
+		return max + 1
+	}
+	inf := max + 1
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		if j > max {
+			prev[j] = inf
+		} else {
+			prev[j] = j
+		}
+	}
+	for i := 1; i <= len(a); i++ {
+		lo, hi := i-max, i+max
+		if lo < 1 {
+			lo = 1
+		}
+		if hi > len(b) {
+			hi = len(b)
+		}
+		if i <= max {
+			cur[0] = i
+		} else {
+			cur[0] = inf
+		}
+		if lo > 1 {
+			cur[lo-1] = inf
+		}
+		rowMin := cur[0]
+		for j := lo; j <= hi; j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if d := prev[j] + 1; d < cur[j] {
+				cur[j] = d
+			}
+			if d := cur[j-1] + 1; d < cur[j] {
+				cur[j] = d
+			}
+			if cur[j] > inf {
+				cur[j] = inf
+			}
+			if cur[j] < rowMin {
+				rowMin = cur[j]
+			}
+		}
+		if hi < len(b) {
+			cur[hi+1] = inf
+		}
+		if rowMin > max {
+			return inf
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,76 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	metrics "github.com/docker/go-metrics"
+	"golang.org/x/time/rate"
+)
+
+var (
+	listenerMetricsNS = metrics.NewNamespace("engine", "api", nil)
+
+	acceptedCounter  = listenerMetricsNS.NewLabeledCounter("accepted_connections", "The number of connections accepted by the API listeners with an accept rate limit", "addr")
+	throttledCounter = listenerMetricsNS.NewLabeledCounter("throttled_connections", "The number of connections whose acceptance was delayed by the accept rate limit", "addr")
+)
+
+func init() {
+	metrics.Register(listenerMetricsNS)
+}
+
+// throttledListener accepts connections at a bounded rate: connections
+// beyond the rate wait in the accept queue of the listener until they are
+// accepted, instead of being rejected.
+type throttledListener struct {
+	net.Listener
+	limiter *rate.Limiter
+	addr    string
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// withAcceptRate returns l, accepting connections at the rate set by
+// cfg.MaxAcceptRate, if any.
+func withAcceptRate(l net.Listener, cfg *Config) net.Listener {
+	if cfg.MaxAcceptRate <= 0 {
+		return l
+	}
+	burst := int(math.Ceil(cfg.MaxAcceptRate))
+	return &throttledListener{
+		Listener: l,
+		limiter:  rate.NewLimiter(rate.Limit(cfg.MaxAcceptRate), burst),
+		addr:     l.Addr().String(),
+		closed:   make(chan struct{}),
+	}
+}
+
+func (l *throttledListener) Accept() (net.Conn, error) {
+	r := l.limiter.Reserve()
+	if delay := r.Delay(); delay > 0 {
+		throttledCounter.WithValues(l.addr).Inc()
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-l.closed:
+			t.Stop()
+			r.Cancel()
+			return nil, net.ErrClosed
+		}
+	}
+	c, err := l.Listener.Accept()
+	if err != nil {
+		r.Cancel()
+		return nil, err
+	}
+	acceptedCounter.WithValues(l.addr).Inc()
+	return c, nil
+}
+
+func (l *throttledListener) Close() error {
+	l.closeOnce.Do(func() { close(l.closed) })
+	return l.Listener.Close()
+}
@@ -0,0 +1,50 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestThrottledListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	assert.Check(t, withAcceptRate(l, &Config{}) == l)
+
+	tl := withAcceptRate(l, &Config{MaxAcceptRate: 2})
+	defer tl.Close()
+
+	accept := func() (time.Duration, error) {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		assert.NilError(t, err)
+		defer conn.Close()
+
+		start := time.Now()
+		c, err := tl.Accept()
+		if err == nil {
+			c.Close()
+		}
+		return time.Since(start), err
+	}
+
+	// the first connections, up to the burst, are accepted immediately
+	for i := 0; i < 2; i++ {
+		d, err := accept()
+		assert.NilError(t, err)
+		assert.Check(t, d < 200*time.Millisecond, "connection %d accepted after %s", i, d)
+	}
+	d, err := accept()
+	assert.NilError(t, err)
+	assert.Check(t, d >= 300*time.Millisecond, "connection accepted after %s", d)
+
+	// closing the listener interrupts throttled calls to Accept
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		tl.Close()
+	}()
+	_, err = tl.Accept()
+	assert.Check(t, is.ErrorIs(err, net.ErrClosed))
+}
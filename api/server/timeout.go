@@ -0,0 +1,144 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/docker/docker/api/server/httputils"
+	"github.com/docker/docker/errdefs"
+	"github.com/pkg/errors"
+)
+
+type requestTimeoutError struct {
+	timeout time.Duration
+}
+
+func (e requestTimeoutError) Error() string {
+	return fmt.Sprintf("request timed out after %s", e.timeout)
+}
+
+func (requestTimeoutError) HTTPStatusCode() int {
+	return http.StatusServiceUnavailable
+}
+
+// clientTimeoutHeader is the header of requests setting the maximum
+// duration of the request, as a Go duration, such as "30s".
+const clientTimeoutHeader = "X-Docker-Timeout"
+
+type clientTimeoutError struct {
+	timeout time.Duration
+}
+
+func (e clientTimeoutError) Error() string {
+	return fmt.Sprintf("request timed out after %s, as set by the %s header", e.timeout, clientTimeoutHeader)
+}
+
+func (clientTimeoutError) HTTPStatusCode() int {
+	return http.StatusGatewayTimeout
+}
+
+type upstreamTimeoutError struct {
+	timeout time.Duration
+}
+
+func (e upstreamTimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s waiting for an upstream service", e.timeout)
+}
+
+func (upstreamTimeoutError) HTTPStatusCode() int {
+	return http.StatusGatewayTimeout
+}
+
+// timeoutHandler returns a handler that cancels the context of handler once
+// timeout expires. If the handler did not write a response by then, the
+// request fails with a "503 Service Unavailable" status.
+//
+// Handlers are expected to observe the cancellation of their context, and
+// return; the request is not abandoned while the handler runs.
+func timeoutHandler(handler httputils.APIFunc, timeout time.Duration) httputils.APIFunc {
+	return deadlineHandler(handler, timeout, requestTimeoutError{timeout: timeout})
+}
+
+// upstreamTimeoutHandler is like timeoutHandler, but fails requests with a
+// "504 Gateway Timeout" status, for timeouts of the dependencies of the
+// daemon, such as a registry.
+func upstreamTimeoutHandler(handler httputils.APIFunc, timeout time.Duration) httputils.APIFunc {
+	return deadlineHandler(handler, timeout, upstreamTimeoutError{timeout: timeout})
+}
+
+// clientTimeoutHandler is like timeoutHandler, but for the timeout set by
+// the client in the X-Docker-Timeout header of the request, if any, which is
+// clamped to maxTimeout. Requests exceeding it fail with a "504 Gateway
+// Timeout" status, and requests with an invalid header with a "400 Bad
+// Request".
+func clientTimeoutHandler(handler httputils.APIFunc, maxTimeout time.Duration) httputils.APIFunc {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		v := r.Header.Get(clientTimeoutHeader)
+		if v == "" {
+			return handler(ctx, w, r, vars)
+		}
+		timeout, err := time.ParseDuration(v)
+		if err != nil || timeout <= 0 {
+			return errdefs.InvalidParameter(errors.Errorf("invalid %s header %q: must be a positive duration, such as 30s", clientTimeoutHeader, v))
+		}
+		if timeout > maxTimeout {
+			timeout = maxTimeout
+		}
+		return deadlineHandler(handler, timeout, clientTimeoutError{timeout: timeout})(ctx, w, r, vars)
+	}
+}
+
+// deadlineHandler returns a handler that cancels the context of handler once
+// timeout expires, returning timeoutErr if the handler did not write a
+// response by then.
+func deadlineHandler(handler httputils.APIFunc, timeout time.Duration, timeoutErr error) httputils.APIFunc {
+	return func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		tw := &timeoutWriter{ResponseWriter: w}
+		err := handler(ctx, tw, r.WithContext(ctx), vars)
+		if !tw.written && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return timeoutErr
+		}
+		return err
+	}
+}
+
+// timeoutWriter records whether a response was written. It forwards
+// http.Flusher and http.Hijacker to the wrapped ResponseWriter.
+type timeoutWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.written = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.written = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher.
+func (w *timeoutWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker.
+func (w *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response does not implement http.Hijacker")
+	}
+	w.written = true
+	return h.Hijack()
+}
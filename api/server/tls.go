@@ -0,0 +1,360 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/http2"
+)
+
+// tlsVersions are the TLS versions that can be used as minimum TLS version.
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ValidateTLSOptions validates the MinTLSVersion and TLSCipherSuites of the
+// configuration.
+func (cfg *Config) ValidateTLSOptions() error {
+	if _, err := parseTLSVersion(cfg.MinTLSVersion); err != nil {
+		return err
+	}
+	_, err := parseCipherSuites(cfg.TLSCipherSuites)
+	return err
+}
+
+// applyTLSOptions sets the minimum TLS version and the cipher suites of
+// tlsConfig. If the minimum version is not configured, TLS 1.2 is used
+// (unless tlsConfig already requires a higher one). If the cipher suites are
+// not configured, the cipher suites of tlsConfig are left unchanged. TLS 1.3
+// cipher suites are not configurable.
+func applyTLSOptions(tlsConfig *tls.Config, minVersion string, cipherSuites []string) error {
+	version, err := parseTLSVersion(minVersion)
+	if err != nil {
+		return err
+	}
+	suites, err := parseCipherSuites(cipherSuites)
+	if err != nil {
+		return err
+	}
+	if version != 0 {
+		tlsConfig.MinVersion = version
+	} else if tlsConfig.MinVersion < tls.VersionTLS12 {
+		tlsConfig.MinVersion = tls.VersionTLS12
+	}
+	if suites != nil {
+		tlsConfig.CipherSuites = suites
+	}
+	return nil
+}
+
+func parseTLSVersion(version string) (uint16, error) {
+	if version == "" {
+		return 0, nil
+	}
+	v, ok := tlsVersions[version]
+	if !ok {
+		return 0, errors.Errorf("invalid minimum TLS version %q: must be one of 1.2, 1.3", version)
+	}
+	return v, nil
+}
+
+func parseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	secure := make(map[string]uint16)
+	var valid []string
+	for _, c := range tls.CipherSuites() {
+		secure[c.Name] = c.ID
+		valid = append(valid, c.Name)
+	}
+	insecure := make(map[string]struct{})
+	for _, c := range tls.InsecureCipherSuites() {
+		insecure[c.Name] = struct{}{}
+	}
+	sort.Strings(valid)
+
+	suites := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := secure[name]
+		if !ok {
+			if _, ok := insecure[name]; ok {
+				return nil, errors.Errorf("cipher suite %s is insecure, and not supported", name)
+			}
+			return nil, errors.Errorf("unknown cipher suite %s: supported cipher suites are %s", name, strings.Join(valid, ", "))
+		}
+		suites = append(suites, id)
+	}
+	return suites, nil
+}
+
+// requireClientCANames configures tlsConfig to require and verify a client
+// certificate, and to reject any connection for which the common name of
+// the verified client certificate is not in names.
+func requireClientCANames(tlsConfig *tls.Config, names []string) {
+	allowed := make(map[string]struct{}, len(names))
+	for _, n := range names {
+		allowed[n] = struct{}{}
+	}
+
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	verify := tlsConfig.VerifyPeerCertificate
+	tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		if verify != nil {
+			if err := verify(rawCerts, verifiedChains); err != nil {
+				return err
+			}
+		}
+		return verifyClientCommonName(allowed, verifiedChains)
+	}
+}
+
+func verifyClientCommonName(allowed map[string]struct{}, verifiedChains [][]*x509.Certificate) error {
+	var cn string
+	for _, chain := range verifiedChains {
+		if len(chain) == 0 {
+			continue
+		}
+		cn = chain[0].Subject.CommonName
+		if _, ok := allowed[cn]; ok {
+			return nil
+		}
+	}
+	if cn == "" {
+		return errors.New("client certificate has no common name")
+	}
+	return errors.Errorf("client certificate common name %q is not allowed", cn)
+}
+
+// tlsFileReloader provides the certificate and client CA pool of a TLS
+// configuration from files on disk, and reloads them when the modification
+// time of the files changes. If reloading fails, the last successfully
+// loaded certificate and CA pool remain in use.
+type tlsFileReloader struct {
+	certFile, keyFile, caFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	caPool  *x509.CertPool
+	modTime map[string]time.Time
+}
+
+func newTLSFileReloader(certFile, keyFile, caFile string) *tlsFileReloader {
+	return &tlsFileReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		caFile:   caFile,
+		modTime:  make(map[string]time.Time),
+	}
+}
+
+// configure sets up tlsConfig to obtain its certificate, and client CA pool,
+// from the reloader.
+func (r *tlsFileReloader) configure(tlsConfig *tls.Config) {
+	tlsConfig.Certificates = nil
+	tlsConfig.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return r.certificate()
+	}
+	if r.caFile == "" {
+		return
+	}
+	base := tlsConfig.Clone()
+	tlsConfig.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		if err := r.reload(); err != nil {
+			return nil, err
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		c := base.Clone()
+		c.ClientCAs = r.caPool
+		return c, nil
+	}
+}
+
+// certificate returns the certificate, reloading it if its files changed.
+func (r *tlsFileReloader) certificate() (*tls.Certificate, error) {
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cert, nil
+}
+
+// reload reloads the files that changed since they were last loaded. It only
+// returns an error if no certificate could be loaded at all; errors reloading
+// changed files are logged, and the previously loaded files remain in use.
+func (r *tlsFileReloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.reloadCertificate(); err != nil {
+		if r.cert == nil {
+			return err
+		}
+		log.WithError(err).Warn("failed to reload TLS certificate; continuing to use the previous certificate")
+	}
+	if r.caFile != "" {
+		if err := r.reloadCAPool(); err != nil {
+			if r.caPool == nil {
+				return err
+			}
+			log.WithError(err).Warn("failed to reload TLS CA certificates; continuing to use the previous CA certificates")
+		}
+	}
+	return nil
+}
+
+func (r *tlsFileReloader) reloadCertificate() error {
+	certMod, certChanged, err := r.changed(r.certFile)
+	if err != nil {
+		return err
+	}
+	keyMod, keyChanged, err := r.changed(r.keyFile)
+	if err != nil {
+		return err
+	}
+	if !certChanged && !keyChanged && r.cert != nil {
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to load TLS certificate")
+	}
+	r.cert = &cert
+	r.modTime[r.certFile] = certMod
+	r.modTime[r.keyFile] = keyMod
+	return nil
+}
+
+func (r *tlsFileReloader) reloadCAPool() error {
+	mod, changed, err := r.changed(r.caFile)
+	if err != nil {
+		return err
+	}
+	if !changed && r.caPool != nil {
+		return nil
+	}
+	pem, err := os.ReadFile(r.caFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to read TLS CA certificates")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return errors.Errorf("failed to append certificates from PEM file: %q", r.caFile)
+	}
+	r.caPool = pool
+	r.modTime[r.caFile] = mod
+	return nil
+}
+
+// changed returns the modification time of file, and whether it differs
+// from the modification time when the file was last loaded.
+func (r *tlsFileReloader) changed(file string) (time.Time, bool, error) {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return time.Time{}, false, errors.Wrap(err, "failed to stat TLS file")
+	}
+	return fi.ModTime(), !fi.ModTime().Equal(r.modTime[file]), nil
+}
+
+// CertPaths are the paths of a TLS certificate and of its key.
+type CertPaths struct {
+	CertFile string
+	KeyFile  string
+}
+
+// configureSNICertificates sets up tlsConfig to select the certificate
+// presented to clients by the server name they request using SNI, among
+// certs, which maps server names (such as "docker.example.com", or
+// "*.example.com" to match any subdomain) to the certificates to present.
+// The certificates are reloaded when their files change, as for the default
+// certificate of tlsConfig, which is presented to clients requesting other
+// server names, or not using SNI.
+func configureSNICertificates(tlsConfig *tls.Config, certs map[string]CertPaths) {
+	reloaders := make(map[string]*tlsFileReloader, len(certs))
+	for name, paths := range certs {
+		r := newTLSFileReloader(paths.CertFile, paths.KeyFile, "")
+		if err := r.reload(); err != nil {
+			// A reloader without a certificate would fail the handshake of
+			// every client requesting this name: present the default
+			// certificate instead.
+			log.WithError(err).WithField("server-name", name).Error("failed to load TLS files; presenting the default certificate for this server name")
+			continue
+		}
+		reloaders[strings.ToLower(name)] = r
+	}
+
+	defaultCertificate := tlsConfig.GetCertificate
+	getCertificate := func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		if r := lookupSNIReloader(reloaders, hello.ServerName); r != nil {
+			return r.certificate()
+		}
+		if defaultCertificate != nil {
+			return defaultCertificate(hello)
+		}
+		// fall back to tlsConfig.Certificates
+		return nil, nil
+	}
+	tlsConfig.GetCertificate = getCertificate
+	if getConfigForClient := tlsConfig.GetConfigForClient; getConfigForClient != nil {
+		// Configurations returned for a client (such as those of the
+		// tlsFileReloader) must select the certificate in the same way.
+		tlsConfig.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			c, err := getConfigForClient(hello)
+			if c != nil {
+				c.GetCertificate = getCertificate
+			}
+			return c, err
+		}
+	}
+}
+
+// lookupSNIReloader returns the reloader of the certificate for serverName,
+// matching wildcard names for the first label of serverName, or nil if
+// there is none.
+func lookupSNIReloader(reloaders map[string]*tlsFileReloader, serverName string) *tlsFileReloader {
+	name := strings.ToLower(strings.TrimSuffix(serverName, "."))
+	if name == "" {
+		return nil
+	}
+	if r, ok := reloaders[name]; ok {
+		return r
+	}
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		return reloaders["*"+name[i:]]
+	}
+	return nil
+}
+
+// configureHTTP2 configures srv to serve HTTP/2 on TLS connections, and
+// returns a copy of tlsConfig advertising "h2" using ALPN.
+func configureHTTP2(srv *http.Server, tlsConfig *tls.Config) (*tls.Config, error) {
+	srv.TLSConfig = tlsConfig.Clone()
+	if err := http2.ConfigureServer(srv, &http2.Server{}); err != nil {
+		srv.TLSConfig, srv.TLSNextProto = nil, nil
+		return nil, err
+	}
+	h2Config := srv.TLSConfig
+	if getConfigForClient := h2Config.GetConfigForClient; getConfigForClient != nil {
+		// Configurations returned for a client (such as those of the
+		// tlsFileReloader) must advertise the same protocols.
+		nextProtos := h2Config.NextProtos
+		h2Config.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			c, err := getConfigForClient(hello)
+			if c != nil {
+				c.NextProtos = nextProtos
+			}
+			return c, err
+		}
+	}
+	return h2Config, nil
+}
@@ -0,0 +1,192 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/server/router"
+	"golang.org/x/net/http2"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestRequireClientCANames(t *testing.T) {
+	tlsConfig := &tls.Config{}
+	requireClientCANames(tlsConfig, []string{"operator-1", "operator-2"})
+	assert.Equal(t, tlsConfig.ClientAuth, tls.RequireAndVerifyClientCert)
+
+	chain := func(cn string) [][]*x509.Certificate {
+		return [][]*x509.Certificate{{{Subject: pkix.Name{CommonName: cn}}}}
+	}
+
+	assert.Check(t, tlsConfig.VerifyPeerCertificate(nil, chain("operator-2")))
+	assert.Check(t, is.Error(tlsConfig.VerifyPeerCertificate(nil, chain("intruder")), `client certificate common name "intruder" is not allowed`))
+	assert.Check(t, is.Error(tlsConfig.VerifyPeerCertificate(nil, nil), "client certificate has no common name"))
+}
+
+func writeTestCertificate(t *testing.T, certFile, keyFile, cn string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.NilError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NilError(t, err)
+	assert.NilError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	assert.NilError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+}
+
+func TestTLSFileReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	writeTestCertificate(t, certFile, keyFile, "first")
+
+	tlsConfig := &tls.Config{}
+	newTLSFileReloader(certFile, keyFile, "").configure(tlsConfig)
+
+	commonName := func() string {
+		t.Helper()
+		cert, err := tlsConfig.GetCertificate(nil)
+		assert.NilError(t, err)
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		assert.NilError(t, err)
+		return leaf.Subject.CommonName
+	}
+	assert.Check(t, is.Equal(commonName(), "first"))
+
+	writeTestCertificate(t, certFile, keyFile, "second")
+	future := time.Now().Add(time.Minute)
+	assert.NilError(t, os.Chtimes(certFile, future, future))
+	assert.NilError(t, os.Chtimes(keyFile, future, future))
+	assert.Check(t, is.Equal(commonName(), "second"))
+
+	// A broken certificate keeps the previous one in use.
+	assert.NilError(t, os.WriteFile(certFile, []byte("garbage"), 0o600))
+	future = future.Add(time.Minute)
+	assert.NilError(t, os.Chtimes(certFile, future, future))
+	assert.Check(t, is.Equal(commonName(), "second"))
+}
+
+func TestSNICertificates(t *testing.T) {
+	dir := t.TempDir()
+	writeCert := func(name string) CertPaths {
+		paths := CertPaths{
+			CertFile: filepath.Join(dir, name+"-cert.pem"),
+			KeyFile:  filepath.Join(dir, name+"-key.pem"),
+		}
+		writeTestCertificate(t, paths.CertFile, paths.KeyFile, name)
+		return paths
+	}
+	def := writeCert("default")
+
+	tlsConfig := &tls.Config{}
+	newTLSFileReloader(def.CertFile, def.KeyFile, "").configure(tlsConfig)
+	configureSNICertificates(tlsConfig, map[string]CertPaths{
+		"docker.example.com": writeCert("docker"),
+		"*.example.org":      writeCert("wildcard"),
+		"missing.example.com": {
+			CertFile: filepath.Join(dir, "missing-cert.pem"),
+			KeyFile:  filepath.Join(dir, "missing-key.pem"),
+		},
+	})
+
+	for serverName, expected := range map[string]string{
+		"docker.example.com":  "docker",
+		"Docker.Example.COM.": "docker",
+		"api.example.org":     "wildcard",
+		"example.org":         "default",
+		"a.b.example.org":     "default",
+		"other.example.com":   "default",
+		"missing.example.com": "default",
+		"":                    "default",
+	} {
+		cert, err := tlsConfig.GetCertificate(&tls.ClientHelloInfo{ServerName: serverName})
+		assert.NilError(t, err)
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		assert.NilError(t, err)
+		assert.Check(t, is.Equal(leaf.Subject.CommonName, expected), "server name %q", serverName)
+	}
+}
+
+func TestHTTP2(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	writeTestCertificate(t, certFile, keyFile, "server")
+
+	srv := New(&Config{TLSCertFile: certFile, TLSKeyFile: keyFile, EnableHTTP2: true})
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/ping", func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+			_, err := w.Write([]byte(r.Proto))
+			return err
+		}),
+	}})
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	srv.AcceptTLS("tcp://api", srv.cfg.TLSConfig, l)
+
+	waitChan := make(chan error, 1)
+	go srv.Wait(waitChan)
+	defer srv.Close()
+	<-srv.Ready()
+
+	client := &http.Client{Transport: &http2.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec // G402: self-signed test certificate
+	}}
+	defer client.CloseIdleConnections()
+	resp, err := client.Get("https://" + l.Addr().String() + "/ping")
+	assert.NilError(t, err)
+	defer resp.Body.Close()
+	assert.Check(t, is.Equal(resp.ProtoMajor, 2))
+}
+
+func TestApplyTLSOptions(t *testing.T) {
+	// the cipher suites set on tlsConfig are kept if none are configured
+	configured := []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA}
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS10, CipherSuites: configured}
+	assert.NilError(t, applyTLSOptions(tlsConfig, "", nil))
+	assert.Check(t, is.Equal(tlsConfig.MinVersion, uint16(tls.VersionTLS12)))
+	assert.Check(t, is.DeepEqual(tlsConfig.CipherSuites, configured))
+
+	assert.NilError(t, applyTLSOptions(tlsConfig, "1.3", []string{"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"}))
+	assert.Check(t, is.Equal(tlsConfig.MinVersion, uint16(tls.VersionTLS13)))
+	assert.Check(t, is.DeepEqual(tlsConfig.CipherSuites, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384}))
+
+	cfg := &Config{MinTLSVersion: "1.1"}
+	assert.Check(t, is.Error(cfg.ValidateTLSOptions(), `invalid minimum TLS version "1.1": must be one of 1.2, 1.3`))
+	cfg = &Config{TLSCipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}}
+	assert.Check(t, is.Error(cfg.ValidateTLSOptions(), "cipher suite TLS_RSA_WITH_RC4_128_SHA is insecure, and not supported"))
+	cfg = &Config{TLSCipherSuites: []string{"NO_SUCH_CIPHER"}}
+	assert.Check(t, is.ErrorContains(cfg.ValidateTLSOptions(), "unknown cipher suite NO_SUCH_CIPHER: supported cipher suites are "))
+}
+
+func TestInvalidTLSOptions(t *testing.T) {
+	// the server does not start serving with invalid TLS options, rather
+	// than serving with other options than the configured ones.
+	tlsConfig := &tls.Config{}
+	srv := New(&Config{TLSConfig: tlsConfig, MinTLSVersion: "1.1"})
+	assert.Check(t, is.Equal(tlsConfig.MinVersion, uint16(tls.VersionTLS12)))
+	waitChan := make(chan error, 1)
+	srv.Wait(waitChan)
+	assert.Check(t, is.Error(<-waitChan, `invalid API server configuration: invalid TLS configuration: invalid minimum TLS version "1.1": must be one of 1.2, 1.3`))
+}
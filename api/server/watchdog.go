@@ -0,0 +1,40 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/docker/docker/api/server/httputils"
+	"github.com/sirupsen/logrus"
+)
+
+// watchSlowRequest logs a warning, with the stacks of all goroutines, if
+// the handling of r takes longer than threshold, to surface handlers that
+// are stuck, for example, waiting for a lock. It does not cancel the
+// request. The returned function must be called once the handling of r
+// completes.
+func watchSlowRequest(r *http.Request, requestID string, threshold time.Duration) (stop func() bool) {
+	start := time.Now()
+	t := time.AfterFunc(threshold, func() {
+		log.WithFields(logrus.Fields{
+			"request-id": requestID,
+			"route":      httputils.RouteTemplateFromContext(r.Context()),
+			"duration":   time.Since(start),
+			"stack":      string(goroutineStacks()),
+		}).Warnf("Handler for %s %s is taking longer than %s", r.Method, r.URL.Path, threshold)
+	})
+	return t.Stop
+}
+
+// goroutineStacks returns the stacks of all goroutines.
+func goroutineStacks() []byte {
+	buf := make([]byte, 16384)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
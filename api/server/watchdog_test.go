@@ -0,0 +1,67 @@
+package server // import "github.com/docker/docker/api/server"
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/server/httputils"
+	"github.com/docker/docker/api/server/router"
+	"github.com/sirupsen/logrus"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+// entryHook sends the warnings that are logged on a channel.
+type entryHook chan *logrus.Entry
+
+func (h entryHook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.WarnLevel}
+}
+
+func (h entryHook) Fire(e *logrus.Entry) error {
+	h <- e
+	return nil
+}
+
+func TestSlowRequestWatchdog(t *testing.T) {
+	hook := make(entryHook, 1)
+	logger := logrus.StandardLogger()
+	hooks := logger.ReplaceHooks(logrus.LevelHooks{})
+	defer logger.ReplaceHooks(hooks)
+	logger.AddHook(hook)
+
+	var warning *logrus.Entry
+	waitForWarning := func(ctx context.Context, w http.ResponseWriter, r *http.Request, vars map[string]string) error {
+		select {
+		case warning = <-hook:
+		case <-time.After(200 * time.Millisecond):
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+	srv := &Server{cfg: &Config{SlowRequestThreshold: 10 * time.Millisecond}}
+	srv.InitRouter(fakeRouter{routes: []router.Route{
+		router.NewGetRoute("/containers/{name:.*}/json", waitForWarning),
+		router.NewGetRoute("/streaming", waitForWarning, router.WithTimeout(router.NoTimeout)),
+	}})
+	m := srv.createMux()
+
+	req := httptest.NewRequest(http.MethodGet, "/containers/foo/json", nil)
+	req.Header.Set(httputils.RequestIDHeader, "slow-request")
+	resp := httptest.NewRecorder()
+	m.ServeHTTP(resp, req)
+	assert.Check(t, is.Equal(resp.Code, http.StatusNoContent))
+	assert.Assert(t, warning != nil, "no warning logged for slow request")
+	assert.Check(t, is.Equal(warning.Data["request-id"], "slow-request"))
+	assert.Check(t, is.Equal(warning.Data["route"], "/containers/{name:.*}/json"))
+	assert.Check(t, is.Contains(warning.Data["stack"], "TestSlowRequestWatchdog"))
+
+	warning = nil
+	resp = httptest.NewRecorder()
+	m.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/streaming", nil))
+	assert.Check(t, is.Equal(resp.Code, http.StatusNoContent))
+	assert.Check(t, warning == nil, "warning logged for streaming request")
+}
@@ -7,6 +7,9 @@ package types
 // swagger:model ErrorResponse
 type ErrorResponse struct {
 
+	// A stable, machine-readable code identifying the kind of error, such as `not_found`, `conflict`, or `invalid_parameter`.
+	Code string `json:"code,omitempty"`
+
 	// The error message.
 	// Required: true
 	Message string `json:"message"`
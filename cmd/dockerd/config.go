@@ -38,6 +38,11 @@ func installCommonConfigFlags(conf *config.Config, flags *pflag.FlagSet) error {
 	flags.Var(opts.NewNamedListOptsRef("storage-opts", &conf.GraphOptions, nil), "storage-opt", "Storage driver options")
 	flags.Var(opts.NewNamedListOptsRef("authorization-plugins", &conf.AuthorizationPlugins, nil), "authorization-plugin", "Authorization plugins to load")
 	flags.Var(opts.NewNamedListOptsRef("exec-opts", &conf.ExecOptions, nil), "exec-opt", "Runtime execution options")
+	flags.Var(opts.NewNamedListOptsRef("tls-allowed-cns", &conf.TLSAllowedCNs, nil), "tls-allowed-cn", "Allowed common names of client certificates")
+	flags.StringVar(&conf.TLSMinVersion, "tls-min-version", "", "Minimum TLS version accepted by the API (1.2 or 1.3) (default 1.2)")
+	flags.Var(opts.NewNamedListOptsRef("tls-cipher-suites", &conf.TLSCipherSuites, nil), "tls-cipher-suite", "Cipher suites allowed for TLS 1.2 connections to the API (default ECDHE key exchange with AES-GCM or ChaCha20-Poly1305 ciphers)")
+	flags.StringVar(&conf.TLSOCSPResponder, "tls-ocsp-responder", "", "URL of the OCSP responder queried for the OCSP response stapled to the TLS certificate")
+	flags.StringVar(&conf.TLSOCSPStapleFile, "tls-ocsp-staple-file", "", "Path to a DER-encoded OCSP response stapled to the TLS certificate")
 	flags.StringVarP(&conf.Pidfile, "pidfile", "p", conf.Pidfile, "Path to use for daemon PID file")
 	flags.StringVar(&conf.Root, "data-root", conf.Root, "Root directory of persistent Docker state")
 	flags.StringVar(&conf.ExecRoot, "exec-root", conf.ExecRoot, "Root directory for execution state files")
@@ -63,10 +68,23 @@ func installCommonConfigFlags(conf *config.Config, flags *pflag.FlagSet) error {
 	flags.IntVar(&conf.MaxConcurrentUploads, "max-concurrent-uploads", config.DefaultMaxConcurrentUploads, "Set the max concurrent uploads for each push")
 	flags.IntVar(&conf.MaxDownloadAttempts, "max-download-attempts", config.DefaultDownloadAttempts, "Set the max download attempts for each pull")
 	flags.IntVar(&conf.ShutdownTimeout, "shutdown-timeout", config.DefaultShutdownTimeout, "Set the default shutdown timeout")
+	flags.IntVar(&conf.APIReadTimeout, "api-read-timeout", 0, "Set the timeout (in seconds) for reading an entire API request")
+	flags.IntVar(&conf.APIReadHeaderTimeout, "api-read-header-timeout", 0, "Set the timeout (in seconds) for reading API request headers")
+	flags.IntVar(&conf.APIWriteTimeout, "api-write-timeout", 0, "Set the timeout (in seconds) for writing an API response")
+	flags.IntVar(&conf.APIIdleTimeout, "api-idle-timeout", 0, "Set the timeout (in seconds) for idle keep-alive API connections")
+	flags.IntVar(&conf.APIMaxHeaderBytes, "api-max-header-bytes", 0, "Set the maximum size (in bytes) of API request headers (default 1 MB)")
+	flags.BoolVar(&conf.APIAccessLog, "api-access-log", false, "Log every request handled by the API")
+	flags.StringVar(&conf.APIAccessLogFormat, "api-access-log-format", "text", `Set the format of the API access log ("text"|"json")`)
+	flags.IntVar(&conf.APIAccessLogSampleRate, "api-access-log-sample-rate", 0, "Only log one in the given number of successful API requests in the access log")
+	flags.IntVar(&conf.APIAccessLogSlowThreshold, "api-access-log-slow-threshold", 0, "Always log API requests taking longer than the given duration (in milliseconds) in the access log")
+	flags.BoolVar(&conf.APIConfigEndpoint, "api-config-endpoint", false, "Enable the API endpoint returning the effective daemon configuration, with secrets redacted")
+	flags.BoolVar(&conf.APIServeDuringStartup, "api-serve-during-startup", false, "Serve the API while the daemon initializes, rejecting requests other than health checks and pings until it is ready")
 
 	flags.StringVar(&conf.SwarmDefaultAdvertiseAddr, "swarm-default-advertise-addr", "", "Set default address or interface for swarm advertised address")
 	flags.BoolVar(&conf.Experimental, "experimental", false, "Enable experimental features")
 	flags.StringVar(&conf.MetricsAddress, "metrics-addr", "", "Set default address and port to serve the metrics api on")
+	flags.BoolVar(&conf.MetricsHealth, "metrics-health", false, "Also serve the health endpoints on the metrics address")
+	flags.BoolVar(&conf.MetricsProfiler, "metrics-profiler", false, "Also serve the profiler endpoints on the metrics address")
 	flags.Var(opts.NewNamedListOptsRef("node-generic-resources", &conf.NodeGenericResources, opts.ValidateSingleGenericResource), "node-generic-resource", "Advertise user-defined resource")
 
 	flags.StringVar(&conf.ContainerdNamespace, "containerd-namespace", config.DefaultContainersNamespace, "Containerd namespace to use")
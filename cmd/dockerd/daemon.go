@@ -3,12 +3,15 @@ package main
 import (
 	"context"
 	"crypto/tls"
+	"encoding/json"
 	"fmt"
 	"net"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -67,7 +70,18 @@ type DaemonCli struct {
 
 	api             *apiserver.Server
 	d               *daemon.Daemon
-	authzMiddleware *authorization.Middleware // authzMiddleware enables to dynamically reload the authorization plugins
+	authzMiddleware *authorization.Middleware  // authzMiddleware enables to dynamically reload the authorization plugins
+	corsMiddleware  *middleware.CORSMiddleware // corsMiddleware enables to dynamically reload the CORS headers
+
+	apiReloadable      apiserver.ReloadableConfig // apiReloadable holds the reloadable settings the API server currently uses
+	upgradeConn        *os.File                   // upgradeConn connects to the process the daemon is being upgraded to, if any
+	apiShutdownTimeout time.Duration              // apiShutdownTimeout bounds the time the API server waits for in-flight requests on shutdown
+	apiDrainGrace      time.Duration              // apiDrainGrace is the time the API server drains for before shutting down on SIGINT or SIGTERM
+
+	// OnLifecycleEvent, if set, is called with the lifecycle events of the
+	// daemon, such as when it is ready, or shutting down. It is called
+	// synchronously, and must not block.
+	OnLifecycleEvent func(LifecycleEvent)
 }
 
 // NewDaemonCli returns a daemon CLI
@@ -89,6 +103,9 @@ func (cli *DaemonCli) start(opts *daemonOptions) (err error) {
 	if err != nil {
 		return err
 	}
+	serverConfig.PanicDumpDir = opts.PanicDumpDir
+	serverConfig.ProfilerWarmup = opts.ProfilerWarmup
+	serverConfig.ProfilerWarmupCPUProfile = opts.ProfilerWarmupCPUProfile
 
 	if opts.Validate {
 		// If config wasn't OK we wouldn't have made it this far.
@@ -96,13 +113,22 @@ func (cli *DaemonCli) start(opts *daemonOptions) (err error) {
 		return nil
 	}
 
+	cli.emitLifecycleEvent(LifecycleInitializing)
+	defer cli.emitLifecycleEvent(LifecycleStopped)
+
 	configureProxyEnv(cli.Config)
 	configureDaemonLogs(cli.Config)
 
 	logrus.Info("Starting up")
 
+	if err := waitForUpgrade(); err != nil {
+		return err
+	}
+
 	cli.configFile = &opts.configFile
 	cli.flags = opts.flags
+	cli.apiShutdownTimeout = opts.APIShutdownTimeout
+	cli.apiDrainGrace = opts.APIDrainGracePeriod
 
 	if cli.Config.Debug {
 		debug.Enable()
@@ -165,11 +191,23 @@ func (cli *DaemonCli) start(opts *daemonOptions) (err error) {
 	}
 
 	cli.api = apiserver.New(serverConfig)
+	cli.api.SetConfigSource(func() (interface{}, error) {
+		return cli.Config.Redacted()
+	})
+	cli.apiReloadable = apiserver.ReloadableConfig{
+		CorsHeaders:       serverConfig.CorsHeaders,
+		ReadTimeout:       serverConfig.ReadTimeout,
+		ReadHeaderTimeout: serverConfig.ReadHeaderTimeout,
+		WriteTimeout:      serverConfig.WriteTimeout,
+		IdleTimeout:       serverConfig.IdleTimeout,
+		RequestTimeout:    serverConfig.RequestTimeout,
+	}
 
 	hosts, err := loadListeners(cli, serverConfig)
 	if err != nil {
 		return errors.Wrap(err, "failed to load listeners")
 	}
+	cli.emitLifecycleEvent(LifecycleListening)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	waitForContainerDShutdown, err := cli.initContainerD(ctx)
@@ -186,6 +224,7 @@ func (cli *DaemonCli) start(opts *daemonOptions) (err error) {
 	defer close(stopc)
 
 	trap.Trap(func() {
+		cli.drain()
 		cli.stop()
 		<-stopc // wait for daemonCli.start() to return
 	}, logrus.StandardLogger())
@@ -199,6 +238,18 @@ func (cli *DaemonCli) start(opts *daemonOptions) (err error) {
 		logrus.Fatalf("Error creating middlewares: %v", err)
 	}
 
+	// The serve API routine never exits unless an error occurs
+	// We need to start it as a goroutine and wait on it so
+	// daemon doesn't exit. With --api-serve-during-startup, it is
+	// started before the daemon is initialized, and requests are
+	// rejected until then (see apiserver.Config.RejectUntilInitialized).
+	// Otherwise, connections wait in the backlog of the listeners
+	// until the daemon is initialized.
+	serveAPIWait := make(chan error)
+	if serverConfig.RejectUntilInitialized {
+		go cli.api.Wait(serveAPIWait)
+	}
+
 	d, err := daemon.NewDaemon(ctx, cli.Config, pluginStore)
 	if err != nil {
 		return errors.Wrap(err, "failed to start daemon")
@@ -213,8 +264,12 @@ func (cli *DaemonCli) start(opts *daemonOptions) (err error) {
 
 	cli.d = d
 
-	if err := startMetricsServer(cli.Config.MetricsAddress); err != nil {
-		return errors.Wrap(err, "failed to start metrics server")
+	// The metrics listener is served by the API server, on the address set
+	// as serverConfig.MetricsAddr.
+	if addr := cli.Config.MetricsAddress; addr != "" {
+		if err := allocateDaemonPort(addr); err != nil {
+			return errors.Wrap(err, "failed to start metrics server")
+		}
 	}
 
 	c, err := createAndStartCluster(cli, d)
@@ -237,19 +292,21 @@ func (cli *DaemonCli) start(opts *daemonOptions) (err error) {
 	routerOptions.cluster = c
 
 	initRouter(routerOptions)
+	cli.api.SetHealthCheck(func() error { return nil })
+	cli.api.SetInitialized()
 
 	go d.ProcessClusterNotifications(ctx, c.GetWatchStream())
 
 	cli.setupConfigReloadTrap()
+	cli.setupUpgradeTrap()
 
-	// The serve API routine never exits unless an error occurs
-	// We need to start it as a goroutine and wait on it so
-	// daemon doesn't exit
-	serveAPIWait := make(chan error)
-	go cli.api.Wait(serveAPIWait)
+	if !serverConfig.RejectUntilInitialized {
+		go cli.api.Wait(serveAPIWait)
+	}
 
 	// after the daemon is done setting up we can notify systemd api
 	notifyReady()
+	cli.emitLifecycleEvent(LifecycleReady)
 
 	// Daemon is fully initialized and handling API traffic
 	// Wait for serve API to complete
@@ -258,6 +315,7 @@ func (cli *DaemonCli) start(opts *daemonOptions) (err error) {
 
 	// notify systemd that we're shutting down
 	notifyStopping()
+	cli.emitLifecycleEvent(LifecycleShuttingDown)
 	shutdownDaemon(d)
 
 	// Stop notification processing and any background processes
@@ -338,6 +396,12 @@ func (cli *DaemonCli) reloadConfig() {
 			return
 		}
 
+		if c.IsValueSet("log-level") {
+			if lvl, err := logrus.ParseLevel(c.LogLevel); err == nil {
+				logrus.SetLevel(lvl)
+			}
+		}
+
 		if c.IsValueSet("debug") {
 			debugEnabled := debug.IsEnabled()
 			switch {
@@ -347,6 +411,8 @@ func (cli *DaemonCli) reloadConfig() {
 				debug.Enable()
 			}
 		}
+
+		cli.reloadAPIServer(c)
 	}
 
 	if err := config.Reload(*cli.configFile, cli.flags, reload); err != nil {
@@ -354,8 +420,137 @@ func (cli *DaemonCli) reloadConfig() {
 	}
 }
 
+// nonReloadableAPIOptions are the options of the API server that are only
+// applied when the daemon starts.
+var nonReloadableAPIOptions = []string{
+	"hosts",
+	"tls",
+	"tlsverify",
+	"tlscacert",
+	"tlscert",
+	"tlskey",
+	"group",
+	"socket-mode",
+	"socket-user",
+	"api-access-log",
+	"api-access-log-format",
+	"api-access-log-sample-rate",
+	"api-access-log-slow-threshold",
+	"api-config-endpoint",
+	"api-serve-during-startup",
+	"api-max-header-bytes",
+	"tls-allowed-cns",
+	"tls-min-version",
+	"tls-cipher-suites",
+	"tls-ocsp-responder",
+	"tls-ocsp-staple-file",
+	"metrics-health",
+	"metrics-profiler",
+}
+
+// reloadAPIServer applies the reloadable API server options set in c, and
+// reports the options whose new value is ignored until the daemon restarts.
+func (cli *DaemonCli) reloadAPIServer(c *config.Config) {
+	rc := cli.apiReloadable
+	if c.IsValueSet("api-cors-header") {
+		if cli.corsMiddleware == nil {
+			if c.CorsHeaders != "" {
+				logrus.Warn("Ignoring api-cors-header: enabling CORS requires a restart of the daemon")
+			}
+		} else {
+			cli.corsMiddleware.SetHeaders(c.CorsHeaders)
+			rc.CorsHeaders = c.CorsHeaders
+		}
+	}
+	if c.IsValueSet("api-read-timeout") {
+		rc.ReadTimeout = time.Duration(c.APIReadTimeout) * time.Second
+	}
+	if c.IsValueSet("api-read-header-timeout") {
+		rc.ReadHeaderTimeout = time.Duration(c.APIReadHeaderTimeout) * time.Second
+	}
+	if c.IsValueSet("api-write-timeout") {
+		rc.WriteTimeout = time.Duration(c.APIWriteTimeout) * time.Second
+	}
+	if c.IsValueSet("api-idle-timeout") {
+		rc.IdleTimeout = time.Duration(c.APIIdleTimeout) * time.Second
+	}
+	if rc != cli.apiReloadable {
+		cli.api.Reload(rc)
+		cli.apiReloadable = rc
+	}
+
+	for _, name := range changedOptions(cli.Config, c, nonReloadableAPIOptions) {
+		logrus.Warnf("Ignoring %s: changing it requires a restart of the daemon", name)
+	}
+}
+
+// changedOptions returns the options of names that are set in newConfig
+// with a value different from their value in conf.
+func changedOptions(conf, newConfig *config.Config, names []string) []string {
+	current := make(map[string]interface{})
+	if b, err := json.Marshal(conf); err == nil {
+		_ = json.Unmarshal(b, &current)
+	}
+	var changed []string
+	for _, name := range names {
+		v, ok := newConfig.ValuesSet[name]
+		if !ok {
+			continue
+		}
+		cur, ok := current[name]
+		if !ok {
+			// omitted from conf because it has its zero value
+			if v == nil || reflect.ValueOf(v).IsZero() {
+				continue
+			}
+		} else if reflect.DeepEqual(cur, v) {
+			continue
+		}
+		changed = append(changed, name)
+	}
+	return changed
+}
+
+// drain sets the API server to draining, and waits for the drain grace
+// period, so that load balancers and orchestrators stop sending requests to
+// the daemon before it is shut down.
+func (cli *DaemonCli) drain() {
+	if cli.apiDrainGrace <= 0 {
+		return
+	}
+	logrus.Infof("Draining the API server for %v before shutting down", cli.apiDrainGrace)
+	cli.api.SetDraining(true)
+	time.Sleep(cli.apiDrainGrace)
+}
+
 func (cli *DaemonCli) stop() {
-	cli.api.Close()
+	cli.api.SetHealthCheck(func() error { return errors.New("daemon is shutting down") })
+	// The shutdown must be bounded: streaming requests (such as "docker
+	// events", or "docker logs -f") only end once the daemon shuts down,
+	// which happens once the API server is shut down. The connections of
+	// the requests still in flight at the deadline are closed.
+	timeout := cli.apiShutdownTimeout
+	if timeout <= 0 {
+		timeout = defaultAPIShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := cli.api.Shutdown(ctx)
+	var shutdownErr *apiserver.ShutdownError
+	if errors.As(err, &shutdownErr) {
+		for _, r := range shutdownErr.Results {
+			if r.Err != nil {
+				logrus.WithError(r.Err).WithField("listener", r.Addr).Error("Failed to shut down API listener")
+			} else {
+				logrus.WithField("listener", r.Addr).Debug("API listener shut down")
+			}
+		}
+		logrus.Errorf("Failed to shut down %d of %d API listeners", len(shutdownErr.Failed()), len(shutdownErr.Results))
+		return
+	}
+	if err != nil {
+		logrus.WithError(err).Error("Failed to shut down the API server")
+	}
 }
 
 // shutdownDaemon just wraps daemon.Shutdown() to handle a timeout in case
@@ -576,19 +771,80 @@ func (cli *DaemonCli) initMiddlewares(s *apiserver.Server, cfg *apiserver.Config
 	v := cfg.Version
 
 	exp := middleware.NewExperimentalMiddleware(cli.Config.Experimental)
-	s.UseMiddleware(exp)
+	s.UseMiddleware(middleware.WithName("experimental", exp))
 
 	vm := middleware.NewVersionMiddleware(v, api.DefaultVersion, api.MinVersion)
-	s.UseMiddleware(vm)
+	s.UseMiddleware(middleware.WithName("version", vm))
+
+	if cfg.MinAPIVersion != "" || cfg.DeprecatedAPIVersion != "" {
+		s.UseMiddleware(middleware.WithName("deprecation", middleware.NewDeprecationMiddleware(cfg.MinAPIVersion, cfg.DeprecatedAPIVersion)))
+	}
+	if cfg.MaxAPIVersion != "" {
+		s.UseMiddleware(middleware.WithName("max-version", middleware.NewMaxVersionMiddleware(cfg.MaxAPIVersion)))
+	}
 
 	if cfg.CorsHeaders != "" {
-		c := middleware.NewCORSMiddleware(cfg.CorsHeaders)
-		s.UseMiddleware(c)
+		cli.corsMiddleware = middleware.NewCORSMiddleware(cfg.CorsHeaders)
+		s.UseMiddleware(middleware.WithName("cors", cli.corsMiddleware))
 	}
 
 	cli.authzMiddleware = authorization.NewMiddleware(cli.Config.AuthorizationPlugins, pluginStore)
 	cli.Config.AuthzMiddleware = cli.authzMiddleware
-	s.UseMiddleware(cli.authzMiddleware)
+	s.UseMiddleware(middleware.WithName("authz", cli.authzMiddleware))
+
+	s.UseMiddleware(middleware.WithName("drain", middleware.NewDrainMiddleware(s.Draining, cfg.DrainAllowlist)))
+
+	if cfg.MaxConcurrentRequests > 0 {
+		s.UseMiddleware(middleware.WithName("concurrency-limit", middleware.NewConcurrencyLimitMiddleware(cfg.MaxConcurrentRequests, cfg.RequestQueueTimeout)))
+	}
+
+	if cfg.CircuitBreakerThreshold > 0 {
+		s.UseMiddleware(middleware.WithName("circuit-breaker", middleware.NewCircuitBreakerMiddleware(cfg.CircuitBreakerGroups, cfg.CircuitBreakerThreshold, cfg.CircuitBreakerCooldown)))
+	}
+
+	if cfg.RateLimit > 0 {
+		s.UseMiddleware(middleware.WithName("rate-limit", middleware.NewRateLimitMiddleware(cfg.RateLimit, cfg.RateLimitBurst, true)))
+	}
+
+	if len(cfg.AllowedHosts) > 0 {
+		s.UseMiddleware(middleware.WithName("host", middleware.NewHostMiddleware(cfg.AllowedHosts)))
+	}
+
+	if len(cfg.AllowedCIDRs) > 0 || len(cfg.DeniedCIDRs) > 0 {
+		ipf, err := middleware.NewIPFilterMiddleware(cfg.AllowedCIDRs, cfg.DeniedCIDRs)
+		if err != nil {
+			return err
+		}
+		s.UseMiddleware(middleware.WithName("ip-filter", ipf))
+	}
+
+	if cfg.EnableCompression {
+		s.UseMiddleware(middleware.WithName("compression", middleware.NewCompressionMiddleware(middleware.DefaultCompressionMinSize)))
+	}
+
+	s.UseMiddleware(middleware.WithName("metrics", middleware.NewMetricsMiddleware()))
+
+	if cfg.Logging {
+		s.UseMiddleware(middleware.WithName("access-log", middleware.NewSampledAccessLogMiddleware(cfg.AccessLogFormat, middleware.AccessLogSampling{
+			Rate:          cfg.AccessLogSampleRate,
+			SlowThreshold: cfg.AccessLogSlowThreshold,
+			SampleErrors:  cfg.AccessLogSampleErrors,
+		})))
+	}
+
+	if len(cfg.ResponseHeaders) > 0 {
+		s.UseMiddleware(middleware.WithName("response-headers", middleware.NewResponseHeadersMiddleware(cfg.ResponseHeaders)))
+	}
+
+	if cfg.EnableTracing {
+		s.UseMiddleware(middleware.WithName("tracing", middleware.NewTracingMiddleware(nil)))
+	}
+
+	if cfg.TLSConfig != nil {
+		// Added last, so that the subject of client certificates is
+		// available to the access log and metrics middlewares.
+		s.UseMiddleware(middleware.WithName("client-cert", middleware.NewClientCertMiddleware()))
+	}
 	return nil
 }
 
@@ -613,10 +869,39 @@ func (cli *DaemonCli) getContainerdDaemonOpts() ([]supervisor.DaemonOpt, error)
 
 func newAPIServerConfig(config *config.Config) (*apiserver.Config, error) {
 	serverConfig := &apiserver.Config{
-		SocketGroup: config.SocketGroup,
-		Version:     dockerversion.Version,
-		CorsHeaders: config.CorsHeaders,
+		SocketGroup:       config.SocketGroup,
+		SocketUser:        config.SocketUser,
+		Version:           dockerversion.Version,
+		CorsHeaders:       config.CorsHeaders,
+		ReadTimeout:       time.Duration(config.APIReadTimeout) * time.Second,
+		ReadHeaderTimeout: time.Duration(config.APIReadHeaderTimeout) * time.Second,
+		WriteTimeout:      time.Duration(config.APIWriteTimeout) * time.Second,
+		IdleTimeout:       time.Duration(config.APIIdleTimeout) * time.Second,
+		MaxHeaderBytes:    config.APIMaxHeaderBytes,
+		Logging:           config.APIAccessLog,
+		AccessLogFormat:   config.APIAccessLogFormat,
+		MetricsAddr:       config.MetricsAddress,
+		// The health and profiler endpoints can be served on the metrics
+		// address, to probe and profile the daemon with the API firewalled.
+		MetricsListenerHealth:   config.MetricsHealth,
+		MetricsListenerProfiler: config.MetricsProfiler,
+		// The effective configuration is returned by the "/_admin/config"
+		// endpoint if enabled, to help diagnose configuration issues.
+		EnableConfigEndpoint: config.APIConfigEndpoint,
+		// Failing requests are always logged, regardless of the sample rate.
+		AccessLogSampleRate:    config.APIAccessLogSampleRate,
+		AccessLogSlowThreshold: time.Duration(config.APIAccessLogSlowThreshold) * time.Millisecond,
+		// If enabled, the API is served while the daemon initializes, so
+		// that it can be probed, but requests are rejected until it is
+		// initialized.
+		RejectUntilInitialized: config.APIServeDuringStartup,
+	}
+
+	socketMode, err := config.GetSocketMode()
+	if err != nil {
+		return nil, err
 	}
+	serverConfig.SocketMode = socketMode
 
 	if config.TLS != nil && *config.TLS {
 		tlsOptions := tlsconfig.Options{
@@ -635,11 +920,41 @@ func newAPIServerConfig(config *config.Config) (*apiserver.Config, error) {
 			return nil, errors.Wrap(err, "invalid TLS configuration")
 		}
 		serverConfig.TLSConfig = tlsConfig
+		serverConfig.TLSCertFile = tlsOptions.CertFile
+		serverConfig.TLSKeyFile = tlsOptions.KeyFile
+		if tlsOptions.ClientAuth == tls.RequireAndVerifyClientCert {
+			serverConfig.TLSCAFile = tlsOptions.CAFile
+		}
+		serverConfig.ClientCANames = config.TLSAllowedCNs
+		serverConfig.MinTLSVersion = config.TLSMinVersion
+		serverConfig.TLSCipherSuites = config.TLSCipherSuites
+		if len(serverConfig.TLSCipherSuites) == 0 {
+			serverConfig.TLSCipherSuites = defaultTLSCipherSuites
+		}
+		serverConfig.OCSPResponderURL = config.TLSOCSPResponder
+		serverConfig.OCSPStapleFile = config.TLSOCSPStapleFile
+	}
+	if err := serverConfig.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid API server configuration")
 	}
 
 	return serverConfig, nil
 }
 
+// defaultTLSCipherSuites are the cipher suites allowed for TLS 1.2
+// connections to the API if --tls-cipher-suite is not set: ECDHE key
+// exchange with AEAD ciphers. This is stricter than the cipher suites of
+// tlsconfig.Server, which dockerd accepted before: clients only supporting
+// the CBC cipher suites need them to be allowed with --tls-cipher-suite.
+var defaultTLSCipherSuites = []string{
+	"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+	"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+	"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+	"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+	"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256",
+	"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256",
+}
+
 // checkTLSAuthOK checks basically for an explicitly disabled TLS/TLSVerify
 // Going forward we do not want to support a scenario where dockerd listens
 //   on TCP without either TLS client auth (or an explicit opt-in to disable it)
@@ -669,6 +984,7 @@ func loadListeners(cli *DaemonCli, serverConfig *apiserver.Config) ([]string, er
 	if len(cli.Config.Hosts) == 0 {
 		return nil, errors.New("no hosts configured")
 	}
+	warnUnusedActivatedSockets(cli.Config.Hosts)
 	var hosts []string
 
 	for i := 0; i < len(cli.Config.Hosts); i++ {
@@ -719,7 +1035,15 @@ func loadListeners(cli *DaemonCli, serverConfig *apiserver.Config) ([]string, er
 				}
 			}
 		}
-		ls, err := listeners.Init(proto, addr, serverConfig.SocketGroup, serverConfig.TLSConfig)
+		// TLS is configured per listener by the API server, so that TCP
+		// sockets can be served with TLS, and unix sockets in plain-text.
+		ls, err := listeners.Init(proto, addr, listeners.SocketOptions{
+			Group:     serverConfig.SocketGroup,
+			User:      serverConfig.SocketUser,
+			Mode:      serverConfig.SocketMode,
+			Backlog:   serverConfig.TCPBacklog,
+			ReusePort: serverConfig.TCPReusePort,
+		}, nil)
 		if err != nil {
 			return nil, err
 		}
@@ -731,12 +1055,31 @@ func loadListeners(cli *DaemonCli, serverConfig *apiserver.Config) ([]string, er
 		}
 		logrus.Debugf("Listener created for HTTP on %s (%s)", proto, addr)
 		hosts = append(hosts, protoAddrParts[1])
-		cli.api.Accept(addr, ls...)
+		if proto == "tcp" || proto == "fd" {
+			cli.api.AcceptTLS(addr, serverConfig.TLSConfig, ls...)
+		} else {
+			cli.api.Accept(addr, ls...)
+		}
 	}
 
 	return hosts, nil
 }
 
+// warnUnusedActivatedSockets logs a warning if the daemon was started using
+// systemd socket activation, but none of the configured hosts uses the
+// sockets passed by systemd ("fd://"), in which case they are left unused.
+func warnUnusedActivatedSockets(hosts []string) {
+	if os.Getenv("LISTEN_FDS") == "" || os.Getenv("LISTEN_PID") != strconv.Itoa(os.Getpid()) {
+		return
+	}
+	for _, h := range hosts {
+		if strings.HasPrefix(h, "fd://") {
+			return
+		}
+	}
+	logrus.Warn("The daemon was started with socket activation, but no host is configured to use the activated sockets; use -H fd:// to listen on them")
+}
+
 func createAndStartCluster(cli *DaemonCli, d *daemon.Daemon) (*cluster.Cluster, error) {
 	name, _ := os.Hostname()
 
@@ -14,6 +14,11 @@ func notifyReady() {
 func notifyStopping() {
 }
 
+// notifyMainPID tells the host that the process with the given pid is now
+// the main process of the daemon, when upgrading the daemon binary
+func notifyMainPID(pid int) {
+}
+
 func validateCPURealtimeOptions(_ *config.Config) error {
 	return nil
 }
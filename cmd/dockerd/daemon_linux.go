@@ -1,6 +1,8 @@
 package main
 
 import (
+	"strconv"
+
 	cdcgroups "github.com/containerd/cgroups"
 	systemdDaemon "github.com/coreos/go-systemd/v22/daemon"
 	"github.com/docker/docker/daemon/config"
@@ -23,6 +25,12 @@ func notifyStopping() {
 	go systemdDaemon.SdNotify(false, systemdDaemon.SdNotifyStopping)
 }
 
+// notifyMainPID tells the host that the process with the given pid is now
+// the main process of the daemon, when upgrading the daemon binary
+func notifyMainPID(pid int) {
+	_, _ = systemdDaemon.SdNotify(false, "MAINPID="+strconv.Itoa(pid))
+}
+
 func validateCPURealtimeOptions(config *config.Config) error {
 	if config.CPURealtimePeriod == 0 && config.CPURealtimeRuntime == 0 {
 		return nil
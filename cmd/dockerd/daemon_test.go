@@ -1,7 +1,17 @@
 package main
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/docker/docker/daemon/config"
 	"github.com/sirupsen/logrus"
@@ -198,3 +208,66 @@ func TestConfigureDaemonLogs(t *testing.T) {
 	configureDaemonLogs(conf)
 	assert.Check(t, is.Equal(logrus.WarnLevel, logrus.GetLevel()))
 }
+
+func TestChangedOptions(t *testing.T) {
+	conf := &config.Config{}
+	conf.Hosts = []string{"unix:///var/run/docker.sock"}
+	conf.APIAccessLogFormat = "json"
+
+	newConfig := &config.Config{}
+	newConfig.ValuesSet = map[string]interface{}{
+		"hosts":                 []interface{}{"unix:///var/run/docker.sock"},
+		"api-access-log":        true,
+		"api-access-log-format": "json",
+		"api-max-header-bytes":  float64(0),
+		"tlscert":               "/etc/docker/cert.pem",
+		"debug":                 true,
+	}
+	changed := changedOptions(conf, newConfig, nonReloadableAPIOptions)
+	assert.Check(t, is.DeepEqual(changed, []string{"tlscert", "api-access-log"}))
+}
+
+func TestAPIServeDuringStartup(t *testing.T) {
+	conf := config.New()
+	serverConfig, err := newAPIServerConfig(conf)
+	assert.NilError(t, err)
+	assert.Check(t, !serverConfig.RejectUntilInitialized, "serving the API during startup must be opt-in")
+
+	conf.APIServeDuringStartup = true
+	serverConfig, err = newAPIServerConfig(conf)
+	assert.NilError(t, err)
+	assert.Check(t, serverConfig.RejectUntilInitialized)
+}
+
+func TestAPIServerTLSCipherSuites(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "dockerd"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.NilError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NilError(t, err)
+	assert.NilError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	assert.NilError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	enabled, disabled := true, false
+	conf := config.New()
+	conf.TLS, conf.TLSVerify = &enabled, &disabled
+	conf.CommonTLSOptions.CertFile, conf.CommonTLSOptions.KeyFile = certFile, keyFile
+
+	serverConfig, err := newAPIServerConfig(conf)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(serverConfig.TLSCipherSuites, defaultTLSCipherSuites))
+
+	conf.TLSCipherSuites = []string{"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA"}
+	serverConfig, err = newAPIServerConfig(conf)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(serverConfig.TLSCipherSuites, conf.TLSCipherSuites))
+}
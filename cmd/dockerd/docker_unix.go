@@ -10,6 +10,7 @@ import (
 )
 
 func runDaemon(opts *daemonOptions) error {
+	defer dumpStacksOnPanic(opts.PanicDumpDir)
 	daemonCli := NewDaemonCli()
 	return daemonCli.start(opts)
 }
@@ -9,6 +9,7 @@ import (
 )
 
 func runDaemon(opts *daemonOptions) error {
+	defer dumpStacksOnPanic(opts.PanicDumpDir)
 	daemonCli := NewDaemonCli()
 
 	// On Windows, this may be launching as a service or with an option to
@@ -0,0 +1,32 @@
+package main
+
+import "github.com/sirupsen/logrus"
+
+// LifecycleEvent is a transition in the lifecycle of the daemon.
+type LifecycleEvent string
+
+const (
+	// LifecycleInitializing is emitted when the daemon starts initializing,
+	// once its configuration is loaded.
+	LifecycleInitializing LifecycleEvent = "initializing"
+	// LifecycleListening is emitted once the API listeners are created,
+	// before the daemon itself is set up.
+	LifecycleListening LifecycleEvent = "listening"
+	// LifecycleReady is emitted once the daemon is fully initialized, and
+	// serving the API.
+	LifecycleReady LifecycleEvent = "ready"
+	// LifecycleShuttingDown is emitted when the daemon starts shutting down.
+	LifecycleShuttingDown LifecycleEvent = "shutting-down"
+	// LifecycleStopped is emitted when the daemon has stopped, whether it
+	// shut down normally, or failed to start.
+	LifecycleStopped LifecycleEvent = "stopped"
+)
+
+// emitLifecycleEvent calls the OnLifecycleEvent callback of the daemon CLI,
+// if any.
+func (cli *DaemonCli) emitLifecycleEvent(ev LifecycleEvent) {
+	logrus.WithField("event", ev).Debug("daemon lifecycle event")
+	if cli.OnLifecycleEvent != nil {
+		cli.OnLifecycleEvent(ev)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"os"
 	"path/filepath"
+	"time"
 
 	cliconfig "github.com/docker/docker/cli/config"
 	"github.com/docker/docker/daemon/config"
@@ -42,8 +43,37 @@ type daemonOptions struct {
 	TLSVerify    bool
 	TLSOptions   *tlsconfig.Options
 	Validate     bool
+
+	// APIShutdownTimeout is the maximum duration the API server waits for
+	// in-flight requests on shutdown, after which their connections are
+	// closed. A zero value uses defaultAPIShutdownTimeout.
+	APIShutdownTimeout time.Duration
+
+	// APIDrainGracePeriod is the duration the API server drains for when
+	// the daemon is stopped by a signal, before it is shut down, so that
+	// load balancers stop sending requests to the daemon. A zero value
+	// shuts the server down without draining it.
+	APIDrainGracePeriod time.Duration
+
+	// PanicDumpDir is the directory to which the stacks of all goroutines
+	// are written if the daemon, or an API handler, panics.
+	PanicDumpDir string
+
+	// ProfilerWarmup is the duration the profiler is enabled for after the
+	// daemon starts, to capture profiles of its startup, after which it is
+	// disabled. A zero value leaves the profiler enabled. If
+	// ProfilerWarmupCPUProfile is set, a CPU profile of the warm-up period,
+	// starting before the daemon is initialized, is written to that file.
+	ProfilerWarmup           time.Duration
+	ProfilerWarmupCPUProfile string
 }
 
+// defaultAPIShutdownTimeout is the maximum duration the API server waits for
+// in-flight requests on shutdown by default. Streaming requests, such as
+// following the logs of a container, only complete when the daemon stops, so
+// the shutdown must be bounded.
+const defaultAPIShutdownTimeout = 10 * time.Second
+
 // newDaemonOptions returns a new daemonFlags
 func newDaemonOptions(config *config.Config) *daemonOptions {
 	return &daemonOptions{
@@ -61,6 +91,11 @@ func (o *daemonOptions) installFlags(flags *pflag.FlagSet) {
 
 	flags.BoolVarP(&o.Debug, "debug", "D", false, "Enable debug mode")
 	flags.BoolVar(&o.Validate, "validate", false, "Validate daemon configuration and exit")
+	flags.DurationVar(&o.APIShutdownTimeout, "api-shutdown-timeout", defaultAPIShutdownTimeout, "Maximum duration to wait for in-flight API requests on shutdown, after which their connections are closed")
+	flags.DurationVar(&o.APIDrainGracePeriod, "api-drain-grace-period", 0, "Duration to drain the API server for before shutting it down on SIGINT or SIGTERM")
+	flags.StringVar(&o.PanicDumpDir, "panic-dump-dir", "", "Directory to write the goroutine stacks to if the daemon panics")
+	flags.DurationVar(&o.ProfilerWarmup, "profiler-warmup", 0, "Duration to enable the profiler for after the daemon starts, before disabling it")
+	flags.StringVar(&o.ProfilerWarmupCPUProfile, "profiler-warmup-cpuprofile", "", "File to write a CPU profile of the profiler warm-up period to")
 	flags.StringVarP(&o.LogLevel, "log-level", "l", "info", `Set the logging level ("debug"|"info"|"warn"|"error"|"fatal")`)
 	flags.BoolVar(&o.TLS, FlagTLS, DefaultTLSValue, "Use TLS; implied by --tlsverify")
 	flags.BoolVar(&o.TLSVerify, FlagTLSVerify, dockerTLSVerify || DefaultTLSValue, "Use TLS and verify the remote")
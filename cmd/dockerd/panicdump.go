@@ -0,0 +1,27 @@
+package main
+
+import (
+	"github.com/docker/docker/pkg/stack"
+	"github.com/sirupsen/logrus"
+)
+
+// dumpStacksOnPanic writes the stacks of all goroutines to a file in dir if
+// the goroutine deferring it panics, and then resumes panicking, so that the
+// state of the daemon when it crashed can be analyzed. It must be deferred
+// directly; panics in other goroutines (such as API handlers, which are
+// recovered by the API server) are not handled.
+func dumpStacksOnPanic(dir string) {
+	if dir == "" {
+		return
+	}
+	p := recover()
+	if p == nil {
+		return
+	}
+	if path, err := stack.DumpToFile(dir); err != nil {
+		logrus.WithError(err).Error("Failed to write the goroutine stacks of the panicking daemon")
+	} else {
+		logrus.Errorf("Daemon panicked: %v; goroutine stacks written to %s", p, path)
+	}
+	panic(p)
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestDumpStacksOnPanic(t *testing.T) {
+	dir := t.TempDir()
+	func() {
+		defer func() {
+			assert.Check(t, is.Equal(recover(), "something went wrong"), "expected the panic to be resumed")
+		}()
+		defer dumpStacksOnPanic(dir)
+		panic("something went wrong")
+	}()
+
+	dumps, err := filepath.Glob(filepath.Join(dir, "goroutine-stacks-*.log"))
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(dumps, 1))
+	dump, err := os.ReadFile(dumps[0])
+	assert.NilError(t, err)
+	assert.Check(t, is.Contains(string(dump), "TestDumpStacksOnPanic"))
+}
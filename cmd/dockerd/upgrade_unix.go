@@ -0,0 +1,201 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/daemon/listeners"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// upgradeFDEnv is the environment variable holding the file descriptor of
+// the socket connecting a new daemon process to the process it takes over
+// from, when upgrading the daemon binary.
+const upgradeFDEnv = "DOCKER_UPGRADE_FD"
+
+// upgradeTimeout is the time the new daemon process has to signal that it
+// started, before the upgrade is aborted.
+const upgradeTimeout = time.Minute
+
+// upgradeShutdownTimeout is the time the new daemon process waits for the
+// previous process to exit, before giving up on the upgrade.
+const upgradeShutdownTimeout = 5 * time.Minute
+
+// setupUpgradeTrap upgrades the daemon binary without closing the API
+// listeners on SIGUSR2: the daemon executes its binary as a new process,
+// handing off the listeners to it, and shuts down once the new process
+// signals that it started. The new process starts the daemon once the
+// previous process exited, and serves the connections queued on the
+// listeners in the meantime.
+//
+// Upgrades are refused unless live-restore is enabled, as the previous
+// process would otherwise stop all containers when shutting down.
+func (cli *DaemonCli) setupUpgradeTrap() {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, unix.SIGUSR2)
+	go func() {
+		for range c {
+			if err := cli.upgrade(); err != nil {
+				logrus.WithError(err).Error("Failed to upgrade the daemon; continuing to serve")
+				continue
+			}
+			logrus.Info("New daemon process started; shutting down")
+			signal.Stop(c)
+			cli.stop()
+			return
+		}
+	}()
+}
+
+// upgrade starts a new daemon process, handing off the API listeners to
+// it, and waits for it to signal that it started.
+func (cli *DaemonCli) upgrade() error {
+	if !cli.d.LiveRestoreEnabled() {
+		return errors.New("upgrading the daemon requires live-restore to be enabled, as containers are stopped when the daemon shuts down")
+	}
+	files, err := cli.api.ListenerFiles()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, lf := range files {
+			_ = lf.File.Close()
+		}
+	}()
+
+	// the hosts are passed to the new process as "proto://addr", as
+	// configured, while the server only knows the addresses.
+	protos := make(map[string]string, len(cli.Config.Hosts))
+	for _, h := range cli.Config.Hosts {
+		if parts := strings.SplitN(h, "://", 2); len(parts) == 2 {
+			protos[parts[1]] = parts[0]
+		}
+	}
+	hosts := make([]string, 0, len(files))
+	extraFiles := make([]*os.File, 0, len(files)+1)
+	for _, lf := range files {
+		proto, ok := protos[lf.Addr]
+		if !ok {
+			return errors.Errorf("no host configured for listener %s", lf.Addr)
+		}
+		hosts = append(hosts, proto+"://"+lf.Addr)
+		extraFiles = append(extraFiles, lf.File)
+	}
+
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return errors.Wrap(err, "failed to create upgrade socket")
+	}
+	conn := os.NewFile(uintptr(fds[0]), "upgrade")
+	childConn := os.NewFile(uintptr(fds[1]), "upgrade")
+
+	// Resolve the binary by its path instead of re-executing /proc/self/exe
+	// (as reexec.Command does), which is the binary being upgraded.
+	binary, err := exec.LookPath(os.Args[0])
+	if err != nil {
+		conn.Close()
+		childConn.Close()
+		return errors.Wrap(err, "failed to find the daemon binary")
+	}
+	cmd := exec.Command(binary, os.Args[1:]...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// ExtraFiles start at file descriptor 3 in the new process.
+	cmd.ExtraFiles = append(extraFiles, childConn)
+	cmd.Env = append(os.Environ(),
+		listeners.ListenFDsEnv+"="+listeners.FormatListenFDs(hosts, 3),
+		upgradeFDEnv+"="+strconv.Itoa(3+len(extraFiles)),
+	)
+	logrus.WithField("binary", binary).Info("Starting new daemon process to upgrade")
+	err = cmd.Start()
+	// Close the end of the new process, so that reading from conn fails if
+	// the new process exits.
+	childConn.Close()
+	if err != nil {
+		conn.Close()
+		return errors.Wrap(err, "failed to start new daemon process")
+	}
+
+	if err := waitForUpgradeStart(conn, upgradeTimeout); err != nil {
+		conn.Close()
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return errors.Wrap(err, "new daemon process failed to start")
+	}
+
+	// The new process waits for conn to be closed, which happens when this
+	// process exits, before starting the daemon.
+	cli.upgradeConn = conn
+	notifyMainPID(cmd.Process.Pid)
+	return nil
+}
+
+// waitForUpgradeStart waits for the new daemon process to signal over conn
+// that it started, for at most timeout. It fails if the new process exits
+// before, which closes its end of conn.
+func waitForUpgradeStart(conn io.Reader, timeout time.Duration) error {
+	started := make(chan error, 1)
+	go func() {
+		_, err := conn.Read(make([]byte, 1))
+		started <- err
+	}()
+	select {
+	case err := <-started:
+		if err == io.EOF {
+			return errors.New("new daemon process exited before signaling that it started")
+		}
+		return err
+	case <-time.After(timeout):
+		return errors.New("timeout")
+	}
+}
+
+// waitForUpgrade signals the daemon process this process takes over from,
+// if any, that it started, and waits for it to exit, for at most
+// upgradeShutdownTimeout.
+func waitForUpgrade() error {
+	v := os.Getenv(upgradeFDEnv)
+	if v == "" {
+		return nil
+	}
+	_ = os.Unsetenv(upgradeFDEnv)
+	fd, err := strconv.Atoi(v)
+	if err != nil {
+		return errors.Errorf("invalid %s: %q", upgradeFDEnv, v)
+	}
+	unix.CloseOnExec(fd)
+	conn := os.NewFile(uintptr(fd), "upgrade")
+	defer conn.Close()
+	return waitForPreviousDaemon(conn, upgradeShutdownTimeout)
+}
+
+// waitForPreviousDaemon signals over conn that this process started, and
+// waits for the previous daemon process to close conn, which happens when
+// it exits, for at most timeout.
+func waitForPreviousDaemon(conn io.ReadWriter, timeout time.Duration) error {
+	if _, err := conn.Write([]byte{1}); err != nil {
+		return errors.Wrap(err, "failed to signal the previous daemon process")
+	}
+	logrus.Info("Waiting for the previous daemon process to shut down")
+	exited := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(io.Discard, conn)
+		close(exited)
+	}()
+	select {
+	case <-exited:
+		return nil
+	case <-time.After(timeout):
+		return errors.Errorf("previous daemon process did not shut down within %v", timeout)
+	}
+}
@@ -0,0 +1,96 @@
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+// upgradeSocketpair returns the end of the upgrade socket of the previous
+// daemon process, and the file descriptor of the end of the new process,
+// which is not wrapped in an *os.File, as waitForUpgrade takes ownership of
+// it.
+func upgradeSocketpair(t *testing.T) (parent *os.File, childFD int) {
+	t.Helper()
+	fds, err := unix.Socketpair(unix.AF_UNIX, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	assert.NilError(t, err)
+	return os.NewFile(uintptr(fds[0]), "parent"), fds[1]
+}
+
+func TestWaitForUpgrade(t *testing.T) {
+	parent, childFD := upgradeSocketpair(t)
+	defer parent.Close()
+	t.Setenv(upgradeFDEnv, strconv.Itoa(childFD))
+
+	done := make(chan error, 1)
+	go func() { done <- waitForUpgrade() }()
+
+	// the new process signals that it started, then waits for the
+	// previous process to exit, closing its end of the socket.
+	assert.NilError(t, waitForUpgradeStart(parent, 10*time.Second))
+	select {
+	case err := <-done:
+		t.Fatalf("waitForUpgrade returned before the previous process exited: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.NilError(t, parent.Close())
+	select {
+	case err := <-done:
+		assert.Check(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("waitForUpgrade did not return once the previous process exited")
+	}
+	assert.Check(t, is.Equal(os.Getenv(upgradeFDEnv), ""))
+}
+
+func TestWaitForUpgradeNotUpgrading(t *testing.T) {
+	t.Setenv(upgradeFDEnv, "")
+	assert.Check(t, waitForUpgrade())
+
+	t.Setenv(upgradeFDEnv, "not-a-fd")
+	assert.Check(t, is.Error(waitForUpgrade(), `invalid DOCKER_UPGRADE_FD: "not-a-fd"`))
+}
+
+func TestWaitForUpgradePreviousProcessGone(t *testing.T) {
+	// the previous process aborted the upgrade, and closed its end of the
+	// socket, before the new process signaled that it started.
+	parent, childFD := upgradeSocketpair(t)
+	assert.NilError(t, parent.Close())
+	t.Setenv(upgradeFDEnv, strconv.Itoa(childFD))
+	assert.Check(t, is.ErrorContains(waitForUpgrade(), "failed to signal the previous daemon process"))
+}
+
+func TestWaitForPreviousDaemonTimeout(t *testing.T) {
+	parent, childFD := upgradeSocketpair(t)
+	child := os.NewFile(uintptr(childFD), "child")
+	defer parent.Close()
+	defer child.Close()
+	err := waitForPreviousDaemon(child, 10*time.Millisecond)
+	assert.Check(t, is.Error(err, "previous daemon process did not shut down within 10ms"))
+}
+
+func TestWaitForUpgradeStartTimeout(t *testing.T) {
+	parent, childFD := upgradeSocketpair(t)
+	child := os.NewFile(uintptr(childFD), "child")
+	defer parent.Close()
+	defer child.Close()
+	assert.Check(t, is.Error(waitForUpgradeStart(parent, 10*time.Millisecond), "timeout"))
+}
+
+func TestWaitForUpgradeStartChildExited(t *testing.T) {
+	// the new process exits before signaling that it started, which closes
+	// its end of the socket.
+	parent, childFD := upgradeSocketpair(t)
+	defer parent.Close()
+	assert.NilError(t, unix.Close(childFD))
+	err := waitForUpgradeStart(parent, 10*time.Second)
+	assert.Check(t, is.Error(err, "new daemon process exited before signaling that it started"))
+}
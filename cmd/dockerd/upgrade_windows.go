@@ -0,0 +1,11 @@
+package main
+
+// setupUpgradeTrap is a no-op on Windows, where listeners cannot be handed
+// off to a new process.
+func (cli *DaemonCli) setupUpgradeTrap() {
+}
+
+// waitForUpgrade is a no-op on Windows.
+func waitForUpgrade() error {
+	return nil
+}
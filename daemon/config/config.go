@@ -7,6 +7,7 @@ import (
 	"net"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -161,6 +162,8 @@ type CommonConfig struct {
 	Root                  string                    `json:"data-root,omitempty"`
 	ExecRoot              string                    `json:"exec-root,omitempty"`
 	SocketGroup           string                    `json:"group,omitempty"`
+	SocketMode            string                    `json:"socket-mode,omitempty"`
+	SocketUser            string                    `json:"socket-user,omitempty"`
 	CorsHeaders           string                    `json:"api-cors-header,omitempty"`
 
 	// Proxies holds the proxies that are configured for the daemon.
@@ -209,12 +212,66 @@ type CommonConfig struct {
 	// to stop when daemon is being shutdown
 	ShutdownTimeout int `json:"shutdown-timeout,omitempty"`
 
+	// APIReadTimeout, APIReadHeaderTimeout, APIWriteTimeout and APIIdleTimeout
+	// are the timeouts (in seconds) applied to connections of the API server.
+	// A zero value means no timeout.
+	APIReadTimeout       int `json:"api-read-timeout,omitempty"`
+	APIReadHeaderTimeout int `json:"api-read-header-timeout,omitempty"`
+	APIWriteTimeout      int `json:"api-write-timeout,omitempty"`
+	APIIdleTimeout       int `json:"api-idle-timeout,omitempty"`
+
+	// APIMaxHeaderBytes is the maximum size (in bytes) of the headers of API
+	// requests. A zero value means the default of 1 MB.
+	APIMaxHeaderBytes int `json:"api-max-header-bytes,omitempty"`
+
+	// APIAccessLog enables logging of every request handled by the API, in
+	// the format set by APIAccessLogFormat ("text" or "json").
+	APIAccessLog       bool   `json:"api-access-log,omitempty"`
+	APIAccessLogFormat string `json:"api-access-log-format,omitempty"`
+
+	// APIAccessLogSampleRate, if greater than 1, only logs one in
+	// APIAccessLogSampleRate successful requests in the access log, in
+	// addition to the requests taking longer than APIAccessLogSlowThreshold
+	// (in milliseconds), if set. Failing requests are always logged.
+	APIAccessLogSampleRate    int `json:"api-access-log-sample-rate,omitempty"`
+	APIAccessLogSlowThreshold int `json:"api-access-log-slow-threshold,omitempty"`
+
+	// APIConfigEndpoint enables the "/_admin/config" endpoint of the API,
+	// returning the effective configuration of the daemon, with the values
+	// that may contain secrets redacted (see Config.Redacted).
+	APIConfigEndpoint bool `json:"api-config-endpoint,omitempty"`
+
+	// APIServeDuringStartup serves the API while the daemon initializes,
+	// so that it can be probed, rejecting requests other than the health
+	// endpoints and pings with a "503 Service Unavailable" until the daemon
+	// is initialized. When disabled, the connections wait in the backlog of
+	// the listeners until the daemon is initialized.
+	APIServeDuringStartup bool `json:"api-serve-during-startup,omitempty"`
+
 	Debug     bool     `json:"debug,omitempty"`
 	Hosts     []string `json:"hosts,omitempty"`
 	LogLevel  string   `json:"log-level,omitempty"`
 	TLS       *bool    `json:"tls,omitempty"`
 	TLSVerify *bool    `json:"tlsverify,omitempty"`
 
+	// TLSAllowedCNs is the list of common names allowed for client
+	// certificates connecting to the API.
+	TLSAllowedCNs []string `json:"tls-allowed-cns,omitempty"`
+
+	// TLSMinVersion is the minimum TLS version accepted by the API, and
+	// TLSCipherSuites the cipher suites allowed for TLS 1.2 connections.
+	// When TLSCipherSuites is empty, only cipher suites with ECDHE key
+	// exchange and AEAD ciphers are allowed; the CBC cipher suites that
+	// were allowed before must be configured explicitly.
+	TLSMinVersion   string   `json:"tls-min-version,omitempty"`
+	TLSCipherSuites []string `json:"tls-cipher-suites,omitempty"`
+
+	// TLSOCSPResponder is the URL of the OCSP responder queried for the
+	// OCSP response stapled to the TLS certificate of the API, and
+	// TLSOCSPStapleFile the path of a file containing the response.
+	TLSOCSPResponder  string `json:"tls-ocsp-responder,omitempty"`
+	TLSOCSPStapleFile string `json:"tls-ocsp-staple-file,omitempty"`
+
 	// Embedded structs that allow config
 	// deserialization without the full struct.
 	CommonTLSOptions
@@ -237,6 +294,11 @@ type CommonConfig struct {
 
 	MetricsAddress string `json:"metrics-addr"`
 
+	// MetricsHealth and MetricsProfiler additionally serve the health
+	// endpoints, and the profiler endpoints, on the metrics address.
+	MetricsHealth   bool `json:"metrics-health,omitempty"`
+	MetricsProfiler bool `json:"metrics-profiler,omitempty"`
+
 	DNSConfig
 	LogConfig
 	BridgeConfig // bridgeConfig holds bridge network specific configuration.
@@ -564,6 +626,25 @@ func findConfigurationConflicts(config map[string]interface{}, flags *pflag.Flag
 	return nil
 }
 
+// GetSocketMode returns the file mode of the daemon's unix sockets, or zero
+// if no mode is configured. The mode must grant read and write access to the
+// owner of the socket, and must not have bits set other than the permission
+// bits.
+func (conf *Config) GetSocketMode() (os.FileMode, error) {
+	mode := conf.SocketMode
+	if mode == "" {
+		return 0, nil
+	}
+	m, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || m&^0o777 != 0 {
+		return 0, fmt.Errorf("invalid socket-mode: %s", mode)
+	}
+	if m&0o600 != 0o600 {
+		return 0, fmt.Errorf("invalid socket-mode: %s: the owner of the socket must have read and write access", mode)
+	}
+	return os.FileMode(m), nil
+}
+
 // Validate validates some specific configs.
 // such as config.DNS, config.Labels, config.DNSSearch,
 // as well as config.MaxConcurrentDownloads, config.MaxConcurrentUploads and config.MaxDownloadAttempts.
@@ -575,6 +656,22 @@ func Validate(config *Config) error {
 		}
 	}
 
+	switch config.APIAccessLogFormat {
+	case "", "text", "json":
+	default:
+		return fmt.Errorf("invalid api-access-log-format: %s", config.APIAccessLogFormat)
+	}
+	if config.APIAccessLogSampleRate < 0 {
+		return fmt.Errorf("invalid api-access-log-sample-rate: %d: must not be negative", config.APIAccessLogSampleRate)
+	}
+	if config.APIAccessLogSlowThreshold < 0 {
+		return fmt.Errorf("invalid api-access-log-slow-threshold: %d: must not be negative", config.APIAccessLogSlowThreshold)
+	}
+
+	if _, err := config.GetSocketMode(); err != nil {
+		return err
+	}
+
 	// validate DNS
 	for _, dns := range config.DNS {
 		if _, err := opts.ValidateIPAddress(dns); err != nil {
@@ -606,6 +703,19 @@ func Validate(config *Config) error {
 	if config.MaxDownloadAttempts < 0 {
 		return fmt.Errorf("invalid max download attempts: %d", config.MaxDownloadAttempts)
 	}
+	for name, timeout := range map[string]int{
+		"api-read-timeout":        config.APIReadTimeout,
+		"api-read-header-timeout": config.APIReadHeaderTimeout,
+		"api-write-timeout":       config.APIWriteTimeout,
+		"api-idle-timeout":        config.APIIdleTimeout,
+	} {
+		if timeout < 0 {
+			return fmt.Errorf("invalid %s: %d", name, timeout)
+		}
+	}
+	if config.APIMaxHeaderBytes < 0 {
+		return fmt.Errorf("invalid api-max-header-bytes: %d", config.APIMaxHeaderBytes)
+	}
 
 	// validate that "default" runtime is not reset
 	if runtimes := config.GetAllRuntimes(); len(runtimes) > 0 {
@@ -655,3 +765,98 @@ func MaskCredentials(rawURL string) string {
 	parsedURL.User = url.UserPassword("xxxxx", "xxxxx")
 	return parsedURL.String()
 }
+
+// freeFormOptions are the options of the daemon configuration file holding
+// driver-specific options, whose values are masked by Redacted, unless listed
+// in displayedOptions, as they may contain secrets (such as the token of
+// the splunk logging driver).
+var freeFormOptions = map[string]bool{
+	"log-opts":           true,
+	"storage-opts":       true,
+	"exec-opts":          true,
+	"cluster-store-opts": true,
+}
+
+// displayedOptions are the driver-specific options whose values are known
+// not to contain secrets.
+var displayedOptions = map[string]bool{
+	"compress":        true,
+	"env":             true,
+	"env-regex":       true,
+	"labels":          true,
+	"labels-regex":    true,
+	"max-buffer-size": true,
+	"max-file":        true,
+	"max-size":        true,
+	"mode":            true,
+	"tag":             true,
+
+	"native.cgroupdriver": true,
+	"overlay2.size":       true,
+	"dm.basesize":         true,
+}
+
+// Redacted returns the configuration, as the options of the daemon
+// configuration file, for display. The credentials of URLs (such as proxy
+// URLs) are masked, as are the values of driver-specific options (such as
+// log-opts), except for those listed in displayedOptions.
+func (conf *Config) Redacted() (map[string]interface{}, error) {
+	conf.Lock()
+	b, err := json.Marshal(conf)
+	conf.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	var redacted map[string]interface{}
+	if err := json.Unmarshal(b, &redacted); err != nil {
+		return nil, err
+	}
+	for k, v := range redacted {
+		if freeFormOptions[k] {
+			redacted[k] = redactOptions(v)
+		} else {
+			redacted[k] = redactValues(v)
+		}
+	}
+	return redacted, nil
+}
+
+const redactedValue = "*****"
+
+// redactOptions masks the values of the driver-specific options v, which are
+// either a map of options, or a list of "name=value" options.
+func redactOptions(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k := range v {
+			if !displayedOptions[strings.ToLower(k)] {
+				v[k] = redactedValue
+			}
+		}
+	case []interface{}:
+		for i, opt := range v {
+			s, _ := opt.(string)
+			name := strings.SplitN(s, "=", 2)[0]
+			if !displayedOptions[strings.ToLower(name)] {
+				v[i] = name + "=" + redactedValue
+			}
+		}
+	}
+	return v
+}
+
+func redactValues(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			v[k] = redactValues(val)
+		}
+	case []interface{}:
+		for i, val := range v {
+			v[i] = redactValues(val)
+		}
+	case string:
+		return MaskCredentials(v)
+	}
+	return v
+}
@@ -142,6 +142,12 @@ func (daemon *Daemon) HasExperimental() bool {
 	return daemon.configStore != nil && daemon.configStore.Experimental
 }
 
+// LiveRestoreEnabled returns whether containers keep running while the
+// daemon is down
+func (daemon *Daemon) LiveRestoreEnabled() bool {
+	return daemon.configStore != nil && daemon.configStore.LiveRestoreEnabled
+}
+
 // Features returns the features map from configStore
 func (daemon *Daemon) Features() *map[string]bool {
 	return &daemon.configStore.Features
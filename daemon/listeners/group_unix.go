@@ -23,3 +23,15 @@ func lookupGID(name string) (int, error) {
 	}
 	return -1, fmt.Errorf("group %s not found", name)
 }
+
+func lookupUID(name string) (int, error) {
+	usr, err := idtools.LookupUser(name)
+	if err == nil {
+		return usr.Uid, nil
+	}
+	uid, err := strconv.Atoi(name)
+	if err == nil {
+		return uid, nil
+	}
+	return -1, fmt.Errorf("user %s not found", name)
+}
@@ -0,0 +1,57 @@
+package listeners // import "github.com/docker/docker/daemon/listeners"
+
+import (
+	"net"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+var (
+	inheritedMu  sync.Mutex
+	inheritedFDs map[string][]int // nil until ListenFDsEnv is parsed
+)
+
+// inheritedListeners returns the listeners for the host proto://addr that
+// the previous daemon process handed off through ListenFDsEnv, if any.
+func inheritedListeners(proto, addr string) ([]net.Listener, error) {
+	inheritedMu.Lock()
+	defer inheritedMu.Unlock()
+
+	if inheritedFDs == nil {
+		fds, err := parseListenFDs(os.Getenv(ListenFDsEnv))
+		if err != nil {
+			return nil, err
+		}
+		_ = os.Unsetenv(ListenFDsEnv)
+		// Do not leak the file descriptors of listeners that are not used
+		// to the processes started by the daemon, such as containerd.
+		for _, fdList := range fds {
+			for _, fd := range fdList {
+				unix.CloseOnExec(fd)
+			}
+		}
+		inheritedFDs = fds
+	}
+
+	host := proto + "://" + addr
+	fds := inheritedFDs[host]
+	delete(inheritedFDs, host)
+
+	var ls []net.Listener
+	for _, fd := range fds {
+		f := os.NewFile(uintptr(fd), host)
+		l, err := net.FileListener(f)
+		_ = f.Close()
+		if err != nil {
+			for _, l := range ls {
+				_ = l.Close()
+			}
+			return nil, errors.Wrapf(err, "failed to use the listener handed off for %s", host)
+		}
+		ls = append(ls, l)
+	}
+	return ls, nil
+}
@@ -0,0 +1,76 @@
+package listeners // import "github.com/docker/docker/daemon/listeners"
+
+import (
+	"net"
+	"testing"
+
+	"golang.org/x/sys/unix"
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestParseListenFDs(t *testing.T) {
+	v := FormatListenFDs([]string{"unix:///var/run/docker.sock", "tcp://0.0.0.0:2376", "fd://", "fd://"}, 3)
+	assert.Check(t, is.Equal(v, "3=unix:///var/run/docker.sock,4=tcp://0.0.0.0:2376,5=fd://,6=fd://"))
+
+	fds, err := parseListenFDs(v)
+	assert.NilError(t, err)
+	assert.Check(t, is.DeepEqual(fds, map[string][]int{
+		"unix:///var/run/docker.sock": {3},
+		"tcp://0.0.0.0:2376":          {4},
+		"fd://":                       {5, 6},
+	}))
+
+	_, err = parseListenFDs("unix:///var/run/docker.sock")
+	assert.Check(t, is.ErrorContains(err, "invalid DOCKER_LISTEN_FDS entry"))
+	_, err = parseListenFDs("1=tcp://0.0.0.0:2376")
+	assert.Check(t, is.ErrorContains(err, "invalid file descriptor"))
+}
+
+func TestFormatListenFDsRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		hosts   []string
+		firstFD int
+	}{
+		{hosts: nil, firstFD: 3},
+		{hosts: []string{"unix:///var/run/docker.sock"}, firstFD: 3},
+		{hosts: []string{"tcp://127.0.0.1:2375", "tcp://[::1]:2376", "unix:///run/docker.sock"}, firstFD: 10},
+		{hosts: []string{"fd://", "fd://", "tcp://127.0.0.1:2375"}, firstFD: 3},
+	} {
+		fds, err := parseListenFDs(FormatListenFDs(tc.hosts, tc.firstFD))
+		assert.NilError(t, err)
+		expected := make(map[string][]int)
+		for i, h := range tc.hosts {
+			expected[h] = append(expected[h], tc.firstFD+i)
+		}
+		assert.Check(t, is.DeepEqual(fds, expected), "hosts %v", tc.hosts)
+	}
+}
+
+func TestInitInheritedListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	defer l.Close()
+	f, err := l.(*net.TCPListener).File()
+	assert.NilError(t, err)
+	// the file descriptor is owned by Init once inherited.
+	fd, err := unix.Dup(int(f.Fd()))
+	assert.NilError(t, err)
+	assert.NilError(t, f.Close())
+
+	host := "tcp://" + l.Addr().String()
+	t.Setenv(ListenFDsEnv, FormatListenFDs([]string{host}, fd))
+	inheritedFDs = nil
+	defer func() { inheritedFDs = nil }()
+
+	ls, err := Init("tcp", l.Addr().String(), SocketOptions{}, nil)
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(ls, 1))
+	defer ls[0].Close()
+	assert.Check(t, is.Equal(ls[0].Addr().String(), l.Addr().String()))
+	assert.Check(t, is.Len(inheritedFDs, 0))
+
+	c, err := net.Dial("tcp", l.Addr().String())
+	assert.NilError(t, err)
+	c.Close()
+}
@@ -0,0 +1,78 @@
+package listeners // import "github.com/docker/docker/daemon/listeners"
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SocketOptions holds the ownership and permissions of the sockets
+// created by Init, and the options of TCP sockets.
+type SocketOptions struct {
+	// Group is the group owning unix sockets, or the users or groups
+	// allowed to access named pipes on Windows. Group, User and Mode can't
+	// be applied to unix sockets in the abstract namespace on Linux (e.g.
+	// "unix://@docker"), which have no presence in the filesystem: Init
+	// fails if User, Mode, or a group other than the default one is set.
+	Group string
+
+	// User is the user owning unix sockets. When empty, the sockets are
+	// owned by root.
+	User string
+
+	// Mode is the file mode of unix sockets. When zero, sockets are
+	// created with mode 0660.
+	Mode os.FileMode
+
+	// Backlog is the size of the accept queue of TCP sockets, which the
+	// kernel caps to net.core.somaxconn. When zero, the default of the Go
+	// runtime (net.core.somaxconn) is used. It is not supported on Windows.
+	Backlog int
+
+	// ReusePort sets SO_REUSEPORT on TCP sockets, so that multiple
+	// processes can listen on the same port. It is not supported on
+	// Windows.
+	ReusePort bool
+}
+
+// ListenFDsEnv is the environment variable through which a daemon process
+// hands off its listeners to a new daemon process, for example, when
+// upgrading the daemon binary. Its value is a comma-separated list of
+// "fd=proto://addr" entries, where fd is the file descriptor of the listener
+// for the host proto://addr in the new process. Init returns the listeners
+// passed this way instead of creating new ones.
+const ListenFDsEnv = "DOCKER_LISTEN_FDS"
+
+// FormatListenFDs returns the value of ListenFDsEnv for the listeners of
+// hosts, where the listener of hosts[i] is the file descriptor firstFD+i in
+// the new process.
+func FormatListenFDs(hosts []string, firstFD int) string {
+	entries := make([]string, 0, len(hosts))
+	for i, h := range hosts {
+		entries = append(entries, strconv.Itoa(firstFD+i)+"="+h)
+	}
+	return strings.Join(entries, ",")
+}
+
+// parseListenFDs parses the value of ListenFDsEnv, returning the file
+// descriptors for each host.
+func parseListenFDs(v string) (map[string][]int, error) {
+	fds := make(map[string][]int)
+	if v == "" {
+		return fds, nil
+	}
+	for _, entry := range strings.Split(v, ",") {
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, errors.Errorf("invalid %s entry: %q", ListenFDsEnv, entry)
+		}
+		fd, err := strconv.Atoi(parts[0])
+		if err != nil || fd < 3 {
+			return nil, errors.Errorf("invalid file descriptor in %s entry: %q", ListenFDsEnv, entry)
+		}
+		fds[parts[1]] = append(fds[parts[1]], fd)
+	}
+	return fds, nil
+}
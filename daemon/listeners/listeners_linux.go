@@ -5,6 +5,7 @@ import (
 	"net"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/coreos/go-systemd/v22/activation"
 	"github.com/docker/docker/pkg/homedir"
@@ -14,9 +15,12 @@ import (
 )
 
 // Init creates new listeners for the server.
-// TODO: Clean up the fact that socketGroup and tlsConfig aren't always used.
-func Init(proto, addr, socketGroup string, tlsConfig *tls.Config) ([]net.Listener, error) {
-	ls := []net.Listener{}
+// TODO: Clean up the fact that socketOpts and tlsConfig aren't always used.
+func Init(proto, addr string, socketOpts SocketOptions, tlsConfig *tls.Config) ([]net.Listener, error) {
+	ls, err := inheritedListeners(proto, addr)
+	if err != nil || len(ls) > 0 {
+		return ls, err
+	}
 
 	switch proto {
 	case "fd":
@@ -26,12 +30,21 @@ func Init(proto, addr, socketGroup string, tlsConfig *tls.Config) ([]net.Listene
 		}
 		ls = append(ls, fds...)
 	case "tcp":
-		l, err := sockets.NewTCPSocket(addr, tlsConfig)
+		l, err := newTCPSocket(addr, socketOpts, tlsConfig)
 		if err != nil {
 			return nil, err
 		}
 		ls = append(ls, l)
 	case "unix":
+		if isAbstractSocket(addr) {
+			l, err := newAbstractUnixSocket(addr, socketOpts)
+			if err != nil {
+				return nil, err
+			}
+			ls = append(ls, l)
+			break
+		}
+		socketGroup := socketOpts.Group
 		gid, err := lookupGID(socketGroup)
 		if err != nil {
 			if socketGroup != "" {
@@ -46,6 +59,10 @@ func Init(proto, addr, socketGroup string, tlsConfig *tls.Config) ([]net.Listene
 		if err != nil {
 			return nil, errors.Wrapf(err, "can't create unix socket %s", addr)
 		}
+		if err := setSocketPermissions(addr, socketOpts); err != nil {
+			l.Close()
+			return nil, err
+		}
 		if _, err := homedir.StickRuntimeDirContents([]string{addr}); err != nil {
 			// StickRuntimeDirContents returns nil error if XDG_RUNTIME_DIR is just unset
 			logrus.WithError(err).Warnf("cannot set sticky bit on socket %s under XDG_RUNTIME_DIR", addr)
@@ -58,6 +75,53 @@ func Init(proto, addr, socketGroup string, tlsConfig *tls.Config) ([]net.Listene
 	return ls, nil
 }
 
+// isAbstractSocket returns whether addr is the address of a unix socket in
+// the abstract namespace, such as "@docker".
+func isAbstractSocket(addr string) bool {
+	return strings.HasPrefix(addr, "@")
+}
+
+// newAbstractUnixSocket creates a unix socket in the abstract namespace.
+// Abstract sockets have no presence in the filesystem, so there is no file
+// to clean up, and the owner and mode of SocketOptions do not apply; access
+// to them is not restricted by file permissions. Rather than silently
+// exposing the socket, an error is returned if socketOpts explicitly sets
+// them; only a warning is logged for the default group.
+func newAbstractUnixSocket(addr string, socketOpts SocketOptions) (net.Listener, error) {
+	if socketOpts.User != "" || socketOpts.Mode != 0 || (socketOpts.Group != "" && socketOpts.Group != defaultSocketGroup) {
+		return nil, errors.Errorf("can't create abstract unix socket %s: the socket group, user and mode can't be applied to sockets in the abstract namespace", addr)
+	}
+	if socketOpts.Group != "" {
+		logrus.Warnf("abstract unix socket %s is not restricted to the %s group: any process in the network namespace of the daemon can connect to it", addr, socketOpts.Group)
+	}
+	// The Go runtime replaces the leading "@" by a null byte.
+	l, err := net.Listen("unix", addr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't create abstract unix socket %s", addr)
+	}
+	return l, nil
+}
+
+// setSocketPermissions applies the owner and file mode of socketOpts to the
+// unix socket at path.
+func setSocketPermissions(path string, socketOpts SocketOptions) error {
+	if socketOpts.User != "" {
+		uid, err := lookupUID(socketOpts.User)
+		if err != nil {
+			return err
+		}
+		if err := os.Chown(path, uid, -1); err != nil {
+			return errors.Wrapf(err, "can't change owner of unix socket %s to %s", path, socketOpts.User)
+		}
+	}
+	if socketOpts.Mode != 0 {
+		if err := os.Chmod(path, socketOpts.Mode); err != nil {
+			return errors.Wrapf(err, "can't change mode of unix socket %s to %#o", path, socketOpts.Mode)
+		}
+	}
+	return nil
+}
+
 // listenFD returns the specified socket activated files as a slice of
 // net.Listeners or all of the activated files if "*" is given.
 func listenFD(addr string, tlsConfig *tls.Config) ([]net.Listener, error) {
@@ -0,0 +1,34 @@
+package listeners // import "github.com/docker/docker/daemon/listeners"
+
+import (
+	"net"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+)
+
+func TestInitAbstractUnixSocket(t *testing.T) {
+	addr := "@docker-test-" + t.Name()
+	ls, err := Init("unix", addr, SocketOptions{Group: defaultSocketGroup}, nil)
+	assert.NilError(t, err)
+	assert.Assert(t, is.Len(ls, 1))
+	defer ls[0].Close()
+	assert.Check(t, is.Equal(ls[0].Addr().String(), addr))
+
+	c, err := net.Dial("unix", addr)
+	assert.NilError(t, err)
+	c.Close()
+}
+
+func TestInitAbstractUnixSocketPermissions(t *testing.T) {
+	addr := "@docker-test-" + t.Name()
+	for _, opts := range []SocketOptions{
+		{Group: "staff"},
+		{User: "root"},
+		{Mode: 0o600},
+	} {
+		_, err := Init("unix", addr, opts, nil)
+		assert.Check(t, is.ErrorContains(err, "abstract namespace"), "options %+v", opts)
+	}
+}
@@ -8,14 +8,18 @@ import (
 
 	winio "github.com/Microsoft/go-winio"
 	"github.com/docker/go-connections/sockets"
+	"github.com/pkg/errors"
 )
 
 // Init creates new listeners for the server.
-func Init(proto, addr, socketGroup string, tlsConfig *tls.Config) ([]net.Listener, error) {
+func Init(proto, addr string, socketOpts SocketOptions, tlsConfig *tls.Config) ([]net.Listener, error) {
 	ls := []net.Listener{}
 
 	switch proto {
 	case "tcp":
+		if socketOpts.Backlog > 0 || socketOpts.ReusePort {
+			return nil, errors.New("TCP backlog and SO_REUSEPORT options are not supported on Windows")
+		}
 		l, err := sockets.NewTCPSocket(addr, tlsConfig)
 		if err != nil {
 			return nil, err
@@ -25,8 +29,8 @@ func Init(proto, addr, socketGroup string, tlsConfig *tls.Config) ([]net.Listene
 	case "npipe":
 		// allow Administrators and SYSTEM, plus whatever additional users or groups were specified
 		sddl := "D:P(A;;GA;;;BA)(A;;GA;;;SY)"
-		if socketGroup != "" {
-			for _, g := range strings.Split(socketGroup, ",") {
+		if socketOpts.Group != "" {
+			for _, g := range strings.Split(socketOpts.Group, ",") {
 				sid, err := winio.LookupSidByName(g)
 				if err != nil {
 					return nil, err
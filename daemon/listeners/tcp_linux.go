@@ -0,0 +1,64 @@
+package listeners // import "github.com/docker/docker/daemon/listeners"
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"syscall"
+
+	"github.com/docker/go-connections/sockets"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// newTCPSocket is like sockets.NewTCPSocket, but applies the TCP options of
+// socketOpts to the listening socket.
+func newTCPSocket(addr string, socketOpts SocketOptions, tlsConfig *tls.Config) (net.Listener, error) {
+	if socketOpts.Backlog <= 0 && !socketOpts.ReusePort {
+		return sockets.NewTCPSocket(addr, tlsConfig)
+	}
+	var lc net.ListenConfig
+	if socketOpts.ReusePort {
+		lc.Control = func(network, address string, c syscall.RawConn) error {
+			var sockErr error
+			if err := c.Control(func(fd uintptr) {
+				sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+			}); err != nil {
+				return err
+			}
+			return errors.Wrapf(sockErr, "can't set SO_REUSEPORT on %s", address)
+		}
+	}
+	l, err := lc.Listen(context.Background(), "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if socketOpts.Backlog > 0 {
+		if err := setBacklog(l.(*net.TCPListener), socketOpts.Backlog); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+	if tlsConfig != nil {
+		tlsConfig.NextProtos = []string{"http/1.1"}
+		l = tls.NewListener(l, tlsConfig)
+	}
+	return l, nil
+}
+
+// setBacklog sets the size of the accept queue of l. Calling listen(2) on a
+// socket that is already listening updates its backlog, which the kernel
+// caps to net.core.somaxconn.
+func setBacklog(l *net.TCPListener, backlog int) error {
+	raw, err := l.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var listenErr error
+	if err := raw.Control(func(fd uintptr) {
+		listenErr = unix.Listen(int(fd), backlog)
+	}); err != nil {
+		return err
+	}
+	return errors.Wrapf(listenErr, "can't set the backlog of %s to %d", l.Addr(), backlog)
+}
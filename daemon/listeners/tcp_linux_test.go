@@ -0,0 +1,24 @@
+package listeners // import "github.com/docker/docker/daemon/listeners"
+
+import (
+	"net"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestNewTCPSocketReusePort(t *testing.T) {
+	opts := SocketOptions{Backlog: 16, ReusePort: true}
+	l1, err := newTCPSocket("127.0.0.1:0", opts, nil)
+	assert.NilError(t, err)
+	defer l1.Close()
+
+	// Without SO_REUSEPORT on both sockets, this fails with EADDRINUSE.
+	l2, err := newTCPSocket(l1.Addr().String(), opts, nil)
+	assert.NilError(t, err)
+	defer l2.Close()
+
+	c, err := net.Dial("tcp", l1.Addr().String())
+	assert.NilError(t, err)
+	c.Close()
+}
@@ -53,7 +53,7 @@ func (s *DockerAPISuite) TestExecAPICreateNoValidContentType(c *testing.T) {
 	if versions.LessThan(testEnv.DaemonAPIVersion(), "1.32") {
 		assert.Equal(c, res.StatusCode, http.StatusInternalServerError)
 	} else {
-		assert.Equal(c, res.StatusCode, http.StatusBadRequest)
+		assert.Equal(c, res.StatusCode, http.StatusUnsupportedMediaType)
 	}
 	b, err := request.ReadBody(body)
 	assert.NilError(c, err)
@@ -41,7 +41,12 @@ func TestContainerInvalidJSON(t *testing.T) {
 			t.Run("invalid content type", func(t *testing.T) {
 				res, body, err := request.Post(ep, request.RawString("{}"), request.ContentType("text/plain"))
 				assert.NilError(t, err)
-				assert.Check(t, is.Equal(res.StatusCode, http.StatusBadRequest))
+				expected := http.StatusUnsupportedMediaType
+				if ep == "/v1.23/containers/foobar/start" {
+					// the Content-Type is only checked if a body is sent on API < v1.24
+					expected = http.StatusBadRequest
+				}
+				assert.Check(t, is.Equal(res.StatusCode, expected))
 
 				buf, err := request.ReadBody(body)
 				assert.NilError(t, err)
@@ -120,7 +120,7 @@ func TestVolumesInvalidJSON(t *testing.T) {
 			t.Run("invalid content type", func(t *testing.T) {
 				res, body, err := request.Post(ep, request.RawString("{}"), request.ContentType("text/plain"))
 				assert.NilError(t, err)
-				assert.Check(t, is.Equal(res.StatusCode, http.StatusBadRequest))
+				assert.Check(t, is.Equal(res.StatusCode, http.StatusUnsupportedMediaType))
 
 				buf, err := request.ReadBody(body)
 				assert.NilError(t, err)
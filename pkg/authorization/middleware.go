@@ -25,6 +25,29 @@ func NewMiddleware(names []string, pg plugingetter.PluginGetter) *Middleware {
 	}
 }
 
+type requestAliasKey struct{}
+
+type requestAlias struct {
+	method, requestURI string
+}
+
+// WithRequestAlias returns a copy of ctx, in which requests are authorized as
+// requests with the given method and URI instead of their own. It is used
+// for routes that are aliases of other routes (such as the WebSocket variant
+// of an endpoint), so that the policies of the plugins for the other route
+// also apply to the alias.
+func WithRequestAlias(ctx context.Context, method, requestURI string) context.Context {
+	return context.WithValue(ctx, requestAliasKey{}, requestAlias{method: method, requestURI: requestURI})
+}
+
+// requestOf returns the method and URI that r is authorized as.
+func requestOf(ctx context.Context, r *http.Request) (method, requestURI string) {
+	if alias, ok := ctx.Value(requestAliasKey{}).(requestAlias); ok {
+		return alias.method, alias.requestURI
+	}
+	return r.Method, r.RequestURI
+}
+
 func (m *Middleware) getAuthzPlugins() []Plugin {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -71,10 +94,11 @@ func (m *Middleware) WrapHandler(handler func(ctx context.Context, w http.Respon
 			userAuthNMethod = "TLS"
 		}
 
-		authCtx := NewCtx(plugins, user, userAuthNMethod, r.Method, r.RequestURI)
+		method, requestURI := requestOf(ctx, r)
+		authCtx := NewCtx(plugins, user, userAuthNMethod, method, requestURI)
 
 		if err := authCtx.AuthZRequest(w, r); err != nil {
-			logrus.Errorf("AuthZRequest for %s %s returned error: %s", r.Method, r.RequestURI, err)
+			logrus.Errorf("AuthZRequest for %s %s returned error: %s", method, requestURI, err)
 			return err
 		}
 
@@ -97,7 +121,7 @@ func (m *Middleware) WrapHandler(handler func(ctx context.Context, w http.Respon
 		authCtx.plugins = plugins
 
 		if err := authCtx.AuthZResponse(rw, r); errD == nil && err != nil {
-			logrus.Errorf("AuthZResponse for %s %s returned error: %s", r.Method, r.RequestURI, err)
+			logrus.Errorf("AuthZResponse for %s %s returned error: %s", method, requestURI, err)
 			return err
 		}
 
@@ -64,4 +64,12 @@ func TestMiddlewareWrapHandler(t *testing.T) {
 
 	})
 
+	t.Run("Request alias", func(t *testing.T) {
+		server.replayResponse = Response{Allow: true}
+		ctx := WithRequestAlias(ctx, http.MethodPost, "/exec/abc/start")
+		assert.NilError(t, mdHandler(ctx, resp, req, map[string]string{}))
+		assert.Check(t, is.Equal(server.recordedRequest.RequestMethod, http.MethodPost))
+		assert.Check(t, is.Equal(server.recordedRequest.RequestURI, "/exec/abc/start"))
+	})
+
 }